@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/dotabuff/manta"
+)
+
+// runAnonymize implements the `anonymize` mode: it writes a copy of a replay
+// with player names, steam IDs and chat replaced, so that it can be shared as
+// a test fixture.
+//
+//	go run . anonymize [-o anonymized.dem] [replay.dem]
+func runAnonymize(args []string) {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	out := fs.String("o", "anonymized.dem", "write the anonymized replay to this file")
+	fs.Parse(args)
+
+	path := "replay1.dem"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	fo, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("create %s: %v", *out, err)
+	}
+
+	if err := manta.Anonymize(f, fo); err != nil {
+		fo.Close()
+		os.Remove(*out)
+		log.Fatalf("anonymize: %v", err)
+	}
+	if err := fo.Close(); err != nil {
+		log.Fatalf("close %s: %v", *out, err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/dotabuff/manta"
+)
+
+// runClip implements the `clip` mode: it writes the ticks from -start to -end
+// of a replay as a standalone replay, e.g. to share a single teamfight.
+//
+//	go run . clip -start 60000 -end 61000 [-o clip.dem] [replay.dem]
+func runClip(args []string) {
+	fs := flag.NewFlagSet("clip", flag.ExitOnError)
+	start := fs.Uint("start", 0, "first tick of the clip")
+	end := fs.Uint("end", 0, "last tick of the clip")
+	out := fs.String("o", "clip.dem", "write the clip to this file")
+	fs.Parse(args)
+
+	if *end < *start {
+		log.Fatalf("clip: end tick %d is before start tick %d", *end, *start)
+	}
+
+	path := "replay1.dem"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	fo, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("create %s: %v", *out, err)
+	}
+
+	if err := manta.WriteClip(f, fo, uint32(*start), uint32(*end)); err != nil {
+		fo.Close()
+		os.Remove(*out)
+		log.Fatalf("clip: %v", err)
+	}
+	if err := fo.Close(); err != nil {
+		log.Fatalf("close %s: %v", *out, err)
+	}
+}
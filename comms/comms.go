@@ -0,0 +1,307 @@
+// Package comms extracts the communication between players of a match: chat,
+// chat wheel phrases, map pings and lines drawn on the map.
+//
+// Replays only carry the ids of chat wheel phrases, their texts live in the
+// game files. Only the twelve stock phrases are known; any other phrase (hero
+// and event wheels, sounds, voice lines) is written as "chat wheel #<id>"
+// unless its text is added to Comms.Phrases.
+package comms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// Kind is the type of a communication.
+type Kind string
+
+const (
+	KindChat      Kind = "chat"
+	KindChatWheel Kind = "chat_wheel"
+	KindPing      Kind = "ping"
+	KindMapLine   Kind = "map_line"
+)
+
+// Channel is the audience of a chat line.
+type Channel string
+
+const (
+	ChannelAll       Channel = "all"
+	ChannelTeam      Channel = "team"
+	ChannelSpectator Channel = "spectator"
+	ChannelOther     Channel = "other"
+)
+
+// Point is a position on the map.
+type Point struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+// Message is a single communication of a player. Pings and map lines carry
+// their position in Points, a map line being one continuous stroke.
+type Message struct {
+	Tick     uint32  `json:"tick"`
+	Time     float32 `json:"time"`
+	Kind     Kind    `json:"type"`
+	Channel  Channel `json:"channel,omitempty"`
+	PlayerID int32   `json:"player"`
+	Player   string  `json:"player_name,omitempty"`
+	Hero     string  `json:"hero,omitempty"`
+	Team     int32   `json:"team,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	PhraseID uint32  `json:"phrase_id,omitempty"`
+	Points   []Point `json:"points,omitempty"`
+
+	serverTime float32
+}
+
+// Counts are the number of communications of each kind by a player.
+type Counts struct {
+	PlayerID  int32  `json:"player"`
+	Player    string `json:"player_name"`
+	AllChat   int    `json:"all_chat"`
+	TeamChat  int    `json:"team_chat"`
+	ChatWheel int    `json:"chat_wheel"`
+	Pings     int    `json:"pings"`
+	MapLines  int    `json:"map_lines"`
+}
+
+// Comms collects the communication of a parser. Create it with New before
+// calling Start and read the results once parsing is done.
+type Comms struct {
+	Match *match.Match
+
+	// Phrases maps chat wheel message ids to their text. It's preset with
+	// the twelve stock phrases only.
+	Phrases map[uint32]string
+
+	p        *manta.Parser
+	messages []*Message
+	lines    map[int32]*Message
+}
+
+// New returns a Comms which collects communication from the given parser.
+func New(p *manta.Parser) *Comms {
+	c := &Comms{
+		Match:   match.New(p),
+		Phrases: make(map[uint32]string, len(defaultPhrases)),
+		p:       p,
+		lines:   make(map[int32]*Message),
+	}
+	for id, text := range defaultPhrases {
+		c.Phrases[id] = text
+	}
+
+	p.Callbacks.OnCUserMessageSayText2(c.onSayText2)
+	p.Callbacks.OnCDOTAUserMsg_ChatMessage(c.onChatMessage)
+	p.Callbacks.OnCDOTAUserMsg_ChatWheel(c.onChatWheel)
+	p.Callbacks.OnCDOTAUserMsg_LocationPing(c.onLocationPing)
+	p.Callbacks.OnCDOTAUserMsg_MapLine(c.onMapLine)
+
+	return c
+}
+
+func (c *Comms) add(m *Message) {
+	m.Tick = c.p.Tick
+	m.serverTime = c.Match.ServerTime()
+	c.messages = append(c.messages, m)
+}
+
+func (c *Comms) onSayText2(m *dota.CUserMessageSayText2) error {
+	msg := &Message{Kind: KindChat, Channel: ChannelAll, PlayerID: -1, Player: m.GetParam1(), Text: m.GetParam2()}
+	if strings.Contains(strings.ToLower(m.GetMessagename()), "team") {
+		msg.Channel = ChannelTeam
+	}
+
+	if pl := c.Match.PlayerForController(m.GetEntityindex()); pl != nil {
+		msg.PlayerID = pl.ID
+	}
+	c.add(msg)
+	return nil
+}
+
+func (c *Comms) onChatMessage(m *dota.CDOTAUserMsg_ChatMessage) error {
+	c.add(&Message{
+		Kind:     KindChat,
+		Channel:  channel(dota.DOTAChatChannelTypeT(m.GetChannelType())),
+		PlayerID: m.GetSourcePlayerId(),
+		Text:     m.GetMessageText(),
+	})
+	return nil
+}
+
+func channel(t dota.DOTAChatChannelTypeT) Channel {
+	switch t {
+	case dota.DOTAChatChannelTypeT_DOTAChannelType_GameAll:
+		return ChannelAll
+	case dota.DOTAChatChannelTypeT_DOTAChannelType_GameAllies, dota.DOTAChatChannelTypeT_DOTAChannelType_Team:
+		return ChannelTeam
+	case dota.DOTAChatChannelTypeT_DOTAChannelType_GameSpectator, dota.DOTAChatChannelTypeT_DOTAChannelType_HLTVSpectator:
+		return ChannelSpectator
+	}
+	return ChannelOther
+}
+
+func (c *Comms) onChatWheel(m *dota.CDOTAUserMsg_ChatWheel) error {
+	id := m.GetChatMessageId()
+	c.add(&Message{Kind: KindChatWheel, PlayerID: m.GetPlayerId(), PhraseID: id})
+	return nil
+}
+
+func (c *Comms) onLocationPing(m *dota.CDOTAUserMsg_LocationPing) error {
+	ping := m.GetLocationPing()
+	c.add(&Message{
+		Kind:     KindPing,
+		PlayerID: m.GetPlayerId(),
+		Points:   []Point{{float32(ping.GetX()), float32(ping.GetY())}},
+	})
+	return nil
+}
+
+// onMapLine collects the points of a stroke into a single message. A stroke
+// starts with an initial point and continues until the player's next one.
+func (c *Comms) onMapLine(m *dota.CDOTAUserMsg_MapLine) error {
+	id := m.GetPlayerId()
+	line := m.GetMapline()
+	pt := Point{float32(line.GetX()), float32(line.GetY())}
+
+	cur, ok := c.lines[id]
+	if !ok || line.GetInitial() {
+		cur = &Message{Kind: KindMapLine, PlayerID: id}
+		c.add(cur)
+		c.lines[id] = cur
+	}
+	cur.Points = append(cur.Points, pt)
+	return nil
+}
+
+// Messages returns all communication in the order it happened, with players,
+// phrases and game times resolved.
+func (c *Comms) Messages() []Message {
+	messages := make([]Message, len(c.messages))
+	for i, m := range c.messages {
+		msg := *m
+		msg.Time = c.Match.GameTime(m.serverTime)
+		if pl := c.Match.Player(msg.PlayerID); pl != nil {
+			msg.Player = pl.Name
+			msg.Hero = c.Match.HeroName(pl)
+			msg.Team = pl.Team
+		}
+		if msg.Kind == KindChatWheel {
+			msg.Text = c.phrase(msg.PhraseID)
+		}
+		messages[i] = msg
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Tick < messages[j].Tick })
+	return messages
+}
+
+func (c *Comms) phrase(id uint32) string {
+	if text, ok := c.Phrases[id]; ok {
+		return text
+	}
+	return fmt.Sprintf("chat wheel #%d", id)
+}
+
+// Counts returns the communication counts of each player, ordered by id.
+func (c *Comms) Counts() []Counts {
+	byPlayer := make(map[int32]*Counts)
+	for _, m := range c.Messages() {
+		n, ok := byPlayer[m.PlayerID]
+		if !ok {
+			n = &Counts{PlayerID: m.PlayerID, Player: m.Player}
+			byPlayer[m.PlayerID] = n
+		}
+		switch m.Kind {
+		case KindChat:
+			if m.Channel == ChannelTeam {
+				n.TeamChat++
+			} else {
+				n.AllChat++
+			}
+		case KindChatWheel:
+			n.ChatWheel++
+		case KindPing:
+			n.Pings++
+		case KindMapLine:
+			n.MapLines++
+		}
+	}
+
+	counts := make([]Counts, 0, len(byPlayer))
+	for _, n := range byPlayer {
+		counts = append(counts, *n)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].PlayerID < counts[j].PlayerID })
+	return counts
+}
+
+// document is the JSON representation of the communication of a match.
+type document struct {
+	Messages []Message `json:"messages"`
+	Counts   []Counts  `json:"counts"`
+}
+
+// WriteJSON writes all messages and the per player counts as a single JSON
+// document.
+func (c *Comms) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(document{Messages: c.Messages(), Counts: c.Counts()})
+}
+
+// WriteTranscript writes the communication as readable lines, one per
+// message, followed by the per player counts.
+func (c *Comms) WriteTranscript(w io.Writer) error {
+	for _, m := range c.Messages() {
+		var line string
+		switch m.Kind {
+		case KindChat:
+			line = fmt.Sprintf("(%s) %s: %s", m.Channel, speaker(m), m.Text)
+		case KindChatWheel:
+			line = fmt.Sprintf("(wheel) %s: %s", speaker(m), m.Text)
+		case KindPing:
+			line = fmt.Sprintf("(ping) %s pings at %.0f,%.0f", speaker(m), m.Points[0].X, m.Points[0].Y)
+		case KindMapLine:
+			line = fmt.Sprintf("(draw) %s draws on the map (%d points)", speaker(m), len(m.Points))
+		}
+		if _, err := fmt.Fprintf(w, "[%6s] %s\n", match.FormatGameTime(m.Time), line); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "\n%-24s %5s %5s %5s %5s %5s\n", "player", "all", "team", "wheel", "pings", "lines"); err != nil {
+		return err
+	}
+	for _, n := range c.Counts() {
+		name := n.Player
+		if name == "" {
+			name = fmt.Sprintf("#%d", n.PlayerID)
+		}
+		if _, err := fmt.Fprintf(w, "%-24s %5d %5d %5d %5d %5d\n", name, n.AllChat, n.TeamChat, n.ChatWheel, n.Pings, n.MapLines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func speaker(m Message) string {
+	name := m.Player
+	if name == "" {
+		name = fmt.Sprintf("player #%d", m.PlayerID)
+	}
+	if m.Hero != "" {
+		return fmt.Sprintf("%s (%s)", name, m.Hero)
+	}
+	return name
+}
@@ -0,0 +1,19 @@
+package comms
+
+// defaultPhrases are the English texts of the stock chat wheel messages.
+// Anything beyond these (sounds, voice lines, event wheels) is resolved from
+// Comms.Phrases or reported by id.
+var defaultPhrases = map[uint32]string{
+	0:  "Okay.",
+	1:  "Careful!",
+	2:  "Get Back!",
+	3:  "We need wards.",
+	4:  "Stun now!",
+	5:  "Help!",
+	6:  "Push now",
+	7:  "Well played!",
+	8:  "Missing!",
+	9:  "Missing top!",
+	10: "Missing mid!",
+	11: "Missing bottom!",
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"dota2/comms"
+	"dota2/corpus"
+	"dota2/damage"
+	"dota2/economy"
+	"dota2/timeline"
+
+	"github.com/dotabuff/manta"
+)
+
+// corpusAnalyzers are the analyzers the `corpus` mode can run, by name.
+var corpusAnalyzers = map[string]corpus.Analyzer{
+	"timeline": func(p *manta.Parser) func() (interface{}, error) {
+		t := timeline.New(p)
+		return func() (interface{}, error) {
+			return map[string]interface{}{
+				"players": t.Match.Players(),
+				"events":  t.Events(),
+			}, nil
+		}
+	},
+	"damage": func(p *manta.Parser) func() (interface{}, error) {
+		t := damage.New(p)
+		return func() (interface{}, error) {
+			return t.Result(), nil
+		}
+	},
+	"economy": func(p *manta.Parser) func() (interface{}, error) {
+		t := economy.New(p)
+		return func() (interface{}, error) {
+			return t.Series(), nil
+		}
+	},
+	"export": exportAnalyzer(time.Second),
+	"comms": func(p *manta.Parser) func() (interface{}, error) {
+		c := comms.New(p)
+		return func() (interface{}, error) {
+			return c.Counts(), nil
+		}
+	},
+	"stats": func(p *manta.Parser) func() (interface{}, error) {
+		pr := manta.NewProfiler(p)
+		return func() (interface{}, error) {
+			return map[string]interface{}{
+				"truncated": p.Truncated(),
+				"last_tick": p.Tick,
+				"minutes":   pr.Minutes(),
+				"demo":      pr.DemoMessages(),
+				"packet":    pr.PacketMessages(),
+				"entities":  pr.Entities(),
+			}, nil
+		}
+	},
+}
+
+// runCorpus implements the `corpus` mode: it parses every given replay (or
+// every .dem below the given directories) concurrently and writes one line
+// of JSON per replay.
+//
+//	go run . corpus [-workers 8] [-analyzers timeline,comms] [-o out.ndjson] replays/...
+func runCorpus(args []string) {
+	fs := flag.NewFlagSet("corpus", flag.ExitOnError)
+	workers := fs.Int("workers", runtime.NumCPU(), "number of replays parsed concurrently")
+	names := fs.String("analyzers", "stats", "comma separated analyzers to run: comms, damage, economy, export, stats, timeline")
+	pipeline := fs.Int("pipeline", 0, "read ahead this many outer messages per replay")
+	out := fs.String("o", "", "write NDJSON to this file instead of stdout")
+	fs.Parse(args)
+
+	analyzers := make(map[string]corpus.Analyzer)
+	for _, name := range strings.Split(*names, ",") {
+		name = strings.TrimSpace(name)
+		a, ok := corpusAnalyzers[name]
+		if !ok {
+			log.Fatalf("unknown analyzer %q", name)
+		}
+		analyzers[name] = a
+	}
+
+	files, err := corpus.Files(fs.Args())
+	if err != nil {
+		log.Fatalf("corpus: %v", err)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			log.Fatalf("create %s: %v", *out, err)
+		}
+		defer w.Close()
+	}
+
+	r := corpus.New(analyzers)
+	r.Workers = *workers
+	r.Pipeline = *pipeline
+
+	if err := r.WriteNDJSON(context.Background(), files, w); err != nil {
+		log.Fatalf("corpus: %v", err)
+	}
+
+	hits, misses := r.Cache.Stats()
+	log.Printf("Parsed %d replays (serializer cache: %d hits, %d misses)", len(files), hits, misses)
+}
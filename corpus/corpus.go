@@ -0,0 +1,234 @@
+// Package corpus parses many replays concurrently and collects the output of
+// analyzers attached to each of them.
+package corpus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dotabuff/manta"
+)
+
+// Analyzer attaches to a parser before it starts and returns a function
+// which produces the analyzer's output once the replay has been parsed.
+type Analyzer func(p *manta.Parser) func() (interface{}, error)
+
+// Result is the outcome of parsing a single replay. A failed replay has Error
+// set and the output of analyzers which still produced one. A truncated
+// replay has Truncated set and the output of analyzers for the part of the
+// game it holds.
+type Result struct {
+	File      string                 `json:"file"`
+	GameBuild uint32                 `json:"game_build,omitempty"`
+	Ticks     uint32                 `json:"ticks,omitempty"`
+	Truncated bool                   `json:"truncated,omitempty"`
+	Seconds   float64                `json:"parse_seconds"`
+	Error     string                 `json:"error,omitempty"`
+	Output    map[string]interface{} `json:"output,omitempty"`
+}
+
+// Runner parses replays with a pool of workers.
+type Runner struct {
+	// Workers is the number of replays parsed at the same time, defaulting
+	// to the number of CPUs.
+	Workers int
+
+	// Analyzers are attached to every parser, the output of each is stored
+	// under its name.
+	Analyzers map[string]Analyzer
+
+	// Cache shares serializers between replays of the same build. New
+	// creates one, set it to nil to build serializers for every replay.
+	Cache *manta.SerializerCache
+
+	// Pipeline is passed to Parser.EnablePipeline when non-zero.
+	Pipeline int
+}
+
+// New returns a Runner with a serializer cache and the given analyzers.
+func New(analyzers map[string]Analyzer) *Runner {
+	return &Runner{
+		Workers:   runtime.NumCPU(),
+		Analyzers: analyzers,
+		Cache:     manta.NewSerializerCache(),
+	}
+}
+
+// Run parses the given files and calls emit with each result as soon as it
+// is available, in completion order. emit is never called concurrently. A
+// replay which fails to parse does not affect the others, Run only returns
+// early when emit fails or ctx is cancelled.
+func (r *Runner) Run(ctx context.Context, files []string, emit func(Result) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := r.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				res := r.parse(file)
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, file := range files {
+			select {
+			case jobs <- file:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	for res := range results {
+		if err == nil {
+			if err = emit(res); err != nil {
+				cancel()
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// WriteNDJSON runs the given files and writes each result as a line of JSON.
+func (r *Runner) WriteNDJSON(ctx context.Context, files []string, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return r.Run(ctx, files, func(res Result) error {
+		return enc.Encode(res)
+	})
+}
+
+// parse parses a single replay, turning any failure into the Error of the
+// result.
+func (r *Runner) parse(file string) (res Result) {
+	res = Result{File: file, Output: make(map[string]interface{})}
+	start := time.Now()
+
+	defer func() {
+		if v := recover(); v != nil {
+			res.Error = fmt.Sprintf("panic: %v", v)
+		}
+		res.Seconds = time.Since(start).Seconds()
+		if len(res.Output) == 0 {
+			res.Output = nil
+		}
+	}()
+
+	f, err := os.Open(file)
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+	defer f.Close()
+
+	p, err := manta.NewStreamParser(f)
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+	if r.Cache != nil {
+		p.UseSerializerCache(r.Cache)
+	}
+	if r.Pipeline > 0 {
+		p.EnablePipeline(r.Pipeline)
+	}
+	p.AllowTruncated(true)
+
+	names := make([]string, 0, len(r.Analyzers))
+	for name := range r.Analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outputs := make(map[string]func() (interface{}, error), len(names))
+	for _, name := range names {
+		outputs[name] = r.Analyzers[name](p)
+	}
+
+	if err := p.Start(); err != nil && err != io.EOF {
+		res.Error = err.Error()
+	}
+	res.GameBuild = p.GameBuild
+	res.Ticks = p.Tick
+	res.Truncated = p.Truncated()
+
+	var errs []string
+	for _, name := range names {
+		out, err := outputs[name]()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		res.Output[name] = out
+	}
+	if len(errs) > 0 && res.Error == "" {
+		res.Error = strings.Join(errs, "; ")
+	}
+
+	return res
+}
+
+// Files expands the given paths into replay files: directories are searched
+// recursively for .dem files, other paths are used as they are.
+func Files(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		st, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(info.Name(), ".dem") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"dota2/damage"
+
+	"github.com/dotabuff/manta"
+)
+
+// runDamage implements the `damage` mode: it writes the damage and healing
+// every player of a replay dealt and received, by unit, ability, damage type
+// and category, as totals and per game minute.
+//
+//	go run . damage [-o damage.json] [replay.dem]
+func runDamage(args []string) {
+	fs := flag.NewFlagSet("damage", flag.ExitOnError)
+	out := fs.String("o", "", "write JSON to this file instead of stdout")
+	fs.Parse(args)
+
+	runReport(fs, *out, "the breakdown ends at tick %d", func(p *manta.Parser) func(io.Writer) error {
+		return damage.New(p).WriteJSON
+	})
+}
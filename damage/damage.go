@@ -0,0 +1,276 @@
+// Package damage breaks down the damage and healing of a match by player,
+// from the DAMAGE and HEAL entries of the combat log: dealt and taken by
+// unit, by ability or item, by damage type and by category of unit, as totals
+// and per game minute.
+//
+// Damage and healing of illusions and summons are attributed to the hero
+// owning them. Damage and healing received by illusions are left out, so
+// that hero damage only counts real heroes.
+package damage
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// Categories of units.
+const (
+	CategoryHero     = "hero"
+	CategoryCreep    = "creep"
+	CategoryBuilding = "building"
+	CategoryRoshan   = "roshan"
+)
+
+// Amounts are amounts of damage or healing by key.
+type Amounts map[string]int32
+
+func (a Amounts) add(key string, value int32) {
+	a[key] += value
+}
+
+// Side is the damage or healing a player dealt or received. Units are the
+// npc names of the targets for dealt amounts and of the sources for received
+// ones, Categories their categories. Attacks are the inflictor "attack",
+// lifesteal the inflictor "lifesteal".
+type Side struct {
+	Total      int32   `json:"total"`
+	Units      Amounts `json:"by_unit"`
+	Inflictors Amounts `json:"by_inflictor"`
+	Types      Amounts `json:"by_type,omitempty"`
+	Categories Amounts `json:"by_category"`
+}
+
+func newSide(types bool) Side {
+	s := Side{Units: Amounts{}, Inflictors: Amounts{}, Categories: Amounts{}}
+	if types {
+		s.Types = Amounts{}
+	}
+	return s
+}
+
+func (s *Side) add(unit, inflictor, damageType, category string, value int32) {
+	s.Total += value
+	s.Units.add(unit, value)
+	s.Inflictors.add(inflictor, value)
+	if s.Types != nil {
+		s.Types.add(damageType, value)
+	}
+	s.Categories.add(category, value)
+}
+
+// Minute are the amounts of a player during a game minute. Damage and
+// healing before the horn count towards minute 0.
+type Minute struct {
+	Minute          int   `json:"minute"`
+	DamageDealt     int32 `json:"damage_dealt"`
+	HeroDamage      int32 `json:"hero_damage"`
+	TowerDamage     int32 `json:"tower_damage"`
+	DamageTaken     int32 `json:"damage_taken"`
+	HealingDone     int32 `json:"healing_done"`
+	HealingReceived int32 `json:"healing_received"`
+}
+
+// Breakdown is the damage and healing of a player. HeroDamage is the damage
+// dealt to enemy heroes, TowerDamage the damage dealt to towers.
+type Breakdown struct {
+	Player      int32  `json:"player"`
+	Name        string `json:"name"`
+	Team        int32  `json:"team"`
+	Hero        string `json:"hero,omitempty"`
+	HeroDamage  int32  `json:"hero_damage"`
+	TowerDamage int32  `json:"tower_damage"`
+	HeroHealing int32  `json:"hero_healing"`
+
+	DamageDealt     Side `json:"damage_dealt"`
+	DamageTaken     Side `json:"damage_taken"`
+	HealingDone     Side `json:"healing_done"`
+	HealingReceived Side `json:"healing_received"`
+
+	Minutes []Minute `json:"minutes"`
+}
+
+// Result is the breakdown of every player of a match, ordered by id. Their
+// series of minutes all have the same length.
+type Result struct {
+	match.Coverage
+	Players []Breakdown `json:"players"`
+}
+
+// Tracker aggregates the damage and healing of a parser. Create it with New
+// before calling Start and read the result once parsing is done.
+type Tracker struct {
+	Match *match.Match
+
+	players map[int32]*Breakdown
+}
+
+// New returns a Tracker which aggregates the damage and healing of the
+// players of the given parser.
+func New(p *manta.Parser) *Tracker {
+	t := &Tracker{
+		Match:   match.New(p),
+		players: make(map[int32]*Breakdown),
+	}
+
+	p.Callbacks.OnCMsgDOTACombatLogEntry(t.onCombatLog)
+	return t
+}
+
+func (t *Tracker) breakdown(pl *match.Player) *Breakdown {
+	b, ok := t.players[pl.ID]
+	if !ok {
+		b = &Breakdown{
+			Player:          pl.ID,
+			DamageDealt:     newSide(true),
+			DamageTaken:     newSide(true),
+			HealingDone:     newSide(false),
+			HealingReceived: newSide(false),
+		}
+		t.players[pl.ID] = b
+	}
+	return b
+}
+
+// minute returns the counters of a breakdown for the game minute of a
+// combat log timestamp.
+func (t *Tracker) minute(b *Breakdown, ts float32) *Minute {
+	m := 0
+	if t.Match.GameStartTime() > 0 {
+		if gt := t.Match.GameTime(ts); gt > 0 {
+			m = int(gt / 60)
+		}
+	}
+	for len(b.Minutes) <= m {
+		b.Minutes = append(b.Minutes, Minute{Minute: len(b.Minutes)})
+	}
+	return &b.Minutes[m]
+}
+
+func (t *Tracker) onCombatLog(m *dota.CMsgDOTACombatLogEntry) error {
+	kind := m.GetType()
+	if kind != dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DAMAGE && kind != dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_HEAL {
+		return nil
+	}
+	if m.GetIsTargetIllusion() {
+		return nil
+	}
+
+	name := t.Match.CombatLogName
+	target := name(m.GetTargetName())
+	source := name(m.GetDamageSourceName())
+	if source == "" {
+		source = name(m.GetAttackerName())
+	}
+	var inflictor string
+	switch {
+	case m.GetInflictorName() != 0:
+		inflictor = name(m.GetInflictorName())
+	case kind == dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DAMAGE:
+		inflictor = "attack"
+	case m.GetHealFromLifesteal():
+		inflictor = "lifesteal"
+	default:
+		inflictor = "other"
+	}
+	damageType := match.DamageTypeName(m.GetDamageType())
+	if damageType == "" {
+		damageType = "other"
+	}
+	value := int32(m.GetValue())
+	ts := m.GetTimestamp()
+
+	targetCategory := category(target, m.GetIsTargetHero(), m.GetIsTargetBuilding())
+	sourceCategory := category(source, m.GetIsAttackerHero(), false)
+
+	if pl := t.Match.PlayerForHero(source); pl != nil {
+		b := t.breakdown(pl)
+		mt := t.minute(b, ts)
+		if kind == dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_HEAL {
+			b.HealingDone.add(target, inflictor, "", targetCategory, value)
+			mt.HealingDone += value
+			if targetCategory == CategoryHero && target != source {
+				b.HeroHealing += value
+			}
+		} else {
+			b.DamageDealt.add(target, inflictor, damageType, targetCategory, value)
+			mt.DamageDealt += value
+			if targetCategory == CategoryHero && m.GetAttackerTeam() != m.GetTargetTeam() {
+				b.HeroDamage += value
+				mt.HeroDamage += value
+			}
+			if targetCategory == CategoryBuilding && strings.Contains(target, "_tower") {
+				b.TowerDamage += value
+				mt.TowerDamage += value
+			}
+		}
+	}
+
+	if pl := t.Match.PlayerForHero(target); pl != nil {
+		b := t.breakdown(pl)
+		mt := t.minute(b, ts)
+		if kind == dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_HEAL {
+			b.HealingReceived.add(source, inflictor, "", sourceCategory, value)
+			mt.HealingReceived += value
+		} else {
+			b.DamageTaken.add(source, inflictor, damageType, sourceCategory, value)
+			mt.DamageTaken += value
+		}
+	}
+	return nil
+}
+
+// category returns the category of a unit from its npc name and the flags of
+// the combat log entry.
+func category(npc string, hero, building bool) string {
+	switch {
+	case match.IsRoshanNPC(npc):
+		return CategoryRoshan
+	case hero || match.IsHeroNPC(npc):
+		return CategoryHero
+	case building || match.IsBuildingNPC(npc):
+		return CategoryBuilding
+	}
+	return CategoryCreep
+}
+
+// Result returns the breakdown of every player who dealt or received damage
+// or healing.
+func (t *Tracker) Result() *Result {
+	res := &Result{Coverage: t.Match.Coverage(), Players: []Breakdown{}}
+
+	minutes := 0
+	for _, b := range t.players {
+		if len(b.Minutes) > minutes {
+			minutes = len(b.Minutes)
+		}
+	}
+
+	for _, pl := range t.Match.Players() {
+		b, ok := t.players[pl.ID]
+		if !ok {
+			continue
+		}
+		out := *b
+		out.Name, out.Team, out.Hero = pl.Name, pl.Team, t.Match.HeroName(pl)
+		out.Minutes = make([]Minute, minutes)
+		copy(out.Minutes, b.Minutes)
+		for i := len(b.Minutes); i < minutes; i++ {
+			out.Minutes[i].Minute = i
+		}
+		res.Players = append(res.Players, out)
+	}
+	return res
+}
+
+// WriteJSON writes the result as a single JSON document.
+func (t *Tracker) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Result())
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"dota2/economy"
+
+	"github.com/dotabuff/manta"
+)
+
+// runEconomy implements the `economy` mode: it writes the gold, net worth
+// and experience of every player of a replay at every game minute and
+// change, with team totals and leads, as JSON for charting.
+//
+//	go run . economy [-o economy.json] [replay.dem]
+func runEconomy(args []string) {
+	fs := flag.NewFlagSet("economy", flag.ExitOnError)
+	out := fs.String("o", "", "write JSON to this file instead of stdout")
+	fs.Parse(args)
+
+	runReport(fs, *out, "the series end at tick %d", func(p *manta.Parser) func(io.Writer) error {
+		return economy.New(p).WriteJSON
+	})
+}
@@ -0,0 +1,232 @@
+// Package economy follows the gold, net worth and experience of every player
+// over a match, from the player resource and the team data entities, as
+// series ready for charting: a point per player at every game minute and at
+// every change, team totals at every minute and the Radiant leads.
+package economy
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// Point is the economy of a player at a tick. Gold is the sum of the
+// reliable and unreliable gold.
+type Point struct {
+	Tick uint32  `json:"tick"`
+	Time float32 `json:"time"`
+	Gold int32   `json:"gold"`
+	match.Economy
+
+	serverTime float32
+	minute     int
+}
+
+// PlayerSeries is the economy of a player over the match. GPM and XPM are
+// the gold and experience earned per minute of game time.
+type PlayerSeries struct {
+	Player int32   `json:"player"`
+	Name   string  `json:"name"`
+	Team   int32   `json:"team"`
+	Hero   string  `json:"hero,omitempty"`
+	GPM    float32 `json:"gpm"`
+	XPM    float32 `json:"xpm"`
+
+	// Minutes has a point at every game minute from the horn, Changes a
+	// point whenever any value changed.
+	Minutes []Point `json:"minutes"`
+	Changes []Point `json:"changes"`
+}
+
+// TeamTotals are the sums of the economies of the players of a team.
+type TeamTotals struct {
+	Gold     int32 `json:"gold"`
+	NetWorth int32 `json:"net_worth"`
+	XP       int32 `json:"xp"`
+	LastHits int32 `json:"last_hits"`
+	Denies   int32 `json:"denies"`
+}
+
+// TeamPoint is the economy of both teams at a game minute. Leads are those of
+// the Radiant, negative when the Dire is ahead.
+type TeamPoint struct {
+	Minute       int        `json:"minute"`
+	Radiant      TeamTotals `json:"radiant"`
+	Dire         TeamTotals `json:"dire"`
+	GoldLead     int32      `json:"gold_lead"`
+	NetWorthLead int32      `json:"net_worth_lead"`
+	XPLead       int32      `json:"xp_lead"`
+}
+
+// Series is the economy of a match.
+type Series struct {
+	match.Coverage
+	Players []PlayerSeries `json:"players"`
+	Teams   []TeamPoint    `json:"teams"`
+}
+
+// Tracker records the economy of the players of a parser. Create it with New
+// before calling Start and read the series once parsing is done.
+type Tracker struct {
+	Match *match.Match
+
+	p       *manta.Parser
+	dirty   bool
+	minute  int
+	players map[int32]*PlayerSeries
+	last    map[int32]match.Economy
+}
+
+// New returns a Tracker which follows the economy of the players of the
+// given parser.
+func New(p *manta.Parser) *Tracker {
+	t := &Tracker{
+		Match:   match.New(p),
+		p:       p,
+		players: make(map[int32]*PlayerSeries),
+		last:    make(map[int32]match.Economy),
+	}
+
+	p.OnEntity(t.onEntity)
+	// Entity updates are applied before handlers of the message run, so
+	// that points hold the values of the whole tick.
+	p.Callbacks.OnCSVCMsg_PacketEntities(t.onPacketEntities)
+	return t
+}
+
+func (t *Tracker) onEntity(e *manta.Entity, op manta.EntityOp) error {
+	switch e.GetClassName() {
+	case "CDOTA_PlayerResource", "CDOTA_DataRadiant", "CDOTA_DataDire":
+		t.dirty = true
+	}
+	return nil
+}
+
+func (t *Tracker) onPacketEntities(*dota.CSVCMsg_PacketEntities) error {
+	serverTime := t.Match.ServerTime()
+
+	// Minutes are counted from the horn, once its time is known.
+	now := t.Match.GameTime(serverTime)
+	minute := t.Match.GameStartTime() > 0 && now >= float32(t.minute*60)
+	if !t.dirty && !minute {
+		return nil
+	}
+	t.dirty = false
+
+	for _, pl := range t.Match.Players() {
+		ec, ok := t.Match.Economy(pl.ID)
+		if !ok {
+			continue
+		}
+		s, ok := t.players[pl.ID]
+		if !ok {
+			s = &PlayerSeries{Player: pl.ID}
+			t.players[pl.ID] = s
+		}
+
+		pt := Point{Tick: t.p.Tick, Gold: ec.Gold(), Economy: ec, serverTime: serverTime, minute: t.minute}
+		if last, ok := t.last[pl.ID]; !ok || last != ec {
+			t.last[pl.ID] = ec
+			s.Changes = append(s.Changes, pt)
+		}
+		if minute {
+			s.Minutes = append(s.Minutes, pt)
+		}
+	}
+	if minute {
+		t.minute = int(now/60) + 1
+	}
+	return nil
+}
+
+// Series returns the economy of the match, with players ordered by id.
+func (t *Tracker) Series() *Series {
+	series := &Series{Coverage: t.Match.Coverage(), Players: []PlayerSeries{}, Teams: []TeamPoint{}}
+	// Per minute rates are over the game up to its end, not through the
+	// post-game, or up to the last tick of a replay cut before the end.
+	end := t.Match.GameEndTime()
+	if end == 0 {
+		end = t.Match.ServerTime()
+	}
+	minutes := t.Match.GameTime(end) / 60
+
+	for _, pl := range t.Match.Players() {
+		s, ok := t.players[pl.ID]
+		if !ok {
+			continue
+		}
+		ps := *s
+		ps.Name, ps.Team, ps.Hero = pl.Name, pl.Team, t.Match.HeroName(pl)
+		ps.Minutes = t.times(s.Minutes)
+		ps.Changes = t.times(s.Changes)
+		if n := len(ps.Changes); n > 0 && minutes > 0 {
+			last := ps.Changes[n-1]
+			ps.GPM = round(float32(last.EarnedGold) / minutes)
+			ps.XPM = round(float32(last.XP) / minutes)
+		}
+		series.Players = append(series.Players, ps)
+	}
+
+	teams := make(map[int]*TeamPoint)
+	for _, ps := range series.Players {
+		for _, pt := range ps.Minutes {
+			tp, ok := teams[pt.minute]
+			if !ok {
+				tp = &TeamPoint{Minute: pt.minute}
+				teams[pt.minute] = tp
+			}
+			var totals *TeamTotals
+			switch ps.Team {
+			case match.TeamRadiant:
+				totals = &tp.Radiant
+			case match.TeamDire:
+				totals = &tp.Dire
+			default:
+				continue
+			}
+			totals.Gold += pt.Gold
+			totals.NetWorth += pt.NetWorth
+			totals.XP += pt.XP
+			totals.LastHits += pt.LastHits
+			totals.Denies += pt.Denies
+		}
+	}
+	for m := 0; m < t.minute; m++ {
+		tp, ok := teams[m]
+		if !ok {
+			continue
+		}
+		tp.GoldLead = tp.Radiant.Gold - tp.Dire.Gold
+		tp.NetWorthLead = tp.Radiant.NetWorth - tp.Dire.NetWorth
+		tp.XPLead = tp.Radiant.XP - tp.Dire.XP
+		series.Teams = append(series.Teams, *tp)
+	}
+	return series
+}
+
+// times returns a copy of points with their game times, which are only known
+// once the game started.
+func (t *Tracker) times(points []Point) []Point {
+	out := make([]Point, len(points))
+	for i, pt := range points {
+		pt.Time = t.Match.GameTime(pt.serverTime)
+		out[i] = pt
+	}
+	return out
+}
+
+func round(x float32) float32 {
+	return float32(math.Round(float64(x)*10) / 10)
+}
+
+// WriteJSON writes the series as a single JSON document.
+func (t *Tracker) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Series())
+}
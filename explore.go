@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"strings"
+
+	"dota2/explorer"
+
+	"github.com/dotabuff/manta"
+)
+
+// runExplore implements the `explore` mode: it lists the entity classes of a
+// replay with their update counts, the fields of classes matching -fields
+// with their types and example values, or every change of the fields given
+// with -watch.
+//
+//	go run . explore [-format table|csv|json] [-fields CDOTA_Unit_Hero_*] [-o out.txt] [replay.dem]
+//	go run . explore -watch CDOTA_Unit_Hero_Puck.m_flMana,CDOTA_Unit_Hero_Puck.m_iHealth [replay.dem]
+func runExplore(args []string) {
+	fs := flag.NewFlagSet("explore", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table, csv or json")
+	fields := fs.String("fields", "", "list the fields of the classes matching this pattern")
+	watches := fs.String("watch", "", "comma separated Class.field expressions whose changes are listed")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	fs.Parse(args)
+
+	f, err := explorer.ParseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var ws []explorer.Watch
+	if *watches != "" {
+		for _, s := range strings.Split(*watches, ",") {
+			w, err := explorer.ParseWatch(strings.TrimSpace(s))
+			if err != nil {
+				log.Fatal(err)
+			}
+			ws = append(ws, w)
+		}
+	}
+
+	runReport(fs, *out, "entities are explored up to tick %d", func(p *manta.Parser) func(io.Writer) error {
+		x := explorer.New(p, ws...)
+		return func(w io.Writer) error {
+			switch {
+			case len(ws) > 0:
+				return x.WriteChanges(w, f)
+			case *fields != "":
+				return x.WriteFields(w, f, *fields)
+			}
+			return x.WriteClasses(w, f)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+// Package explorer describes the entities of a replay: the classes seen and
+// how often their entities are updated, the fields of each class with their
+// networked types and example values, and every change of watched fields.
+package explorer
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+)
+
+// Class is an entity class seen in a replay.
+type Class struct {
+	Name    string  `json:"class"`
+	Created int     `json:"created"`
+	Updated int     `json:"updated"`
+	Deleted int     `json:"deleted"`
+	Fields  []Field `json:"fields,omitempty"`
+}
+
+// Field is a field of an entity class. Example is the first value other
+// than the zero value seen for it, or the zero value if no other was seen.
+type Field struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Example interface{} `json:"example"`
+}
+
+// Watch selects a field of the entities of the classes matching Class, which
+// may be a pattern as accepted by path.Match, like CDOTA_Unit_Hero_*.
+type Watch struct {
+	Class string
+	Field string
+}
+
+// ParseWatch parses a watch expression such as CDOTA_Unit_Hero_Puck.m_flMana
+// or CDOTA_PlayerResource.m_vecPlayerData.0003.m_iszPlayerName: the class is
+// everything before the first dot.
+func ParseWatch(s string) (Watch, error) {
+	i := strings.Index(s, ".")
+	if i <= 0 || i == len(s)-1 {
+		return Watch{}, fmt.Errorf("explorer: bad watch expression %q, want Class.field", s)
+	}
+	w := Watch{Class: s[:i], Field: s[i+1:]}
+	if _, err := path.Match(w.Class, ""); err != nil {
+		return Watch{}, fmt.Errorf("explorer: bad class pattern %q: %v", w.Class, err)
+	}
+	return w, nil
+}
+
+func (w Watch) String() string {
+	return w.Class + "." + w.Field
+}
+
+// Matches reports whether the watch selects entities of the given class.
+func (w Watch) Matches(class string) bool {
+	ok, _ := path.Match(w.Class, class)
+	return ok
+}
+
+// Change is a new value of a watched field. The first value of an entity is
+// reported when it is created.
+type Change struct {
+	Tick   uint32      `json:"tick"`
+	Time   float32     `json:"time"`
+	Entity int32       `json:"entity"`
+	Class  string      `json:"class"`
+	Field  string      `json:"field"`
+	Value  interface{} `json:"value"`
+}
+
+// Explorer collects the entity classes and watched fields of a parser.
+// Create it with New before calling Start and read the results once parsing
+// is done.
+type Explorer struct {
+	Match *match.Match
+
+	p       *manta.Parser
+	watches []Watch
+	classes map[string]*class
+	changes []Change
+	last    map[watchKey]interface{}
+}
+
+// class is the state kept for a Class.
+type class struct {
+	Class
+	fields map[string]*Field
+}
+
+// watchKey identifies a watched field of an entity.
+type watchKey struct {
+	index int32
+	field string
+}
+
+// New returns an Explorer which follows the entities of the given parser and
+// records the changes of the given watched fields.
+func New(p *manta.Parser, watches ...Watch) *Explorer {
+	x := &Explorer{
+		Match:   match.New(p),
+		p:       p,
+		watches: watches,
+		classes: make(map[string]*class),
+		last:    make(map[watchKey]interface{}),
+	}
+	p.OnEntity(x.onEntity)
+	return x
+}
+
+func (x *Explorer) onEntity(e *manta.Entity, op manta.EntityOp) error {
+	name := e.GetClassName()
+	c, ok := x.classes[name]
+	if !ok {
+		c = &class{Class: Class{Name: name}, fields: make(map[string]*Field)}
+		x.classes[name] = c
+	}
+
+	switch {
+	case op.Flag(manta.EntityOpCreated):
+		c.Created++
+		c.sample(e)
+	case op.Flag(manta.EntityOpDeleted):
+		c.Deleted++
+		c.sample(e)
+	case op.Flag(manta.EntityOpUpdated):
+		c.Updated++
+	}
+
+	for _, w := range x.watches {
+		if w.Matches(name) {
+			x.watch(e, op, w.Field)
+		}
+	}
+	return nil
+}
+
+// sample records the fields of an entity and their values as examples.
+func (c *class) sample(e *manta.Entity) {
+	for name, v := range e.Map() {
+		f, ok := c.fields[name]
+		if !ok {
+			f = &Field{Name: name, Type: e.FieldType(name), Example: v}
+			c.fields[name] = f
+		}
+		if isZero(f.Example) && !isZero(v) {
+			f.Example = v
+		}
+	}
+}
+
+// watch records the value of a watched field if it changed.
+func (x *Explorer) watch(e *manta.Entity, op manta.EntityOp, field string) {
+	key := watchKey{e.GetIndex(), field}
+	if op.Flag(manta.EntityOpDeleted) {
+		delete(x.last, key)
+		return
+	}
+
+	v := e.Get(field)
+	if v == nil {
+		return
+	}
+	if last, ok := x.last[key]; ok && reflect.DeepEqual(last, v) {
+		return
+	}
+	x.last[key] = v
+
+	x.changes = append(x.changes, Change{
+		Tick:   x.p.Tick,
+		Time:   x.Match.GameTime(x.Match.ServerTime()),
+		Entity: e.GetIndex(),
+		Class:  e.GetClassName(),
+		Field:  field,
+		Value:  v,
+	})
+}
+
+// Classes returns the classes seen ordered by name, with the fields of those
+// matching the given pattern (as accepted by path.Match) ordered by name. An
+// empty pattern leaves out all fields.
+func (x *Explorer) Classes(pattern string) []Class {
+	// Entities still alive have their final values as examples.
+	if pattern != "" {
+		x.p.FilterEntity(func(e *manta.Entity) bool {
+			if c, ok := x.classes[e.GetClassName()]; ok {
+				c.sample(e)
+			}
+			return false
+		})
+	}
+
+	classes := make([]Class, 0, len(x.classes))
+	for _, c := range x.classes {
+		cls := c.Class
+		if ok, _ := path.Match(pattern, cls.Name); ok && pattern != "" {
+			for _, f := range c.fields {
+				cls.Fields = append(cls.Fields, *f)
+			}
+			sort.Slice(cls.Fields, func(i, j int) bool { return cls.Fields[i].Name < cls.Fields[j].Name })
+		}
+		classes = append(classes, cls)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+	return classes
+}
+
+// Changes returns the changes of watched fields in the order they happened.
+func (x *Explorer) Changes() []Change {
+	return x.changes
+}
+
+// isZero reports whether a value is the zero value of its type. Vectors are
+// zero if all of their components are.
+func isZero(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			if !rv.Index(i).IsZero() {
+				return false
+			}
+		}
+		return true
+	}
+	return rv.IsZero()
+}
@@ -0,0 +1,147 @@
+package explorer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"dota2/match"
+)
+
+// Format is an output format of the explorer.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatCSV   Format = "csv"
+	FormatJSON  Format = "json"
+)
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatTable, FormatCSV, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("explorer: unknown format %q, want table, csv or json", s)
+}
+
+// WriteClasses writes the classes seen with their update counts.
+func (x *Explorer) WriteClasses(w io.Writer, f Format) error {
+	classes := x.Classes("")
+	if f == FormatJSON {
+		return writeJSON(w, classesJSON{x.Match.Coverage(), classes})
+	}
+
+	rows := make([][]string, 0, len(classes))
+	for _, c := range classes {
+		rows = append(rows, []string{c.Name, strconv.Itoa(c.Created), strconv.Itoa(c.Updated), strconv.Itoa(c.Deleted)})
+	}
+	return writeRows(w, f, []string{"class", "created", "updated", "deleted"}, rows)
+}
+
+// WriteFields writes the fields of the classes matching the given pattern
+// with their types and example values.
+func (x *Explorer) WriteFields(w io.Writer, f Format, pattern string) error {
+	classes := []Class{}
+	for _, c := range x.Classes(pattern) {
+		if len(c.Fields) > 0 {
+			classes = append(classes, c)
+		}
+	}
+	if f == FormatJSON {
+		return writeJSON(w, classesJSON{x.Match.Coverage(), classes})
+	}
+
+	var rows [][]string
+	for _, c := range classes {
+		for _, fd := range c.Fields {
+			rows = append(rows, []string{c.Name, fd.Name, fd.Type, FormatValue(fd.Example)})
+		}
+	}
+	return writeRows(w, f, []string{"class", "field", "type", "example"}, rows)
+}
+
+// WriteChanges writes the changes of watched fields.
+func (x *Explorer) WriteChanges(w io.Writer, f Format) error {
+	changes := x.Changes()
+	if f == FormatJSON {
+		if changes == nil {
+			changes = []Change{}
+		}
+		return writeJSON(w, changesJSON{x.Match.Coverage(), changes})
+	}
+
+	rows := make([][]string, 0, len(changes))
+	for _, c := range changes {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(c.Tick), 10),
+			match.FormatGameTime(c.Time),
+			strconv.Itoa(int(c.Entity)),
+			c.Class,
+			c.Field,
+			FormatValue(c.Value),
+		})
+	}
+	return writeRows(w, f, []string{"tick", "time", "entity", "class", "field", "value"}, rows)
+}
+
+// classesJSON and changesJSON are the JSON documents written, with how much
+// of the replay they cover.
+type classesJSON struct {
+	match.Coverage
+	Classes []Class `json:"classes"`
+}
+
+type changesJSON struct {
+	match.Coverage
+	Changes []Change `json:"changes"`
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeRows writes a header and rows as an aligned table or as CSV.
+func writeRows(w io.Writer, f Format, header []string, rows [][]string) error {
+	if f == FormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		return cw.WriteAll(rows)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// FormatValue formats an entity value for a table cell: strings are quoted
+// and vectors are written as [x y z].
+func FormatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strconv.Quote(x)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case []float32:
+		parts := make([]string, len(x))
+		for i, f := range x {
+			parts[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	}
+	return fmt.Sprint(v)
+}
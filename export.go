@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"dota2/corpus"
+	"dota2/export"
+
+	"github.com/dotabuff/manta"
+)
+
+// exportTables are the outputs of the export analyzer for a replay.
+type exportTables struct {
+	Heroes []export.HeroState `json:"heroes"`
+	Events []export.Event     `json:"events"`
+}
+
+// exportAnalyzer samples the heroes of a replay every interval of game time
+// and collects its events, for the export and corpus modes.
+func exportAnalyzer(interval time.Duration) corpus.Analyzer {
+	return func(p *manta.Parser) func() (interface{}, error) {
+		x := export.New(p, interval)
+		return func() (interface{}, error) {
+			return &exportTables{Heroes: x.Heroes(), Events: x.Events()}, nil
+		}
+	}
+}
+
+// runExport implements the `export` mode: it samples every hero of the given
+// replays (or of every .dem below the given directories) and writes the
+// samples and timeline events of all of them to heroes.csv and events.csv,
+// or .parquet files, in the output directory. See package export for the
+// schema.
+//
+//	go run . export [-format csv|parquet] [-interval 1s] [-dir out] [-workers 8] [replays/...]
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "file format: csv or parquet")
+	interval := fs.Duration("interval", time.Second, "game time between two samples of the heroes")
+	dir := fs.String("dir", ".", "directory the tables are written to")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of replays parsed concurrently")
+	fs.Parse(args)
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"replay1.dem"}
+	}
+	files, err := corpus.Files(paths)
+	if err != nil {
+		log.Fatalf("corpus: %v", err)
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatalf("create %s: %v", *dir, err)
+	}
+	heroes := createTable(filepath.Join(*dir, "heroes."+string(f)), f, export.HeroState{})
+	events := createTable(filepath.Join(*dir, "events."+string(f)), f, export.Event{})
+
+	r := corpus.New(map[string]corpus.Analyzer{"export": exportAnalyzer(*interval)})
+	r.Workers = *workers
+
+	samples, count := 0, 0
+	err = r.Run(context.Background(), files, func(res corpus.Result) error {
+		if res.Error != "" {
+			log.Printf("%s: %s", res.File, res.Error)
+		}
+		if res.Truncated {
+			log.Printf("%s: replay is truncated, exported up to tick %d", res.File, res.Ticks)
+		}
+		tables, ok := res.Output["export"].(*exportTables)
+		if !ok {
+			return nil
+		}
+
+		replay := filepath.Base(res.File)
+		for i := range tables.Heroes {
+			tables.Heroes[i].Replay = replay
+			if err := heroes.Write(&tables.Heroes[i]); err != nil {
+				return err
+			}
+		}
+		for i := range tables.Events {
+			tables.Events[i].Replay = replay
+			if err := events.Write(&tables.Events[i]); err != nil {
+				return err
+			}
+		}
+		samples += len(tables.Heroes)
+		count++
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("export: %v", err)
+	}
+
+	heroes.close()
+	events.close()
+	log.Printf("Exported %d hero samples of %d replays to %s", samples, count, *dir)
+}
+
+// table is a table file being written.
+type table struct {
+	*export.TableWriter
+	f *os.File
+}
+
+func createTable(path string, format export.Format, row interface{}) *table {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("create %s: %v", path, err)
+	}
+	tw, err := export.NewTableWriter(f, format, row)
+	if err != nil {
+		log.Fatalf("create %s: %v", path, err)
+	}
+	return &table{TableWriter: tw, f: f}
+}
+
+func (t *table) close() {
+	if err := t.Close(); err != nil {
+		log.Fatalf("write %s: %v", t.f.Name(), err)
+	}
+	if err := t.f.Close(); err != nil {
+		log.Fatalf("write %s: %v", t.f.Name(), err)
+	}
+}
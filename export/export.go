@@ -0,0 +1,225 @@
+// Package export samples the state of every hero at a fixed interval and
+// collects the timeline events of a replay, as flat tables meant for data
+// analysis tools such as pandas, written as CSV or Parquet.
+//
+// The schema of the tables is stable: columns are only ever added at the end.
+//
+// heroes, one row per hero and sample:
+//
+//	replay        string   file name of the replay
+//	tick          int32    tick of the sample
+//	time          float    game time in seconds, negative before the horn
+//	player        int32    player id
+//	team          int32    2 for Radiant, 3 for Dire
+//	hero          string   entity class of the hero (CDOTA_Unit_Hero_*)
+//	x, y          float    world position
+//	health        int32
+//	max_health    int32
+//	mana          float
+//	max_mana      float
+//	level         int32
+//	xp            int32    experience earned in the game
+//	gold          int32    reliable and unreliable gold
+//	net_worth     int32
+//	items         string   item names of the six main slots, separated by
+//	                       spaces, with empty slots left out
+//	neutral_item  string   item name of the neutral item, or empty
+//	pt_stat       string   str, int or agi if the hero has Power Treads
+//	alive         boolean
+//
+// events, one row per timeline event (see package timeline):
+//
+//	replay, tick, time, type, player, target, unit, target_unit, name,
+//	text, value, x, y
+package export
+
+import (
+	"strings"
+	"time"
+
+	"dota2/match"
+	"dota2/timeline"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// HeroState is a row of the heroes table.
+type HeroState struct {
+	Replay      string  `json:"replay,omitempty" parquet:"name=replay, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Tick        int32   `json:"tick" parquet:"name=tick, type=INT32"`
+	Time        float32 `json:"time" parquet:"name=time, type=FLOAT"`
+	Player      int32   `json:"player" parquet:"name=player, type=INT32"`
+	Team        int32   `json:"team" parquet:"name=team, type=INT32"`
+	Hero        string  `json:"hero" parquet:"name=hero, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	X           float32 `json:"x" parquet:"name=x, type=FLOAT"`
+	Y           float32 `json:"y" parquet:"name=y, type=FLOAT"`
+	Health      int32   `json:"health" parquet:"name=health, type=INT32"`
+	MaxHealth   int32   `json:"max_health" parquet:"name=max_health, type=INT32"`
+	Mana        float32 `json:"mana" parquet:"name=mana, type=FLOAT"`
+	MaxMana     float32 `json:"max_mana" parquet:"name=max_mana, type=FLOAT"`
+	Level       int32   `json:"level" parquet:"name=level, type=INT32"`
+	XP          int32   `json:"xp" parquet:"name=xp, type=INT32"`
+	Gold        int32   `json:"gold" parquet:"name=gold, type=INT32"`
+	NetWorth    int32   `json:"net_worth" parquet:"name=net_worth, type=INT32"`
+	Items       string  `json:"items" parquet:"name=items, type=BYTE_ARRAY, convertedtype=UTF8"`
+	NeutralItem string  `json:"neutral_item" parquet:"name=neutral_item, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	PTStat      string  `json:"pt_stat" parquet:"name=pt_stat, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Alive       bool    `json:"alive" parquet:"name=alive, type=BOOLEAN"`
+}
+
+// Event is a row of the events table.
+type Event struct {
+	Replay     string  `json:"replay,omitempty" parquet:"name=replay, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Tick       int32   `json:"tick" parquet:"name=tick, type=INT32"`
+	Time       float32 `json:"time" parquet:"name=time, type=FLOAT"`
+	Type       string  `json:"type" parquet:"name=type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Player     int32   `json:"player" parquet:"name=player, type=INT32"`
+	Target     int32   `json:"target" parquet:"name=target, type=INT32"`
+	Unit       string  `json:"unit" parquet:"name=unit, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	TargetUnit string  `json:"target_unit" parquet:"name=target_unit, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Name       string  `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Text       string  `json:"text" parquet:"name=text, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Value      int32   `json:"value" parquet:"name=value, type=INT32"`
+	X          float32 `json:"x" parquet:"name=x, type=FLOAT"`
+	Y          float32 `json:"y" parquet:"name=y, type=FLOAT"`
+}
+
+// Exporter samples the heroes of a parser and collects its events. Create
+// it with New before calling Start and read the tables once parsing is done.
+type Exporter struct {
+	Match *match.Match
+
+	p        *manta.Parser
+	timeline *timeline.Timeline
+	interval time.Duration
+	next     uint32
+	heroes   []HeroState
+
+	// times are the server times of the samples, converted to game times
+	// once the start of the game is known.
+	times []float32
+}
+
+// New returns an Exporter which samples the heroes of the given parser every
+// interval of game time, once per tick if the interval is shorter.
+func New(p *manta.Parser, interval time.Duration) *Exporter {
+	t := timeline.New(p)
+	x := &Exporter{
+		Match:    t.Match,
+		p:        p,
+		timeline: t,
+		interval: interval,
+	}
+
+	// Entity updates are applied before handlers of the message run, so
+	// that samples see the state of the whole tick.
+	p.Callbacks.OnCSVCMsg_PacketEntities(x.onPacketEntities)
+	return x
+}
+
+// intervalTicks returns the sampling interval in ticks.
+func (x *Exporter) intervalTicks() uint32 {
+	n := uint32(x.interval.Seconds()/float64(x.Match.TickInterval()) + 0.5)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (x *Exporter) onPacketEntities(*dota.CSVCMsg_PacketEntities) error {
+	if x.p.Tick < x.next {
+		return nil
+	}
+	n := x.intervalTicks()
+	x.next = (x.p.Tick/n + 1) * n
+
+	now := x.Match.ServerTime()
+	for _, pl := range x.Match.Players() {
+		hero := x.p.FindEntityByHandle(pl.HeroHandle)
+		if pl.HeroHandle == 0 || hero == nil {
+			continue
+		}
+		x.heroes = append(x.heroes, x.sample(pl, hero))
+		x.times = append(x.times, now)
+	}
+	return nil
+}
+
+// sample returns the state of the hero of a player.
+func (x *Exporter) sample(pl *match.Player, hero *manta.Entity) HeroState {
+	s := HeroState{
+		Tick:   int32(x.p.Tick),
+		Player: pl.ID,
+		Team:   pl.Team,
+		Hero:   hero.GetClassName(),
+	}
+	s.X, s.Y, _ = match.Position(hero)
+	s.Health, _ = hero.GetInt32("m_iHealth")
+	s.MaxHealth, _ = hero.GetInt32("m_iMaxHealth")
+	s.Mana, _ = hero.GetFloat32("m_flMana")
+	s.MaxMana, _ = hero.GetFloat32("m_flMaxMana")
+	s.Level, _ = hero.GetInt32("m_iCurrentLevel")
+	if life, ok := match.Int(hero, "m_lifeState"); ok {
+		s.Alive = life == 0
+	} else {
+		s.Alive = s.Health > 0
+	}
+
+	if ec, ok := x.Match.Economy(pl.ID); ok {
+		s.XP = ec.XP
+		s.Gold = ec.Gold()
+		s.NetWorth = ec.NetWorth
+		if ec.Level > 0 {
+			s.Level = ec.Level
+		}
+	}
+
+	var items []string
+	for slot := match.SlotInventory; slot < match.SlotBackpack; slot++ {
+		item := match.Item(x.p, hero, slot)
+		if item == nil {
+			continue
+		}
+		items = append(items, match.ItemName(x.p, item))
+		if item.GetClassName() == match.PowerTreadsClass {
+			s.PTStat, _ = match.PowerTreadsStat(item)
+		}
+	}
+	s.Items = strings.Join(items, " ")
+	if item := match.Item(x.p, hero, match.SlotNeutral); item != nil {
+		s.NeutralItem = match.ItemName(x.p, item)
+	}
+	return s
+}
+
+// Heroes returns the samples of the heroes, ordered by tick and player.
+func (x *Exporter) Heroes() []HeroState {
+	for i := range x.heroes {
+		x.heroes[i].Time = x.Match.GameTime(x.times[i])
+	}
+	return x.heroes
+}
+
+// Events returns the events of the timeline.
+func (x *Exporter) Events() []Event {
+	timeline := x.timeline.Events()
+	events := make([]Event, len(timeline))
+	for i, e := range timeline {
+		events[i] = Event{
+			Tick:       int32(e.Tick),
+			Time:       e.Time,
+			Type:       string(e.Kind),
+			Player:     e.Player,
+			Target:     e.Target,
+			Unit:       e.Unit,
+			TargetUnit: e.TargetUnit,
+			Name:       e.Name,
+			Text:       e.Text,
+			Value:      e.Value,
+			X:          e.X,
+			Y:          e.Y,
+		}
+	}
+	return events
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/stretchr/testify/assert"
+)
+
+func field(name, typ string) manta.BuilderField {
+	return manta.BuilderField{Name: name, Type: typ}
+}
+
+func TestExportAlive(t *testing.T) {
+	assert := assert.New(t)
+
+	// A hero is dead as soon as its life state changes, before its health
+	// is updated. The life state is networked as a uint8.
+	b := manta.NewReplayBuilder()
+	b.Class("CDOTA_PlayerResource",
+		field("m_vecPlayerData.0000.m_iszPlayerName", "char[128]"),
+		field("m_vecPlayerData.0000.m_iPlayerTeam", "int32"),
+	)
+	b.Class("CDOTA_Unit_Hero_AntiMage",
+		field("m_iPlayerID", "int32"),
+		field("m_iHealth", "int32"),
+		field("m_lifeState", "uint8"),
+	)
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{
+		"m_vecPlayerData.0000.m_iszPlayerName": "alice",
+		"m_vecPlayerData.0000.m_iPlayerTeam":   int32(match.TeamRadiant),
+	})
+	b.Create(10, "CDOTA_Unit_Hero_AntiMage", map[string]interface{}{"m_iPlayerID": int32(0), "m_iHealth": int32(640)})
+	b.Advance(30)
+	b.Update(10, map[string]interface{}{"m_lifeState": uint8(1)})
+	b.Advance(30)
+	b.Update(10, map[string]interface{}{"m_iHealth": int32(0), "m_lifeState": uint8(2)})
+	b.Advance(30)
+	b.Update(10, map[string]interface{}{"m_iHealth": int32(640), "m_lifeState": uint8(0)})
+	b.Advance(1)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := manta.NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	x := New(p, time.Second)
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	var alive []bool
+	for _, s := range x.Heroes() {
+		alive = append(alive, s.Alive)
+	}
+	assert.Equal([]bool{true, false, false, true}, alive)
+}
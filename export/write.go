@@ -0,0 +1,112 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Format is a file format of the tables.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatCSV, FormatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("export: unknown format %q, want csv or parquet", s)
+}
+
+// TableWriter writes the rows of a table, either HeroState or Event rows, to
+// a CSV or Parquet file. The CSV header and Parquet schema are derived from
+// the parquet tags of the row type.
+type TableWriter struct {
+	typ reflect.Type
+	csv *csv.Writer
+	pq  *writer.ParquetWriter
+}
+
+// NewTableWriter returns a TableWriter for rows of the same type as row.
+func NewTableWriter(w io.Writer, f Format, row interface{}) (*TableWriter, error) {
+	tw := &TableWriter{typ: reflect.Indirect(reflect.ValueOf(row)).Type()}
+
+	switch f {
+	case FormatCSV:
+		tw.csv = csv.NewWriter(w)
+		if err := tw.csv.Write(columns(tw.typ)); err != nil {
+			return nil, err
+		}
+	case FormatParquet:
+		pq, err := writer.NewParquetWriterFromWriter(w, reflect.New(tw.typ).Interface(), 1)
+		if err != nil {
+			return nil, err
+		}
+		pq.CompressionType = parquet.CompressionCodec_SNAPPY
+		tw.pq = pq
+	default:
+		return nil, fmt.Errorf("export: unknown format %q", f)
+	}
+	return tw, nil
+}
+
+// Write writes a row.
+func (tw *TableWriter) Write(row interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Type() != tw.typ {
+		return fmt.Errorf("export: row of type %s written to a table of %s", v.Type(), tw.typ)
+	}
+	if tw.pq != nil {
+		return tw.pq.Write(v.Interface())
+	}
+
+	record := make([]string, v.NumField())
+	for i := range record {
+		record[i] = formatCSV(v.Field(i).Interface())
+	}
+	return tw.csv.Write(record)
+}
+
+// Close writes whatever is buffered, and the footer of Parquet files. It
+// does not close the underlying writer.
+func (tw *TableWriter) Close() error {
+	if tw.pq != nil {
+		return tw.pq.WriteStop()
+	}
+	tw.csv.Flush()
+	return tw.csv.Error()
+}
+
+// columns returns the column names of a row type from its parquet tags.
+func columns(t reflect.Type) []string {
+	names := make([]string, t.NumField())
+	for i := range names {
+		for _, part := range strings.Split(t.Field(i).Tag.Get("parquet"), ",") {
+			if name := strings.TrimSpace(part); strings.HasPrefix(name, "name=") {
+				names[i] = strings.TrimPrefix(name, "name=")
+			}
+		}
+	}
+	return names
+}
+
+func formatCSV(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"dota2/inspector"
+)
+
+// runInspect implements the `inspect` mode: an interactive prompt which steps
+// through a replay and inspects its entities, combat log and string tables.
+// Commands are read from stdin, enter help to list them.
+//
+//	go run . inspect [replay.dem]
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Parse(args)
+
+	path := "replay1.dem"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	in, err := inspector.New(func() (io.ReadCloser, error) { return os.Open(path) }, os.Stdout)
+	if err != nil {
+		log.Fatalf("open %s: %v", path, err)
+	}
+	defer in.Close()
+
+	if err := in.Run(os.Stdin); err != nil {
+		log.Fatalf("read commands: %v", err)
+	}
+}
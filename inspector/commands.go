@@ -0,0 +1,380 @@
+package inspector
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"dota2/explorer"
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+)
+
+const help = `commands:
+  step [n]                    parse n ticks (1 by default)
+  goto <tick>                 move to a tick, rewinding the replay if needed
+  continue                    parse until a watched field changes
+  tick                        print the current tick and game time
+  ls [class] [field op value ...]
+                              list the entities of the classes matching a
+                              pattern like CDOTA_Unit_Hero_*, whose fields
+                              compare to values with = != < > <= >= or ~
+                              (contains), like m_iHealth<200
+  show <index> [field]        print the fields of an entity, optionally only
+                              those containing a text
+  combatlog [text]            search the combat log parsed so far, the last
+                              20 entries without a text
+  tables                      list the string tables
+  table <name> [text]         list the entries of a string table, optionally
+                              only those whose key contains a text
+  watch [Class.field]         watch a field, or list the watches
+  unwatch <n|all>             remove a watch
+  quit
+An empty line repeats the last command.
+`
+
+// Exec runs a single command and reports whether it was quit.
+func (in *Inspector) Exec(line string) (quit bool, err error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "help", "h", "?":
+		fmt.Fprint(in.out, help)
+	case "quit", "q", "exit":
+		return true, nil
+	case "step", "s", "next", "n":
+		n := uint64(1)
+		if len(args) > 0 {
+			if n, err = strconv.ParseUint(args[0], 10, 32); err != nil {
+				return false, fmt.Errorf("bad tick count %q", args[0])
+			}
+		}
+		err = in.advance(in.p.Tick+uint32(n), false)
+	case "goto", "g", "jump":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: goto <tick>")
+		}
+		tick, perr := strconv.ParseUint(args[0], 10, 32)
+		if perr != nil {
+			return false, fmt.Errorf("bad tick %q", args[0])
+		}
+		err = in.Goto(uint32(tick))
+	case "continue", "c":
+		if len(in.watches) == 0 {
+			return false, fmt.Errorf("no watches, add one with watch Class.field")
+		}
+		err = in.advance(^uint32(0), true)
+	case "tick", "time":
+		fmt.Fprintf(in.out, "tick %d, game time %s\n", in.p.Tick, match.FormatGameTime(in.gameTime()))
+	case "ls", "entities":
+		err = in.list(args)
+	case "show", "print", "p":
+		err = in.show(args)
+	case "combatlog", "cl":
+		in.searchCombatLog(strings.Join(args, " "))
+	case "tables":
+		in.tables()
+	case "table":
+		err = in.table(args)
+	case "watch", "w":
+		err = in.watch(args)
+	case "unwatch":
+		err = in.unwatch(args)
+	default:
+		return false, fmt.Errorf("unknown command %q, try help", cmd)
+	}
+	return false, err
+}
+
+// filter is a condition on a field of the entities listed by ls.
+type filter struct {
+	field string
+	op    string
+	value string
+}
+
+// ops are the comparison operators of filters, longest first.
+var ops = []string{"<=", ">=", "!=", "=", "<", ">", "~"}
+
+func parseFilter(s string) (filter, error) {
+	for i := 0; i < len(s); i++ {
+		for _, op := range ops {
+			if i > 0 && strings.HasPrefix(s[i:], op) {
+				return filter{field: s[:i], op: op, value: s[i+len(op):]}, nil
+			}
+		}
+	}
+	return filter{}, fmt.Errorf("bad filter %q, want field op value", s)
+}
+
+// match reports whether an entity has the field of the filter with a
+// matching value. Numbers are compared as numbers, anything else as text.
+func (f filter) match(e *manta.Entity) bool {
+	v := e.Get(f.field)
+	if v == nil {
+		return false
+	}
+
+	s := fmt.Sprint(v)
+	if str, ok := v.(string); ok {
+		s = str
+	}
+	if f.op == "~" {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(f.value))
+	}
+
+	var c int
+	x, xok := number(v)
+	y, yerr := strconv.ParseFloat(f.value, 64)
+	if b, ok := v.(bool); ok {
+		xok, x = true, 0
+		if b {
+			x = 1
+		}
+		if yb, err := strconv.ParseBool(f.value); err == nil {
+			yerr, y = nil, 0
+			if yb {
+				y = 1
+			}
+		}
+	}
+	switch {
+	case xok && yerr == nil && x < y:
+		c = -1
+	case xok && yerr == nil && x > y:
+		c = 1
+	case xok && yerr == nil:
+		c = 0
+	default:
+		c = strings.Compare(s, f.value)
+	}
+
+	switch f.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	default:
+		return c >= 0
+	}
+}
+
+// number converts a numeric entity value.
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	}
+	return 0, false
+}
+
+func (in *Inspector) list(args []string) error {
+	pattern := "*"
+	if len(args) > 0 && !strings.ContainsAny(args[0], "=<>!~") {
+		pattern, args = args[0], args[1:]
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("bad class pattern %q: %v", pattern, err)
+	}
+
+	filters := make([]filter, 0, len(args))
+	for _, a := range args {
+		f, err := parseFilter(a)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+
+	entities := in.p.FilterEntity(func(e *manta.Entity) bool {
+		if e == nil {
+			return false
+		}
+		if ok, _ := path.Match(pattern, e.GetClassName()); !ok {
+			return false
+		}
+		for _, f := range filters {
+			if !f.match(e) {
+				return false
+			}
+		}
+		return true
+	})
+	sort.Slice(entities, func(i, j int) bool { return entities[i].GetIndex() < entities[j].GetIndex() })
+
+	tw := tabwriter.NewWriter(in.out, 0, 8, 2, ' ', 0)
+	for _, e := range entities {
+		row := []string{strconv.Itoa(int(e.GetIndex())), e.GetClassName()}
+		for _, f := range filters {
+			row = append(row, f.field+"="+explorer.FormatValue(e.Get(f.field)))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+	fmt.Fprintf(in.out, "%d entities\n", len(entities))
+	return nil
+}
+
+func (in *Inspector) show(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: show <index> [field]")
+	}
+	index, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad entity index %q", args[0])
+	}
+	e := in.p.FindEntity(int32(index))
+	if e == nil {
+		return fmt.Errorf("no entity %d at tick %d", index, in.p.Tick)
+	}
+	text := ""
+	if len(args) == 2 {
+		text = strings.ToLower(args[1])
+	}
+
+	fields := e.Map()
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if strings.Contains(strings.ToLower(name), text) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(in.out, "#%d %s serial %d handle %d\n", e.GetIndex(), e.GetClassName(), e.GetSerial(), e.GetHandle())
+	tw := tabwriter.NewWriter(in.out, 0, 8, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, e.FieldType(name), explorer.FormatValue(fields[name]))
+	}
+	return tw.Flush()
+}
+
+// searchCombatLog prints the combat log entries parsed so far which contain
+// a text, or the last ones without a text.
+func (in *Inspector) searchCombatLog(text string) {
+	entries := in.combatLog
+	if text == "" {
+		if len(entries) > 20 {
+			entries = entries[len(entries)-20:]
+		}
+	} else {
+		text = strings.ToLower(text)
+		var found []combatLogEntry
+		for _, c := range entries {
+			if strings.Contains(strings.ToLower(c.text), text) {
+				found = append(found, c)
+			}
+		}
+		entries = found
+	}
+
+	for _, c := range entries {
+		fmt.Fprintf(in.out, "%d\t%s\n", c.tick, c.text)
+	}
+	fmt.Fprintf(in.out, "%d of %d entries\n", len(entries), len(in.combatLog))
+}
+
+func (in *Inspector) tables() {
+	tw := tabwriter.NewWriter(in.out, 0, 8, 2, ' ', 0)
+	for _, name := range in.p.StringTableNames() {
+		entries, _ := in.p.StringTableEntries(name)
+		fmt.Fprintf(tw, "%s\t%d entries\n", name, len(entries))
+	}
+	tw.Flush()
+}
+
+func (in *Inspector) table(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: table <name> [text]")
+	}
+	entries, ok := in.p.StringTableEntries(args[0])
+	if !ok {
+		return fmt.Errorf("no string table %q at tick %d", args[0], in.p.Tick)
+	}
+	text := ""
+	if len(args) == 2 {
+		text = strings.ToLower(args[1])
+	}
+
+	n := 0
+	tw := tabwriter.NewWriter(in.out, 0, 8, 2, ' ', 0)
+	for _, t := range entries {
+		if !strings.Contains(strings.ToLower(t.Key), text) {
+			continue
+		}
+		n++
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", t.Index, strconv.Quote(t.Key), formatTableValue(t.Value))
+	}
+	tw.Flush()
+	fmt.Fprintf(in.out, "%d of %d entries\n", n, len(entries))
+	return nil
+}
+
+// formatTableValue formats the value of a string table entry, which is
+// either text or an encoded protobuf.
+func formatTableValue(v []byte) string {
+	switch {
+	case len(v) == 0:
+		return ""
+	case utf8.Valid(v) && !strings.ContainsAny(string(v), "\x00\r\n"):
+		return strconv.Quote(string(v))
+	}
+	return fmt.Sprintf("%d bytes", len(v))
+}
+
+func (in *Inspector) watch(args []string) error {
+	if len(args) == 0 {
+		for i, w := range in.watches {
+			fmt.Fprintf(in.out, "%d\t%s\n", i+1, w)
+		}
+		return nil
+	}
+
+	for _, a := range args {
+		w, err := explorer.ParseWatch(a)
+		if err != nil {
+			return err
+		}
+		in.watches = append(in.watches, w)
+	}
+	in.seedWatches()
+	return nil
+}
+
+func (in *Inspector) unwatch(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: unwatch <n|all>")
+	}
+	if args[0] == "all" {
+		in.watches = nil
+		return nil
+	}
+	i, err := strconv.Atoi(args[0])
+	if err != nil || i < 1 || i > len(in.watches) {
+		return fmt.Errorf("no watch %q, list them with watch", args[0])
+	}
+	in.watches = append(in.watches[:i-1], in.watches[i:]...)
+	return nil
+}
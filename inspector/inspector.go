@@ -0,0 +1,258 @@
+// Package inspector is an interactive replay inspector: it parses a replay
+// one step at a time and, between steps, lists entities and prints their
+// fields, searches the combat log and the string tables, and pauses when
+// watched fields change.
+package inspector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"dota2/explorer"
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// Opener opens the replay being inspected. It is called again to rewind.
+type Opener func() (io.ReadCloser, error)
+
+// Inspector holds a parser paused between two outer messages of a replay.
+type Inspector struct {
+	open  Opener
+	out   io.Writer
+	r     io.ReadCloser
+	p     *manta.Parser
+	match *match.Match
+
+	watches []explorer.Watch
+	last    map[watchKey]interface{}
+	hits    []explorer.Change
+
+	combatLog []combatLogEntry
+	ended     bool
+}
+
+// watchKey identifies a watched field of an entity.
+type watchKey struct {
+	index int32
+	field string
+}
+
+// combatLogEntry is a combat log entry parsed so far, formatted for display.
+type combatLogEntry struct {
+	tick uint32
+	text string
+}
+
+// New opens the replay and returns an Inspector paused before its first
+// message, which writes its output to out.
+func New(open Opener, out io.Writer) (*Inspector, error) {
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+	p, err := manta.NewStreamParser(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	in := &Inspector{open: open, out: out, r: r, p: p}
+	in.attach()
+	return in, nil
+}
+
+// Close closes the replay.
+func (in *Inspector) Close() error {
+	return in.r.Close()
+}
+
+// Parser returns the parser of the replay, for inspecting it further.
+func (in *Inspector) Parser() *manta.Parser {
+	return in.p
+}
+
+// attach registers the handlers of the inspector, which Reset removes.
+func (in *Inspector) attach() {
+	in.match = match.New(in.p)
+	in.last = make(map[watchKey]interface{})
+	in.combatLog = in.combatLog[:0]
+	in.ended = false
+	in.p.OnEntity(in.onEntity)
+	in.p.Callbacks.OnCMsgDOTACombatLogEntry(in.onCombatLog)
+}
+
+// rewind reopens the replay and parses it again from the start.
+func (in *Inspector) rewind() error {
+	r, err := in.open()
+	if err != nil {
+		return err
+	}
+	in.r.Close()
+	in.r = r
+	if err := in.p.Reset(r); err != nil {
+		return err
+	}
+	in.attach()
+	return nil
+}
+
+// gameTime returns the current game time.
+func (in *Inspector) gameTime() float32 {
+	return in.match.GameTime(in.match.ServerTime())
+}
+
+func (in *Inspector) onEntity(e *manta.Entity, op manta.EntityOp) error {
+	name := e.GetClassName()
+	for _, w := range in.watches {
+		if !w.Matches(name) {
+			continue
+		}
+
+		key := watchKey{e.GetIndex(), w.Field}
+		if op.Flag(manta.EntityOpDeleted) {
+			delete(in.last, key)
+			continue
+		}
+		v := e.Get(w.Field)
+		if v == nil {
+			continue
+		}
+		if last, ok := in.last[key]; ok && reflect.DeepEqual(last, v) {
+			continue
+		}
+		in.last[key] = v
+
+		in.hits = append(in.hits, explorer.Change{
+			Tick:   in.p.Tick,
+			Time:   in.gameTime(),
+			Entity: e.GetIndex(),
+			Class:  name,
+			Field:  w.Field,
+			Value:  v,
+		})
+	}
+	return nil
+}
+
+func (in *Inspector) onCombatLog(m *dota.CMsgDOTACombatLogEntry) error {
+	name := in.match.CombatLogName
+	kind := strings.TrimPrefix(m.GetType().String(), "DOTA_COMBATLOG_")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", match.FormatGameTime(in.match.GameTime(m.GetTimestamp())), kind, name(m.GetAttackerName()))
+	if target := name(m.GetTargetName()); target != "" {
+		fmt.Fprintf(&b, " -> %s", target)
+	}
+	if inflictor := name(m.GetInflictorName()); inflictor != "" {
+		fmt.Fprintf(&b, " with %s", inflictor)
+	}
+	if v := m.GetValue(); v != 0 {
+		fmt.Fprintf(&b, " (%d)", v)
+	}
+
+	in.combatLog = append(in.combatLog, combatLogEntry{tick: in.p.Tick, text: b.String()})
+	return nil
+}
+
+// advance parses messages until the tick reaches target or the replay ends.
+// With pause set it also stops after a message which changed a watched field.
+func (in *Inspector) advance(target uint32, pause bool) error {
+	if in.ended {
+		fmt.Fprintf(in.out, "the replay has ended, goto an earlier tick to rewind\n")
+		return nil
+	}
+	for !in.ended {
+		in.hits = in.hits[:0]
+		err := in.p.Next()
+		for _, c := range in.hits {
+			fmt.Fprintf(in.out, "watch: tick %d %s #%d %s.%s = %s\n",
+				c.Tick, match.FormatGameTime(c.Time), c.Entity, c.Class, c.Field, explorer.FormatValue(c.Value))
+		}
+
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			in.ended = true
+			if in.p.Truncated() {
+				fmt.Fprintf(in.out, "replay is truncated, it ends at tick %d\n", in.p.Tick)
+			} else {
+				fmt.Fprintf(in.out, "end of replay at tick %d\n", in.p.Tick)
+			}
+			return nil
+		case err != nil:
+			return err
+		case in.p.Tick >= target:
+			return nil
+		case pause && len(in.hits) > 0:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Goto moves to the first message at or after the given tick, rewinding the
+// replay if the tick has already been parsed.
+func (in *Inspector) Goto(tick uint32) error {
+	if tick < in.p.Tick {
+		if err := in.rewind(); err != nil {
+			return err
+		}
+		// Watched fields are only reported again after the target.
+		watches := in.watches
+		in.watches = nil
+		defer func() {
+			in.watches = watches
+			in.seedWatches()
+		}()
+	}
+	return in.advance(tick, false)
+}
+
+// seedWatches records the current values of the watched fields, so that only
+// later changes are reported.
+func (in *Inspector) seedWatches() {
+	in.p.FilterEntity(func(e *manta.Entity) bool {
+		for _, w := range in.watches {
+			if w.Matches(e.GetClassName()) {
+				if v := e.Get(w.Field); v != nil {
+					in.last[watchKey{e.GetIndex(), w.Field}] = v
+				}
+			}
+		}
+		return false
+	})
+}
+
+// Run reads commands from r until it ends or quit is entered, printing a
+// prompt with the current tick before each. An empty line repeats the last
+// command, which makes stepping through a replay easy.
+func (in *Inspector) Run(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	last := ""
+	for {
+		fmt.Fprintf(in.out, "[tick %d %s]> ", in.p.Tick, match.FormatGameTime(in.gameTime()))
+		if !s.Scan() {
+			fmt.Fprintln(in.out)
+			return s.Err()
+		}
+
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+
+		quit, err := in.Exec(line)
+		if err != nil {
+			fmt.Fprintf(in.out, "error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
@@ -10,6 +10,47 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stats":
+			runStats(os.Args[2:])
+			return
+		case "corpus":
+			runCorpus(os.Args[2:])
+			return
+		case "clip":
+			runClip(os.Args[2:])
+			return
+		case "anonymize":
+			runAnonymize(os.Args[2:])
+			return
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "explore":
+			runExplore(os.Args[2:])
+			return
+		case "inspect":
+			runInspect(os.Args[2:])
+			return
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "economy":
+			runEconomy(os.Args[2:])
+			return
+		case "recap":
+			runRecap(os.Args[2:])
+			return
+		case "damage":
+			runDamage(os.Args[2:])
+			return
+		}
+	}
+
 	f, err := os.Open("replay1.dem")
 	if err != nil {
 		log.Fatalf("open: %v", err)
@@ -26,6 +67,10 @@ func main() {
 		log.Fatalf("NewStreamParser: %v", err)
 	}
 
+	// Only chat and the combat log are used, which need the CombatLogNames
+	// string table but not entities.
+	p.SetSubsystems(manta.SubsystemStringTables)
+
 	// Map to store entity index -> ability name
 	// Note: This is a simplified approach. In practice, extracting ability names
 	// from PacketEntities can be complex and may require additional parsing.
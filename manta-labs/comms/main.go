@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"dota2/comms"
+
+	"github.com/dotabuff/manta"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "write JSON instead of a readable transcript")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: comms [-json] [replay.dem]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Only the twelve stock chat wheel phrases are known, others are written as \"chat wheel #<id>\".\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	path := "../replay1.dem"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	p, err := manta.NewStreamParser(f)
+	if err != nil {
+		log.Fatalf("NewStreamParser: %v", err)
+	}
+
+	c := comms.New(p)
+
+	if err := p.Start(); err != nil && err != io.EOF {
+		log.Fatalf("parse error: %v", err)
+	}
+
+	if *asJSON {
+		err = c.WriteJSON(os.Stdout)
+	} else {
+		err = c.WriteTranscript(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("write: %v", err)
+	}
+}
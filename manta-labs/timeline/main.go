@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"dota2/timeline"
+
+	"github.com/dotabuff/manta"
+)
+
+func main() {
+	path := "../replay1.dem"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	p, err := manta.NewStreamParser(f)
+	if err != nil {
+		log.Fatalf("NewStreamParser: %v", err)
+	}
+
+	t := timeline.New(p)
+
+	if err := p.Start(); err != nil && err != io.EOF {
+		log.Fatalf("parse error: %v", err)
+	}
+
+	out, err := os.Create("timeline.json")
+	if err != nil {
+		log.Fatalf("create timeline.json: %v", err)
+	}
+	defer out.Close()
+
+	if err := t.WriteJSON(out); err != nil {
+		log.Fatalf("write timeline.json: %v", err)
+	}
+
+	log.Printf("Wrote %d events to timeline.json", len(t.Events()))
+}
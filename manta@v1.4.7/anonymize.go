@@ -0,0 +1,559 @@
+package manta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+// anonymizedChat replaces the text of chat messages.
+const anonymizedChat = ""
+
+// anonymizedKind tells how a value is anonymized.
+type anonymizedKind int
+
+const (
+	anonymizedName anonymizedKind = iota + 1
+	anonymizedSteamID
+	anonymizedText
+)
+
+// anonymizedFields maps the names of entity fields holding player details to
+// how they are anonymized. Elements of arrays use the name of the array.
+var anonymizedFields = map[string]anonymizedKind{
+	"m_iszPlayerName":   anonymizedName,
+	"m_iszPlayerNames":  anonymizedName,
+	"m_steamID":         anonymizedSteamID,
+	"m_iPlayerSteamID":  anonymizedSteamID,
+	"m_iPlayerSteamIDs": anonymizedSteamID,
+	"m_iAccountID":      anonymizedSteamID,
+	"m_unAccountID":     anonymizedSteamID,
+}
+
+// anonymizedGameEvents maps the names of the game events carrying player
+// details to the keys holding them and how they are anonymized. Keys of
+// other events, such as the names of abilities and items, are left alone.
+var anonymizedGameEvents = map[string]map[string]anonymizedKind{
+	"player_connect": {
+		"name":      anonymizedName,
+		"networkid": anonymizedSteamID,
+		"xuid":      anonymizedSteamID,
+		"address":   anonymizedText,
+	},
+	"player_disconnect": {
+		"name":      anonymizedName,
+		"networkid": anonymizedSteamID,
+		"xuid":      anonymizedSteamID,
+	},
+	"player_info": {
+		"name":      anonymizedName,
+		"networkid": anonymizedSteamID,
+		"steamid":   anonymizedSteamID,
+	},
+	"player_changename": {
+		"oldname": anonymizedName,
+		"newname": anonymizedName,
+	},
+	"player_chat": {
+		"text": anonymizedText,
+	},
+	"player_say": {
+		"text": anonymizedText,
+	},
+}
+
+// Anonymize writes the replay read from r to w with player names, steam IDs,
+// chat text, userinfo string table entries and the server and client names
+// of the file header replaced. Each name and steam
+// ID is replaced by the same alias throughout the replay, in order of
+// appearance, as in "Player 1". Everything else, including the encoding of
+// other entity fields, is copied as it is, so that the replay parses to the
+// same game state.
+func Anonymize(r io.Reader, w io.Writer) error {
+	p, err := NewStreamParser(r)
+	if err != nil {
+		return err
+	}
+
+	// Entities are read by the anonymizer, which only needs their classes.
+	p.FilterEntityClasses(func(string) bool { return false })
+
+	a := &anonymizer{
+		p:        p,
+		w:        bufio.NewWriter(w),
+		names:    make(map[string]string),
+		accounts: make(map[uint32]uint32),
+		classes:  make(map[int32]*class),
+	}
+
+	if _, err := a.w.Write(magicSource2); err != nil {
+		return err
+	}
+	if _, err := a.w.Write(make([]byte, 8)); err != nil {
+		return err
+	}
+
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		return a.onDemoMessage(t, tick, buf, p.lastOuterMessage.compressed)
+	})
+	if err := p.Start(); err != nil {
+		return err
+	}
+
+	return a.w.Flush()
+}
+
+// anonymizer rewrites the outer messages of a replay as the parser reads
+// them.
+type anonymizer struct {
+	p        *Parser
+	w        *bufio.Writer
+	names    map[string]string
+	accounts map[uint32]uint32
+	classes  map[int32]*class
+	tables   []*stringTable
+}
+
+// name returns the alias of a player name.
+func (a *anonymizer) name(s string) string {
+	if s == "" {
+		return s
+	}
+	if alias, ok := a.names[s]; ok {
+		return alias
+	}
+	alias := fmt.Sprintf("Player %d", len(a.names)+1)
+	a.names[s] = alias
+	return alias
+}
+
+// steamID returns the alias of a steam ID, keeping its universe and type
+// bits, or of a 32-bit account ID.
+func (a *anonymizer) steamID(x uint64) uint64 {
+	account := uint32(x)
+	if account == 0 {
+		return x
+	}
+	alias, ok := a.accounts[account]
+	if !ok {
+		alias = uint32(len(a.accounts) + 1)
+		a.accounts[account] = alias
+	}
+	return x&^0xffffffff | uint64(alias)
+}
+
+// networkID returns the alias of a network ID such as "[U:1:22202]".
+func (a *anonymizer) networkID(s string) string {
+	var account uint64
+	if _, err := fmt.Sscanf(s, "[U:1:%d]", &account); err != nil {
+		return s
+	}
+	return fmt.Sprintf("[U:1:%d]", a.steamID(account))
+}
+
+// onDemoMessage writes an anonymized outer message.
+func (a *anonymizer) onDemoMessage(t int32, tick uint32, buf []byte, compressed bool) error {
+	var m proto.Message
+	switch dota.EDemoCommands(t) {
+	case dota.EDemoCommands_DEM_Packet, dota.EDemoCommands_DEM_SignonPacket:
+		msg := &dota.CDemoPacket{}
+		if err := proto.Unmarshal(buf, msg); err != nil {
+			return err
+		}
+		data, err := a.packet(msg.GetData())
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+		m = msg
+
+	case dota.EDemoCommands_DEM_FullPacket:
+		msg := &dota.CDemoFullPacket{}
+		if err := proto.Unmarshal(buf, msg); err != nil {
+			return err
+		}
+		if msg.StringTable != nil {
+			a.stringTables(msg.StringTable)
+		}
+		if msg.Packet != nil {
+			data, err := a.packet(msg.Packet.GetData())
+			if err != nil {
+				return err
+			}
+			msg.Packet.Data = data
+		}
+		m = msg
+
+	case dota.EDemoCommands_DEM_StringTables:
+		msg := &dota.CDemoStringTables{}
+		if err := proto.Unmarshal(buf, msg); err != nil {
+			return err
+		}
+		a.stringTables(msg)
+		m = msg
+
+	case dota.EDemoCommands_DEM_FileHeader:
+		msg := &dota.CDemoFileHeader{}
+		if err := proto.Unmarshal(buf, msg); err != nil {
+			return err
+		}
+		if msg.ServerName != nil {
+			msg.ServerName = proto.String(anonymizedChat)
+		}
+		if msg.ClientName != nil {
+			msg.ClientName = proto.String(anonymizedChat)
+		}
+		m = msg
+
+	case dota.EDemoCommands_DEM_FileInfo:
+		msg := &dota.CDemoFileInfo{}
+		if err := proto.Unmarshal(buf, msg); err != nil {
+			return err
+		}
+		for _, info := range msg.GetGameInfo().GetDota().GetPlayerInfo() {
+			if info.PlayerName != nil {
+				info.PlayerName = proto.String(a.name(info.GetPlayerName()))
+			}
+			if info.Steamid != nil {
+				info.Steamid = proto.Uint64(a.steamID(info.GetSteamid()))
+			}
+		}
+		m = msg
+	}
+
+	if m != nil {
+		var err error
+		if buf, err = proto.Marshal(m); err != nil {
+			return err
+		}
+	}
+
+	return writeOuterMessage(a.w, t, tick, buf, compressed)
+}
+
+// packet returns the anonymized inner messages of a packet.
+func (a *anonymizer) packet(data []byte) ([]byte, error) {
+	r := newReader(data)
+	w := newWriter()
+
+	for r.remBytes() > 0 {
+		t := int32(r.readUBitVar())
+		buf := r.readBytes(r.readVarUint32())
+
+		var m proto.Message
+		switch t {
+		case int32(dota.SVC_Messages_svc_PacketEntities):
+			msg := &dota.CSVCMsg_PacketEntities{}
+			if err := proto.Unmarshal(buf, msg); err != nil {
+				return nil, err
+			}
+			msg.EntityData = a.entityData(msg)
+			m = msg
+
+		case int32(dota.SVC_Messages_svc_CreateStringTable):
+			msg := &dota.CSVCMsg_CreateStringTable{}
+			if err := proto.Unmarshal(buf, msg); err != nil {
+				return nil, err
+			}
+			if err := a.createStringTable(msg); err != nil {
+				return nil, err
+			}
+			m = msg
+
+		case int32(dota.SVC_Messages_svc_UpdateStringTable):
+			msg := &dota.CSVCMsg_UpdateStringTable{}
+			if err := proto.Unmarshal(buf, msg); err != nil {
+				return nil, err
+			}
+			a.updateStringTable(msg)
+			m = msg
+
+		case int32(dota.EBaseUserMessages_UM_SayText2):
+			msg := &dota.CUserMessageSayText2{}
+			if err := proto.Unmarshal(buf, msg); err != nil {
+				return nil, err
+			}
+			if msg.Param1 != nil {
+				msg.Param1 = proto.String(a.name(msg.GetParam1()))
+			}
+			if msg.Param2 != nil {
+				msg.Param2 = proto.String(anonymizedChat)
+			}
+			m = msg
+
+		case int32(dota.EDotaUserMessages_DOTA_UM_ChatMessage):
+			msg := &dota.CDOTAUserMsg_ChatMessage{}
+			if err := proto.Unmarshal(buf, msg); err != nil {
+				return nil, err
+			}
+			if msg.MessageText != nil {
+				msg.MessageText = proto.String(anonymizedChat)
+			}
+			m = msg
+
+		case int32(dota.EBaseGameEvents_GE_Source1LegacyGameEvent):
+			msg := &dota.CMsgSource1LegacyGameEvent{}
+			if err := proto.Unmarshal(buf, msg); err != nil {
+				return nil, err
+			}
+			a.gameEvent(msg)
+			m = msg
+		}
+
+		if m != nil {
+			var err error
+			if buf, err = proto.Marshal(m); err != nil {
+				return nil, err
+			}
+		}
+
+		w.writeUBitVar(uint32(t))
+		w.writeVarUint32(uint32(len(buf)))
+		w.writeBytes(buf)
+	}
+
+	return w.bytes(), nil
+}
+
+// entityData returns anonymized entity data, copying the encoding of every
+// field but those of anonymizedFields.
+func (a *anonymizer) entityData(m *dota.CSVCMsg_PacketEntities) []byte {
+	r := newReader(m.GetEntityData())
+	w := newWriter()
+
+	index := int32(-1)
+	for updates := int(m.GetUpdatedEntries()); updates > 0; updates-- {
+		delta := r.readUBitVar()
+		w.writeUBitVar(delta)
+		index += int32(delta) + 1
+
+		cmd := r.readBits(2)
+		w.writeBits(cmd, 2)
+		if cmd&0x01 != 0 {
+			if cmd&0x02 != 0 {
+				delete(a.classes, index)
+			}
+			continue
+		}
+
+		if cmd&0x02 != 0 {
+			classId := r.readBits(a.p.classIdSize)
+			serial := r.readBits(17)
+			x := r.readVarUint32()
+			w.writeBits(classId, a.p.classIdSize)
+			w.writeBits(serial, 17)
+			w.writeVarUint32(x)
+
+			a.classes[index] = a.p.classesById[int32(classId)]
+		}
+
+		c := a.classes[index]
+		if c == nil {
+			_panicf("unable to find class of entity %d", index)
+		}
+		a.fields(r, w, c.serializer)
+	}
+
+	// Whatever follows the entities is kept.
+	start := r.pos*8 - r.bitCount
+	end := r.size * 8
+	w.writeBitsFrom(readBitRange(r.buf, start, end), end-start)
+
+	return w.bytes()
+}
+
+// fields copies the field updates of an entity, anonymizing the values of
+// anonymizedFields.
+func (a *anonymizer) fields(r *reader, w *writer, s *serializer) {
+	start := r.pos*8 - r.bitCount
+	fps := readFieldPaths(r)
+	end := r.pos*8 - r.bitCount
+	w.writeBitsFrom(readBitRange(r.buf, start, end), end-start)
+
+	for _, fp := range fps {
+		decoder := s.getDecoderForFieldPath(fp, 0)
+
+		start := r.pos*8 - r.bitCount
+		v := decoder(r)
+		end := r.pos*8 - r.bitCount
+
+		f := s.getFieldForFieldPath(fp, 0)
+		if x, ok := a.value(anonymizedFields[f.varName], v); ok {
+			if err := writeBuilderValue(w, f, s.getTypeForFieldPath(fp, 0), x); err != nil {
+				_panicf("unable to anonymize %s: %s", f.varName, err)
+			}
+		} else {
+			w.writeBitsFrom(readBitRange(r.buf, start, end), end-start)
+		}
+
+		fp.release()
+	}
+}
+
+// value returns the anonymized value of a decoded value, and whether it
+// changes.
+func (a *anonymizer) value(kind anonymizedKind, v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case string:
+		switch kind {
+		case anonymizedName:
+			return a.name(x), true
+		case anonymizedText:
+			return anonymizedChat, true
+		}
+	case uint64:
+		if kind == anonymizedSteamID {
+			return a.steamID(x), true
+		}
+	}
+	return nil, false
+}
+
+// gameEvent anonymizes the keys of a game event.
+func (a *anonymizer) gameEvent(m *dota.CMsgSource1LegacyGameEvent) {
+	name, ok := a.p.gameEventNames[m.GetEventid()]
+	if !ok {
+		return
+	}
+	t, ok := a.p.gameEventTypes[name]
+	if !ok {
+		return
+	}
+
+	rules, ok := anonymizedGameEvents[name]
+	if !ok {
+		return
+	}
+
+	keys := m.GetKeys()
+	for key, field := range t.fields {
+		kind, ok := rules[key]
+		if !ok || field.i >= len(keys) {
+			continue
+		}
+		k := keys[field.i]
+		switch {
+		case k.ValString != nil && kind == anonymizedSteamID:
+			k.ValString = proto.String(a.networkID(k.GetValString()))
+		case k.ValString != nil:
+			x, _ := a.value(kind, k.GetValString())
+			k.ValString = proto.String(x.(string))
+		case k.ValUint64 != nil && kind == anonymizedSteamID:
+			k.ValUint64 = proto.Uint64(a.steamID(k.GetValUint64()))
+		}
+	}
+}
+
+// playerInfo returns an anonymized userinfo string table value.
+func (a *anonymizer) playerInfo(buf []byte) []byte {
+	info := &dota.CMsgPlayerInfo{}
+	if err := proto.Unmarshal(buf, info); err != nil {
+		return buf
+	}
+	if info.Name != nil {
+		info.Name = proto.String(a.name(info.GetName()))
+	}
+	if info.Xuid != nil {
+		info.Xuid = proto.Uint64(a.steamID(info.GetXuid()))
+	}
+	if info.Steamid != nil {
+		info.Steamid = proto.Uint64(a.steamID(info.GetSteamid()))
+	}
+	out, err := proto.Marshal(info)
+	if err != nil {
+		return buf
+	}
+	return out
+}
+
+// stringTables anonymizes the userinfo entries of a string table snapshot.
+func (a *anonymizer) stringTables(m *dota.CDemoStringTables) {
+	for _, t := range m.GetTables() {
+		if t.GetTableName() != "userinfo" {
+			continue
+		}
+		for _, items := range [][]*dota.CDemoStringTablesItemsT{t.Items, t.ItemsClientside} {
+			for _, item := range items {
+				if len(item.Data) > 0 {
+					item.Data = a.playerInfo(item.Data)
+				}
+			}
+		}
+	}
+}
+
+// createStringTable keeps the details of a string table, anonymizing its
+// entries if it is the userinfo table.
+func (a *anonymizer) createStringTable(m *dota.CSVCMsg_CreateStringTable) error {
+	t := &stringTable{
+		index:             int32(len(a.tables)),
+		name:              m.GetName(),
+		userDataFixedSize: m.GetUserDataFixedSize(),
+		userDataSizeBits:  m.GetUserDataSizeBits(),
+		flags:             m.GetFlags(),
+		varintBitCounts:   m.GetUsingVarintBitcounts(),
+	}
+	a.tables = append(a.tables, t)
+
+	if t.name != "userinfo" {
+		return nil
+	}
+
+	buf := m.GetStringData()
+	if m.GetDataCompressed() {
+		var err error
+		if r := newReader(buf); r.readStringN(4) == "LZSS" {
+			buf, err = unlzss(buf)
+		} else {
+			buf, err = snappy.Decode(nil, buf)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	data := a.userinfo(t, buf, m.GetNumEntries())
+	if data == nil {
+		return nil
+	}
+	m.StringData = data
+	m.DataCompressed = proto.Bool(false)
+	m.UncompressedSize = proto.Int32(int32(len(m.StringData)))
+	return nil
+}
+
+// updateStringTable anonymizes the entries of an update of the userinfo
+// table.
+func (a *anonymizer) updateStringTable(m *dota.CSVCMsg_UpdateStringTable) {
+	id := int(m.GetTableId())
+	if id >= len(a.tables) || a.tables[id].name != "userinfo" {
+		return
+	}
+	if data := a.userinfo(a.tables[id], m.GetStringData(), m.GetNumChangedEntries()); data != nil {
+		m.StringData = data
+	}
+}
+
+// userinfo returns anonymized userinfo string table data, or nil if there are
+// no player details in it.
+func (a *anonymizer) userinfo(t *stringTable, buf []byte, n int32) []byte {
+	items := parseStringTable(buf, n, t.name, t.userDataFixedSize, t.userDataSizeBits, t.flags, t.varintBitCounts)
+	if len(items) != int(n) {
+		_panicf("unable to parse userinfo string table")
+	}
+	changed := false
+	for _, item := range items {
+		if len(item.Value) > 0 {
+			item.Value = a.playerInfo(item.Value)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeStringTable(items, t.userDataFixedSize, t.userDataSizeBits, t.flags, t.varintBitCounts)
+}
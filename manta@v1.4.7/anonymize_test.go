@@ -0,0 +1,342 @@
+package manta
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// _anonymized returns whether an entity value is anonymized.
+func _anonymized(name string) bool {
+	for _, s := range strings.Split(name, ".") {
+		if _, ok := anonymizedFields[s]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// _contains_bits returns whether a string is found in a buffer at any bit
+// alignment, as inner messages are not aligned to bytes.
+func _contains_bits(buf []byte, s string) bool {
+	for offset := uint32(0); offset < 8; offset++ {
+		r := newReader(buf)
+		r.readBits(offset)
+		if bytes.Contains(r.readBytes(r.remBytes()), []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// _anonymize_replay builds a replay holding player details in every place
+// they are found in real replays.
+func _anonymize_replay(t *testing.T) []byte {
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.Class("CDOTAPlayerController",
+		BuilderField{"m_iszPlayerName", "char[128]"},
+		BuilderField{"m_steamID", "uint64"},
+		BuilderField{"m_nPlayerID", "int32"},
+	)
+
+	info, err := proto.Marshal(&dota.CMsgPlayerInfo{
+		Name:    proto.String("yurnero"),
+		Xuid:    proto.Uint64(76561198000000123),
+		Steamid: proto.Uint64(76561198000000123),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.StringTable("userinfo", "0", info)
+
+	b.Message(int32(dota.EBaseGameEvents_GE_Source1LegacyGameEventList), &dota.CMsgSource1LegacyGameEventList{
+		Descriptors: []*dota.CMsgSource1LegacyGameEventListDescriptorT{{
+			Eventid: proto.Int32(1),
+			Name:    proto.String("player_connect"),
+			Keys: []*dota.CMsgSource1LegacyGameEventListKeyT{
+				{Type: proto.Int32(gameEventTypeString), Name: proto.String("name")},
+				{Type: proto.Int32(gameEventTypeString), Name: proto.String("networkid")},
+				{Type: proto.Int32(gameEventTypeUint64), Name: proto.String("xuid")},
+				{Type: proto.Int32(gameEventTypeShort), Name: proto.String("userid")},
+			},
+		}, {
+			Eventid: proto.Int32(2),
+			Name:    proto.String("game_message"),
+			Keys: []*dota.CMsgSource1LegacyGameEventListKeyT{
+				{Type: proto.Int32(gameEventTypeByte), Name: proto.String("target")},
+				{Type: proto.Int32(gameEventTypeString), Name: proto.String("text")},
+			},
+		}},
+	})
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{
+		"m_iszPlayerNames.0006":  "yurnero",
+		"m_iPlayerSteamIDs.0006": uint64(76561198000000123),
+	})
+	b.Create(2, "CDOTAPlayerController", map[string]interface{}{
+		"m_iszPlayerName": "yurnero",
+		"m_steamID":       uint64(76561198000000123),
+		"m_nPlayerID":     6,
+	})
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{
+		"m_iHealth":     620,
+		"m_iszUnitName": "npc_dota_hero_juggernaut",
+	})
+	b.Advance(30)
+
+	b.Message(int32(dota.EBaseGameEvents_GE_Source1LegacyGameEvent), &dota.CMsgSource1LegacyGameEvent{
+		Eventid: proto.Int32(1),
+		Keys: []*dota.CMsgSource1LegacyGameEventKeyT{
+			{Type: proto.Int32(gameEventTypeString), ValString: proto.String("nailgun")},
+			{Type: proto.Int32(gameEventTypeString), ValString: proto.String("[U:1:456]")},
+			{Type: proto.Int32(gameEventTypeUint64), ValUint64: proto.Uint64(76561197960265728 + 456)},
+			{Type: proto.Int32(gameEventTypeShort), ValShort: proto.Int32(3)},
+		},
+	})
+	b.Message(int32(dota.EBaseGameEvents_GE_Source1LegacyGameEvent), &dota.CMsgSource1LegacyGameEvent{
+		Eventid: proto.Int32(2),
+		Keys: []*dota.CMsgSource1LegacyGameEventKeyT{
+			{Type: proto.Int32(gameEventTypeByte), ValByte: proto.Int32(1)},
+			{Type: proto.Int32(gameEventTypeString), ValString: proto.String("Roshan has been slain")},
+		},
+	})
+	b.Update(1, map[string]interface{}{"m_iszPlayerNames.0007": "nailgun"})
+	b.Update(10, map[string]interface{}{"m_iHealth": 500})
+	b.Message(int32(dota.EBaseUserMessages_UM_SayText2), &dota.CUserMessageSayText2{
+		Param1: proto.String("nailgun"),
+		Param2: proto.String("gg wp meet me at the fountain"),
+	})
+	b.Message(int32(dota.EDotaUserMessages_DOTA_UM_ChatMessage), &dota.CDOTAUserMsg_ChatMessage{
+		SourcePlayerId: proto.Int32(6),
+		MessageText:    proto.String("need a ward here"),
+	})
+
+	data, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Real replays start with the file header and end with the file info
+	// after the stop.
+	header, err := proto.Marshal(&dota.CDemoFileHeader{
+		DemoFileStamp: proto.String("PBDEMS2\x00"),
+		ServerName:    proto.String("yurnero's lobby"),
+		ClientName:    proto.String("SourceTV nailgun"),
+		MapName:       proto.String("dota"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := proto.Marshal(&dota.CDemoFileInfo{
+		GameInfo: &dota.CGameInfo{
+			Dota: &dota.CGameInfo_CDotaGameInfo{
+				PlayerInfo: []*dota.CGameInfo_CDotaGameInfo_CPlayerInfo{{
+					HeroName:   proto.String("npc_dota_hero_juggernaut"),
+					PlayerName: proto.String("yurnero"),
+					Steamid:    proto.Uint64(76561198000000123),
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBuffer(append([]byte{}, data[:16]...))
+	if err := writeOuterMessage(out, int32(dota.EDemoCommands_DEM_FileHeader), 0, header, false); err != nil {
+		t.Fatal(err)
+	}
+	out.Write(data[16:])
+	if err := writeOuterMessage(out, int32(dota.EDemoCommands_DEM_FileInfo), 30, buf, true); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// _anonymize_state is what a parser sees of a replay.
+type _anonymize_state struct {
+	entities map[int32]map[string]interface{}
+	chat     []string
+	events   []string
+	userinfo []*dota.CMsgPlayerInfo
+	fileInfo *dota.CDemoFileInfo
+	header   *dota.CDemoFileHeader
+}
+
+func _anonymize_parse(t *testing.T, data []byte) *_anonymize_state {
+	p, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &_anonymize_state{entities: make(map[int32]map[string]interface{})}
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		s.entities[e.GetIndex()] = e.Map()
+		return nil
+	})
+	p.Callbacks.OnCUserMessageSayText2(func(m *dota.CUserMessageSayText2) error {
+		s.chat = append(s.chat, m.GetParam1()+": "+m.GetParam2())
+		return nil
+	})
+	p.Callbacks.OnCDOTAUserMsg_ChatMessage(func(m *dota.CDOTAUserMsg_ChatMessage) error {
+		s.chat = append(s.chat, m.GetMessageText())
+		return nil
+	})
+	p.OnGameEvent("player_connect", func(e *GameEvent) error {
+		name, _ := e.GetString("name")
+		id, _ := e.GetString("networkid")
+		xuid, _ := e.GetUint64("xuid")
+		userid, _ := e.GetInt32("userid")
+		s.events = append(s.events, fmt.Sprintf("%s %s %d %d", name, id, xuid, userid))
+		return nil
+	})
+	p.OnGameEvent("game_message", func(e *GameEvent) error {
+		text, _ := e.GetString("text")
+		s.events = append(s.events, text)
+		return nil
+	})
+	p.Callbacks.OnCDemoFileHeader(func(m *dota.CDemoFileHeader) error {
+		s.header = m
+		return nil
+	})
+	p.Callbacks.OnCDemoFileInfo(func(m *dota.CDemoFileInfo) error {
+		s.fileInfo = m
+		return nil
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if table, ok := p.stringTables.GetTableByName("userinfo"); ok {
+		for i := int32(0); i < int32(len(table.Items)); i++ {
+			info := &dota.CMsgPlayerInfo{}
+			if err := proto.Unmarshal(table.Items[i].Value, info); err != nil {
+				t.Fatal(err)
+			}
+			s.userinfo = append(s.userinfo, info)
+		}
+	}
+
+	return s
+}
+
+func TestAnonymize(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _anonymize_replay(t)
+	out := &bytes.Buffer{}
+	if !assert.Nil(Anonymize(bytes.NewReader(data), out)) {
+		return
+	}
+
+	// Nothing identifying is left anywhere.
+	for _, s := range []string{"yurnero", "nailgun", "gg wp", "need a ward", "[U:1:456]", "lobby", "SourceTV"} {
+		assert.True(_contains_bits(data, s), s)
+		assert.False(_contains_bits(out.Bytes(), s), s)
+	}
+
+	want := _anonymize_parse(t, data)
+	got := _anonymize_parse(t, out.Bytes())
+
+	// Names and steam IDs have the same alias everywhere.
+	assert.Equal("Player 1", got.entities[1]["m_iszPlayerNames.0006"])
+	assert.Equal("Player 2", got.entities[1]["m_iszPlayerNames.0007"])
+	assert.Equal("", got.entities[1]["m_iszPlayerNames.0000"])
+	assert.Equal(uint64(76561197960265728+1), got.entities[1]["m_iPlayerSteamIDs.0006"])
+	assert.Equal(uint64(0), got.entities[1]["m_iPlayerSteamIDs.0007"])
+	assert.Equal("Player 1", got.entities[2]["m_iszPlayerName"])
+	assert.Equal(uint64(76561197960265728+1), got.entities[2]["m_steamID"])
+	if assert.Len(got.userinfo, 1) {
+		assert.Equal("Player 1", got.userinfo[0].GetName())
+		assert.Equal(uint64(76561197960265728+1), got.userinfo[0].GetXuid())
+		assert.Equal(uint64(76561197960265728+1), got.userinfo[0].GetSteamid())
+	}
+	assert.Equal([]string{"Player 2 [U:1:2] 76561197960265730 3", "Roshan has been slain"}, got.events)
+	assert.Equal([]string{"Player 2: ", ""}, got.chat)
+	if assert.NotNil(got.header) {
+		assert.Equal("", got.header.GetServerName())
+		assert.Equal("", got.header.GetClientName())
+		assert.Equal("dota", got.header.GetMapName())
+	}
+	if assert.NotNil(got.fileInfo) {
+		player := got.fileInfo.GetGameInfo().GetDota().GetPlayerInfo()[0]
+		assert.Equal("Player 1", player.GetPlayerName())
+		assert.Equal(uint64(76561197960265728+1), player.GetSteamid())
+		assert.Equal("npc_dota_hero_juggernaut", player.GetHeroName())
+	}
+
+	// Everything else is the same.
+	assert.Equal(len(want.entities), len(got.entities))
+	for index, values := range want.entities {
+		assert.Equal(len(values), len(got.entities[index]), "entity %d", index)
+		for name, v := range values {
+			if !_anonymized(name) {
+				assert.Equal(v, got.entities[index][name], "entity %d %s", index, name)
+			}
+		}
+	}
+}
+
+func TestAnonymizeUnchanged(t *testing.T) {
+	assert := assert.New(t)
+
+	// Replays without player details are copied as they are, down to the
+	// bits of entity updates.
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.StringTable("userinfo", "0", nil)
+	b.Create(0, "CDOTAGamerulesProxy", nil)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_vecOrigin": []float32{1, 2, 3}})
+	for i := 0; i < 10; i++ {
+		b.Advance(1)
+		b.Update(0, map[string]interface{}{"m_pGameRules.m_fGameTime": float32(i)})
+		b.Update(10, map[string]interface{}{"m_iHealth": i * 10})
+	}
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	out := &bytes.Buffer{}
+	if assert.Nil(Anonymize(bytes.NewReader(data), out)) {
+		assert.Equal(data, out.Bytes())
+	}
+}
+
+func TestAnonymizeReplay(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping test in CI environment")
+	}
+	if testing.Short() {
+		t.Skip("skipping replays in short mode")
+	}
+
+	assert := assert.New(t)
+
+	data := mustGetReplayData("2159568145", "https://s3-us-west-2.amazonaws.com/manta.dotabuff/2159568145.dem")
+	out := &bytes.Buffer{}
+	if !assert.Nil(Anonymize(bytes.NewReader(data), out)) {
+		return
+	}
+
+	want := _anonymize_parse(t, data)
+	got := _anonymize_parse(t, out.Bytes())
+	assert.Equal(len(want.entities), len(got.entities))
+	for index, values := range want.entities {
+		for name, v := range values {
+			if !_anonymized(name) {
+				assert.Equal(v, got.entities[index][name], "entity %d %s", index, name)
+			}
+		}
+	}
+	for _, info := range want.userinfo {
+		if info.GetName() != "" {
+			assert.False(bytes.Contains(out.Bytes(), []byte(info.GetName())), info.GetName())
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package manta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dotabuff/manta/dota"
+)
+
+// ErrBroadcastNotFound is returned by BroadcastClient.Fragment for a
+// fragment which is not available, either not written yet or expired.
+var ErrBroadcastNotFound = errors.New("manta: broadcast fragment not found")
+
+// ErrBroadcastTimeout is returned when a broadcast does not advance for
+// longer than the timeout of its client.
+var ErrBroadcastTimeout = errors.New("manta: timed out waiting for broadcast fragment")
+
+// BroadcastSync describes the state of a broadcast, as served by its /sync
+// endpoint.
+type BroadcastSync struct {
+	Tick             int     `json:"tick"`
+	RealtimeDelay    float64 `json:"rtdelay"`
+	ReceiveAge       float64 `json:"rcvage"`
+	Fragment         int     `json:"fragment"`
+	SignupFragment   int     `json:"signup_fragment"`
+	TicksPerSecond   int     `json:"tps"`
+	KeyframeInterval float64 `json:"keyframe_interval"`
+	Map              string  `json:"map"`
+	Protocol         int     `json:"protocol"`
+}
+
+// BroadcastClient fetches the fragments of a tv_broadcast HTTP stream. A
+// broadcast is made of a start fragment holding the signon messages, and of
+// numbered fragments each with a full snapshot and the delta following it.
+// Fragments contain outer messages as they appear in a replay, without the
+// header.
+type BroadcastClient struct {
+	// BaseURL is the URL of the broadcast, under which /sync and the
+	// fragments are found.
+	BaseURL string
+
+	// HTTPClient performs the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+
+	// Poll is how long to wait before asking again for a fragment which is
+	// not available yet.
+	Poll time.Duration
+
+	// Timeout is how long to wait for the next fragment before failing with
+	// ErrBroadcastTimeout, zero waits until the context is done.
+	Timeout time.Duration
+}
+
+// NewBroadcastClient returns a BroadcastClient for the broadcast at baseURL.
+func NewBroadcastClient(baseURL string) *BroadcastClient {
+	return &BroadcastClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Poll:    time.Second,
+		Timeout: time.Minute,
+	}
+}
+
+// get fetches a path under the base URL.
+func (c *BroadcastClient) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, ErrBroadcastNotFound
+	}
+	return nil, _errorf("broadcast: GET %s: %s", path, resp.Status)
+}
+
+// Sync fetches the current state of the broadcast.
+func (c *BroadcastClient) Sync(ctx context.Context) (*BroadcastSync, error) {
+	buf, err := c.get(ctx, "/sync")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BroadcastSync{}
+	if err := json.Unmarshal(buf, s); err != nil {
+		return nil, _errorf("broadcast: invalid sync: %s", err)
+	}
+	return s, nil
+}
+
+// Fragment fetches the given part of a fragment: "start", "full" or "delta".
+// It returns ErrBroadcastNotFound when the fragment is not available.
+func (c *BroadcastClient) Fragment(ctx context.Context, n int, part string) ([]byte, error) {
+	return c.get(ctx, fmt.Sprintf("/%d/%s", n, part))
+}
+
+// NewBroadcastParser creates a Parser for a live broadcast. Starting it
+// fetches the signon messages and the latest full snapshot, then every delta
+// as it becomes available. When the broadcast moves past a fragment which
+// could not be fetched, the Parser resyncs from the latest full snapshot:
+// all entities are deleted and created again from it. Parsing ends after the
+// fragment holding the CDemoStop message, or fails once the broadcast does
+// not advance for the timeout of the client or ctx is done.
+func NewBroadcastParser(ctx context.Context, c *BroadcastClient) (*Parser, error) {
+	sync, err := c.Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := newParser(bytes.NewReader(nil))
+	parser.broadcast = &broadcastReader{
+		client: c,
+		ctx:    ctx,
+		sync:   sync,
+	}
+
+	return parser, nil
+}
+
+// broadcastReader feeds the fragments of a broadcast to a Parser.
+type broadcastReader struct {
+	client   *BroadcastClient
+	ctx      context.Context
+	sync     *BroadcastSync
+	started  bool
+	stopped  bool
+	fragment int // next delta fragment
+	resyncs  int
+}
+
+// next reads and dispatches the next outer message, fetching fragments as
+// needed.
+func (b *broadcastReader) next(p *Parser) error {
+	msg, err := p.readOuterMessage()
+	if err == io.EOF {
+		if b.stopped {
+			return io.EOF
+		}
+		return b.load(p)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.Tick = msg.tick
+	p.lastOuterMessage = msg
+
+	if msg.typeId == int32(dota.EDemoCommands_DEM_Stop) {
+		b.stopped = true
+	}
+
+	return p.Callbacks.callByDemoType(msg.typeId, msg.tick, msg.data)
+}
+
+// load points the stream of the Parser at the next fragment.
+func (b *broadcastReader) load(p *Parser) error {
+	if !b.started {
+		start, err := b.client.Fragment(b.ctx, b.sync.SignupFragment, "start")
+		if err != nil {
+			return err
+		}
+		full, err := b.client.Fragment(b.ctx, b.sync.Fragment, "full")
+		if err != nil {
+			return err
+		}
+
+		p.stream.Reader = io.MultiReader(bytes.NewReader(start), bytes.NewReader(full))
+		b.fragment = b.sync.Fragment
+		b.started = true
+		return nil
+	}
+
+	var deadline time.Time
+	if b.client.Timeout > 0 {
+		deadline = time.Now().Add(b.client.Timeout)
+	}
+
+	for {
+		buf, err := b.client.Fragment(b.ctx, b.fragment, "delta")
+		if err == nil {
+			p.stream.Reader = bytes.NewReader(buf)
+			b.fragment++
+			return nil
+		}
+		if err != ErrBroadcastNotFound {
+			return err
+		}
+
+		// A missing fragment older than the latest one is gone for good.
+		sync, err := b.client.Sync(b.ctx)
+		if err != nil {
+			return err
+		}
+		if sync.Fragment > b.fragment {
+			return b.resync(p, sync)
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return ErrBroadcastTimeout
+		}
+
+		t := time.NewTimer(b.client.Poll)
+		select {
+		case <-t.C:
+		case <-b.ctx.Done():
+			t.Stop()
+			return b.ctx.Err()
+		}
+	}
+}
+
+// resync drops all entities and continues from the full snapshot of the
+// latest fragment.
+func (b *broadcastReader) resync(p *Parser, sync *BroadcastSync) error {
+	full, err := b.client.Fragment(b.ctx, sync.Fragment, "full")
+	if err != nil {
+		return err
+	}
+
+	if err := p.dropEntities(); err != nil {
+		return err
+	}
+
+	p.stream.Reader = bytes.NewReader(full)
+	b.sync = sync
+	b.fragment = sync.Fragment
+	b.resyncs++
+	return nil
+}
@@ -0,0 +1,286 @@
+package manta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/stretchr/testify/assert"
+)
+
+// _outer_messages splits a replay into its encoded outer messages, leaving
+// out the header.
+func _outer_messages(t testing.TB, data []byte) [][]byte {
+	r := bytes.NewReader(data[16:])
+	s := newStream(r)
+
+	var ms [][]byte
+	for r.Len() > 0 {
+		start := len(data) - r.Len()
+		if _, err := s.readCommand(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.readVarUint32(); err != nil {
+			t.Fatal(err)
+		}
+		size, err := s.readVarUint32()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.readBytes(size); err != nil {
+			t.Fatal(err)
+		}
+		ms = append(ms, data[start:len(data)-r.Len()])
+	}
+	return ms
+}
+
+// _broadcast_server serves the fragments of a broadcast.
+type _broadcast_server struct {
+	sync.Mutex
+	sync      BroadcastSync
+	fragments map[string][]byte
+	requests  map[string]int
+	missing   map[string]int // number of requests answered 404 first
+	onRequest func(path string)
+}
+
+func _new_broadcast_server() *_broadcast_server {
+	return &_broadcast_server{
+		fragments: make(map[string][]byte),
+		requests:  make(map[string]int),
+		missing:   make(map[string]int),
+	}
+}
+
+func (s *_broadcast_server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	path := r.URL.Path
+	s.requests[path]++
+	if s.onRequest != nil {
+		s.onRequest(path)
+	}
+
+	if path == "/tv/sync" {
+		json.NewEncoder(w).Encode(s.sync)
+		return
+	}
+
+	buf, ok := s.fragments[path]
+	if !ok || s.requests[path] <= s.missing[path] {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(buf)
+}
+
+// add serves the given outer messages as a fragment part.
+func (s *_broadcast_server) add(n int, part string, ms ...[]byte) {
+	s.fragments[fmt.Sprintf("/tv/%d/%s", n, part)] = bytes.Join(ms, nil)
+}
+
+func _broadcast_messages(p *Parser) ([]_raw_message, error) {
+	var ms []_raw_message
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		ms = append(ms, _raw_message{t, tick, append([]byte(nil), buf...)})
+		return nil
+	})
+	return ms, p.Start()
+}
+
+func TestBroadcastParser(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _demo_stream_stopped(30)
+	want := _collect_demo_messages(t, data, 0)
+	ms := _outer_messages(t, data)
+
+	s := _new_broadcast_server()
+	s.sync = BroadcastSync{Fragment: 0, SignupFragment: 0, TicksPerSecond: 30}
+	s.add(0, "start", ms[:2]...)
+	s.add(0, "full")
+	for i, n := 2, 0; i < len(ms); i, n = i+4, n+1 {
+		end := i + 4
+		if end > len(ms) {
+			end = len(ms)
+		}
+		s.add(n, "delta", ms[i:end]...)
+	}
+
+	// Deltas become available after being asked for a few times.
+	s.missing["/tv/3/delta"] = 3
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c := NewBroadcastClient(srv.URL + "/tv/")
+	c.Poll = time.Millisecond
+
+	p, err := NewBroadcastParser(context.Background(), c)
+	if !assert.Nil(err) {
+		return
+	}
+	got, err := _broadcast_messages(p)
+	assert.Nil(err)
+	assert.Equal(want, got)
+	assert.Equal(4, s.requests["/tv/3/delta"])
+	assert.Equal(0, p.broadcast.resyncs)
+
+	// Nothing is fetched after the stop message.
+	_, ok := s.requests[fmt.Sprintf("/tv/%d/delta", (len(ms)-2+3)/4)]
+	assert.False(ok)
+}
+
+func TestBroadcastParserResync(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := _outer_messages(t, _demo_stream_stopped(20))
+
+	s := _new_broadcast_server()
+	s.sync = BroadcastSync{Fragment: 0}
+	s.add(0, "start", ms[0])
+	s.add(0, "full")
+	s.add(0, "delta", ms[1:3]...)
+	s.add(1, "delta", ms[3:5]...)
+	// Fragments 2 and 3 are never served, the broadcast moves on to 4.
+	s.add(4, "full", ms[10])
+	s.add(4, "delta", ms[11:]...)
+	s.onRequest = func(path string) {
+		if path == "/tv/2/delta" {
+			s.sync.Fragment = 4
+		}
+	}
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c := NewBroadcastClient(srv.URL + "/tv")
+	c.Poll = time.Millisecond
+
+	p, err := NewBroadcastParser(context.Background(), c)
+	if !assert.Nil(err) {
+		return
+	}
+
+	// Entities known before the resync are deleted.
+	p.entities[7] = newEntity(7, 1, &class{name: "CDOTA_Unit_Hero_Puck"})
+	var ops []EntityOp
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		ops = append(ops, op)
+		return nil
+	})
+
+	got, err := _broadcast_messages(p)
+	assert.Nil(err)
+	assert.Equal(1, p.broadcast.resyncs)
+	assert.Equal([]EntityOp{EntityOpDeletedLeft}, ops)
+	assert.Empty(p.entities)
+
+	var ticks []uint32
+	for _, m := range got {
+		ticks = append(ticks, m.tick)
+	}
+	assert.Equal([]uint32{0, 2, 4, 6, 8, 20, 22, 24, 26, 28, 30, 32, 34, 36, 38, 40, 40}, ticks)
+}
+
+func TestBroadcastParserTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := _outer_messages(t, _demo_stream(4))
+
+	s := _new_broadcast_server()
+	s.add(0, "start", ms[0])
+	s.add(0, "full", ms[1])
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c := NewBroadcastClient(srv.URL + "/tv")
+	c.Poll = time.Millisecond
+	c.Timeout = 20 * time.Millisecond
+
+	p, err := NewBroadcastParser(context.Background(), c)
+	if !assert.Nil(err) {
+		return
+	}
+	got, err := _broadcast_messages(p)
+	assert.Equal(ErrBroadcastTimeout, err)
+	assert.Len(got, 2)
+
+	// A broadcast which is not there fails right away.
+	_, err = NewBroadcastParser(context.Background(), NewBroadcastClient(srv.URL+"/missing"))
+	assert.NotNil(err)
+}
+
+func TestBroadcastParserReplay(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping test in CI environment")
+	}
+
+	assert := assert.New(t)
+
+	data := mustGetReplayData("2159568145", "https://s3-us-west-2.amazonaws.com/manta.dotabuff/2159568145.dem")
+	ms := _outer_messages(t, data)
+
+	// The signon messages up to the first sync tick make the start fragment,
+	// the rest is cut into deltas.
+	signon := 0
+	for i, m := range ms {
+		cmd, _ := newStream(bytes.NewReader(m)).readCommand()
+		if cmd&^dota.EDemoCommands_DEM_IsCompressed == dota.EDemoCommands_DEM_SyncTick {
+			signon = i + 1
+			break
+		}
+	}
+
+	s := _new_broadcast_server()
+	s.add(0, "start", ms[:signon]...)
+	s.add(0, "full")
+	for i, n := signon, 0; i < len(ms); i, n = i+100, n+1 {
+		end := i + 100
+		if end > len(ms) {
+			end = len(ms)
+		}
+		s.add(n, "delta", ms[i:end]...)
+	}
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c := NewBroadcastClient(srv.URL + "/tv")
+	c.Timeout = time.Second
+	p, err := NewBroadcastParser(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities := 0
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		entities++
+		return nil
+	})
+	assert.Nil(p.Start())
+
+	// The broadcast delivers the same entity events as the replay.
+	whole, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wholeEntities := 0
+	whole.OnEntity(func(e *Entity, op EntityOp) error {
+		wholeEntities++
+		return nil
+	})
+	assert.Nil(whole.Start())
+	assert.Equal(wholeEntities, entities)
+	assert.Equal(whole.Tick, p.Tick)
+}
@@ -0,0 +1,38 @@
+package manta
+
+// Buffers passed to callbacks
+//
+// Raw message handlers registered with OnAnyDemoMessage, OnAnyPacketMessage
+// and OnUnknownMessage receive the undecoded contents of messages. By default
+// these may alias memory which the Parser reuses for the next message, so a
+// handler which keeps a buffer past the call must copy it, for example with
+// RetainBytes or by wrapping the handler with RetainingHandler. Messages
+// decoded for typed callbacks own all of their fields and may be kept as
+// they are.
+//
+// UseImmutableBuffers removes the need to copy: every buffer is then freshly
+// allocated and never written to by the Parser again.
+
+// UseImmutableBuffers controls whether buffers passed to raw message
+// handlers are owned by the handlers, at the cost of an allocation for each
+// outer message. It must be called before Start.
+func (p *Parser) UseImmutableBuffers(immutable bool) {
+	p.immutableBuffers = immutable
+}
+
+// RetainBytes returns a copy of a buffer passed to a callback, which remains
+// valid after the callback returns.
+func RetainBytes(buf []byte) []byte {
+	if buf == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(buf)), buf...)
+}
+
+// RetainingHandler wraps a RawMessageHandler so that it receives a copy of
+// each buffer, which it may keep.
+func RetainingHandler(fn RawMessageHandler) RawMessageHandler {
+	return func(t int32, tick uint32, buf []byte) error {
+		return fn(t, tick, RetainBytes(buf))
+	}
+}
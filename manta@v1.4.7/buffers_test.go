@@ -0,0 +1,93 @@
+package manta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// _retained_demo_messages parses a demo stream, keeping every buffer passed to
+// OnAnyDemoMessage without copying it.
+func _retained_demo_messages(t *testing.T, data []byte, immutable bool, depth int) [][]byte {
+	p, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.UseImmutableBuffers(immutable)
+	p.EnablePipeline(depth)
+
+	var bufs [][]byte
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		bufs = append(bufs, buf)
+		return nil
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return bufs
+}
+
+// _aliased returns whether two buffers share memory.
+func _aliased(a, b []byte) bool {
+	if cap(a) == 0 || cap(b) == 0 {
+		return false
+	}
+	return &a[:cap(a)][cap(a)-1] == &b[:cap(b)][cap(b)-1]
+}
+
+func TestImmutableBuffers(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _demo_stream(20)
+	want := _collect_demo_messages(t, data, 0)
+
+	// Without immutable buffers, uncompressed messages share the stream
+	// buffer and are overwritten by the messages after them.
+	bufs := _retained_demo_messages(t, data, false, 0)
+	assert.True(_aliased(bufs[0], bufs[2]))
+	assert.NotEqual(want[0].buf, bufs[0])
+
+	for _, depth := range []int{0, 4} {
+		bufs := _retained_demo_messages(t, data, true, depth)
+		if !assert.Len(bufs, len(want)) {
+			continue
+		}
+		for i := range bufs {
+			assert.Equal(want[i].buf, bufs[i], "message %d, depth %d", i, depth)
+			for j := i + 1; j < len(bufs); j++ {
+				assert.False(_aliased(bufs[i], bufs[j]), "messages %d and %d, depth %d", i, j, depth)
+			}
+		}
+	}
+}
+
+func TestRetainingHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _demo_stream(20)
+	want := _collect_demo_messages(t, data, 0)
+
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+
+	var bufs [][]byte
+	p.Callbacks.OnAnyDemoMessage(RetainingHandler(func(t int32, tick uint32, buf []byte) error {
+		bufs = append(bufs, buf)
+		return nil
+	}))
+	assert.Nil(p.Start())
+
+	if assert.Len(bufs, len(want)) {
+		for i := range bufs {
+			assert.Equal(want[i].buf, bufs[i], "message %d", i)
+		}
+	}
+
+	buf := []byte{1, 2, 3}
+	retained := RetainBytes(buf)
+	buf[0] = 9
+	assert.Equal([]byte{1, 2, 3}, retained)
+	assert.Nil(RetainBytes(nil))
+}
@@ -0,0 +1,904 @@
+package manta
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+)
+
+// builderGameBuild is the game build of replays written by a ReplayBuilder,
+// past the builds whose fields need patches.
+const builderGameBuild = 6000
+
+// builderPacketMessages is the most inner messages written in a packet.
+// Messages of a packet are sorted by the parser, which only keeps the order
+// of messages of the same priority for small packets.
+const builderPacketMessages = 8
+
+// BuilderField declares a field of a class written by a ReplayBuilder.
+//
+// Names holding dots declare fields of nested tables, as in
+// "m_pGameRules.m_fGameTime". Types are network types such as "int32",
+// "uint32", "uint64", "float32", "bool", "Vector", "CUtlSymbolLarge" or
+// "CHandle< CBaseEntity >", and may be fixed arrays such as "uint64[24]",
+// whose elements are named like "m_iPlayerSteamIDs.0006".
+type BuilderField struct {
+	Name string
+	Type string
+}
+
+// ReplayBuilder writes replays programmatically, to test code consuming
+// replays against exact scenarios.
+//
+// Classes and the initial content of string tables are declared first. Then
+// entities, string table changes and inner messages are written at the
+// current tick, which moves forward with Advance. Everything written at a
+// tick is seen by the parser in the order it was written.
+//
+// Errors are kept until Bytes, which returns the first of them.
+type ReplayBuilder struct {
+	classes       []*builderClass
+	classesByName map[string]*builderClass
+	tables        []*builderTable
+	tablesByName  map[string]*builderTable
+	entities      map[int32]*builderEntity
+	serials       map[int32]int32
+	classIdSize   uint32
+
+	started  bool
+	signon   bytes.Buffer // outer messages before the first tick
+	packets  bytes.Buffer // outer messages of the ticks written
+	tick     uint32
+	written  bool // whether a packet was written at the current tick
+	pending  bool // whether anything is pending in the packet
+	priority int  // highest priority of a message pending in the packet
+	messages []builderMessage
+	ops      []*builderEntityOp
+
+	err error
+}
+
+type builderClass struct {
+	id         int32
+	name       string
+	fields     []BuilderField
+	serializer *serializer
+}
+
+type builderTable struct {
+	index   int32
+	name    string
+	items   []*stringTableItem
+	keys    map[string]int32
+	created bool
+	changed map[int32]bool
+}
+
+type builderEntity struct {
+	index  int32
+	serial int32
+	class  *builderClass
+}
+
+type builderEntityOp struct {
+	e      *builderEntity
+	cmd    uint32
+	fields fieldBits
+}
+
+type builderMessage struct {
+	t   int32
+	buf []byte
+}
+
+// NewReplayBuilder returns a builder for a replay starting at tick 0.
+func NewReplayBuilder() *ReplayBuilder {
+	b := &ReplayBuilder{
+		classesByName: make(map[string]*builderClass),
+		tablesByName:  make(map[string]*builderTable),
+		entities:      make(map[int32]*builderEntity),
+		serials:       make(map[int32]int32),
+	}
+	b.table("instancebaseline")
+	return b
+}
+
+// Class declares an entity class with the given fields. Classes must be
+// declared before anything is written at a tick.
+func (b *ReplayBuilder) Class(name string, fields ...BuilderField) {
+	if b.err != nil {
+		return
+	}
+	if b.started {
+		b.err = _errorf("builder: class %s declared after the first tick", name)
+		return
+	}
+	if _, ok := b.classesByName[name]; ok {
+		b.err = _errorf("builder: class %s declared twice", name)
+		return
+	}
+
+	c := &builderClass{
+		id:     int32(len(b.classes)),
+		name:   name,
+		fields: fields,
+	}
+	b.classes = append(b.classes, c)
+	b.classesByName[name] = c
+}
+
+// StringTable sets the value of the item with the given key in the named
+// string table, creating both as needed, and returns the index of the item.
+// Items set before anything is written at a tick are part of the signon
+// data, later ones are sent as updates at the current tick.
+func (b *ReplayBuilder) StringTable(table, key string, value []byte) int32 {
+	if b.err != nil {
+		return 0
+	}
+	if table == "instancebaseline" {
+		b.err = _errorf("builder: instancebaseline is written from the classes")
+		return 0
+	}
+
+	t := b.tablesByName[table]
+	if b.started {
+		b.reserve(int(dota.SVC_Messages_svc_UpdateStringTable))
+		if t == nil {
+			t = b.table(table)
+		}
+	} else if t == nil {
+		t = b.table(table)
+	}
+
+	index, ok := t.keys[key]
+	if !ok {
+		index = int32(len(t.items))
+		t.items = append(t.items, &stringTableItem{Index: index, Key: key})
+		t.keys[key] = index
+	}
+	t.items[index].Value = value
+	t.changed[index] = true
+
+	return index
+}
+
+// CombatLogName returns the index of the given name in the CombatLogNames
+// string table, adding it as needed.
+func (b *ReplayBuilder) CombatLogName(name string) uint32 {
+	if t := b.tablesByName["CombatLogNames"]; t != nil {
+		if index, ok := t.keys[name]; ok {
+			return uint32(index)
+		}
+	}
+	return uint32(b.StringTable("CombatLogNames", name, nil))
+}
+
+// Tick returns the current tick.
+func (b *ReplayBuilder) Tick() uint32 {
+	return b.tick
+}
+
+// Advance moves the current tick forward by n ticks.
+func (b *ReplayBuilder) Advance(n uint32) {
+	if !b.begin() {
+		return
+	}
+	b.flush()
+	b.tick += n
+	b.written = false
+}
+
+// Create creates an entity of the named class at the given index, returning
+// its handle. Fields without a value keep their zero value.
+func (b *ReplayBuilder) Create(index int32, class string, values map[string]interface{}) uint64 {
+	if !b.begin() {
+		return 0
+	}
+
+	c, ok := b.classesByName[class]
+	if !ok {
+		b.err = _errorf("builder: unknown class %s", class)
+		return 0
+	}
+	if _, ok := b.entities[index]; ok {
+		b.err = _errorf("builder: entity %d already exists", index)
+		return 0
+	}
+
+	fields, err := writeBuilderValues(c.serializer, values)
+	if err != nil {
+		b.err = _errorf("builder: entity %d (%s): %s", index, class, err)
+		return 0
+	}
+
+	b.serials[index]++
+	e := &builderEntity{index: index, serial: b.serials[index], class: c}
+	b.entities[index] = e
+	b.addOp(&builderEntityOp{e: e, cmd: 0x02, fields: fields})
+
+	return b.Handle(index)
+}
+
+// Update sets the given values of the entity at the given index.
+func (b *ReplayBuilder) Update(index int32, values map[string]interface{}) {
+	if !b.begin() {
+		return
+	}
+
+	e, ok := b.entities[index]
+	if !ok {
+		b.err = _errorf("builder: no entity %d to update", index)
+		return
+	}
+
+	fields, err := writeBuilderValues(e.class.serializer, values)
+	if err != nil {
+		b.err = _errorf("builder: entity %d (%s): %s", index, e.class.name, err)
+		return
+	}
+
+	b.addOp(&builderEntityOp{e: e, cmd: 0x00, fields: fields})
+}
+
+// Delete deletes the entity at the given index.
+func (b *ReplayBuilder) Delete(index int32) {
+	if !b.begin() {
+		return
+	}
+
+	e, ok := b.entities[index]
+	if !ok {
+		b.err = _errorf("builder: no entity %d to delete", index)
+		return
+	}
+	delete(b.entities, index)
+
+	b.addOp(&builderEntityOp{e: e, cmd: 0x03})
+}
+
+// Handle returns the handle of the entity at the given index, or 0 if there
+// is none.
+func (b *ReplayBuilder) Handle(index int32) uint64 {
+	e, ok := b.entities[index]
+	if !ok {
+		return 0
+	}
+	return uint64(e.serial)<<indexBits | uint64(e.index)
+}
+
+// Message writes an inner message of the given type at the current tick.
+func (b *ReplayBuilder) Message(t int32, m proto.Message) {
+	if !b.begin() {
+		return
+	}
+
+	buf, err := proto.Marshal(m)
+	if err != nil {
+		b.err = err
+		return
+	}
+
+	b.reserve(int(t))
+	b.messages = append(b.messages, builderMessage{t, buf})
+}
+
+// CombatLog writes a combat log entry at the current tick.
+func (b *ReplayBuilder) CombatLog(m *dota.CMsgDOTACombatLogEntry) {
+	b.Message(int32(dota.EDotaUserMessages_DOTA_UM_CombatLogDataHLTV), m)
+}
+
+// Bytes returns the replay written so far, ending at the current tick, or
+// the first error met while writing it.
+func (b *ReplayBuilder) Bytes() ([]byte, error) {
+	if !b.begin() {
+		return nil, b.err
+	}
+	b.flush()
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(magicSource2)
+	buf.Write(make([]byte, 8))
+	buf.Write(b.signon.Bytes())
+	buf.Write(b.packets.Bytes())
+
+	stop, err := proto.Marshal(&dota.CDemoStop{})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeOuterMessage(buf, int32(dota.EDemoCommands_DEM_Stop), b.tick, stop, false); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// table creates a string table.
+func (b *ReplayBuilder) table(name string) *builderTable {
+	t := &builderTable{
+		index:   int32(len(b.tables)),
+		name:    name,
+		keys:    make(map[string]int32),
+		changed: make(map[int32]bool),
+	}
+	b.tables = append(b.tables, t)
+	b.tablesByName[name] = t
+	return t
+}
+
+// begin writes the signon data when the first tick is written, returning
+// whether the builder can go on.
+func (b *ReplayBuilder) begin() bool {
+	if b.err != nil {
+		return false
+	}
+	if b.started {
+		return true
+	}
+	b.started = true
+
+	if err := b.writeSignon(); err != nil {
+		b.err = err
+		return false
+	}
+	return true
+}
+
+// writeSignon writes the server info, string tables, send tables and class
+// info of the replay.
+func (b *ReplayBuilder) writeSignon() error {
+	sendTables, err := b.sendTables()
+	if err != nil {
+		return err
+	}
+	serializers, err := parseSendTables(sendTables, builderGameBuild)
+	if err != nil {
+		return err
+	}
+
+	// Baselines hold the zero value of every field.
+	baselines := b.tablesByName["instancebaseline"]
+	for _, c := range b.classes {
+		c.serializer = serializers[c.name]
+		fields, err := writeBuilderValues(c.serializer, builderZeroValues(c.serializer))
+		if err != nil {
+			return _errorf("builder: class %s: %s", c.name, err)
+		}
+		baselines.items = append(baselines.items, &stringTableItem{
+			Index: c.id,
+			Key:   strconv.Itoa(int(c.id)),
+			Value: fields.bytes(),
+		})
+	}
+
+	maxClasses := len(b.classes)
+	if maxClasses < 1 {
+		maxClasses = 1
+	}
+	b.classIdSize = uint32(math.Log(float64(maxClasses))/math.Log(2)) + 1
+
+	packet := newWriter()
+	err = writeInnerMessage(packet, int32(dota.SVC_Messages_svc_ServerInfo), &dota.CSVCMsg_ServerInfo{
+		MaxClasses:   proto.Int32(int32(maxClasses)),
+		TickInterval: proto.Float32(1.0 / 30),
+		GameDir:      proto.String(fmt.Sprintf("/dota/dota_v%d/", builderGameBuild)),
+	})
+	if err != nil {
+		return err
+	}
+	for _, t := range b.tables {
+		if err := b.writeTable(packet, t); err != nil {
+			return err
+		}
+	}
+
+	classInfo := &dota.CDemoClassInfo{}
+	for _, c := range b.classes {
+		classInfo.Classes = append(classInfo.Classes, &dota.CDemoClassInfoClassT{
+			ClassId:     proto.Int32(c.id),
+			NetworkName: proto.String(c.name),
+			TableName:   proto.String(c.name),
+		})
+	}
+
+	messages := []struct {
+		t int32
+		m proto.Message
+	}{
+		{int32(dota.EDemoCommands_DEM_SignonPacket), &dota.CDemoPacket{Data: packet.bytes()}},
+		{int32(dota.EDemoCommands_DEM_SendTables), &dota.CDemoSendTables{Data: sendTables}},
+		{int32(dota.EDemoCommands_DEM_ClassInfo), classInfo},
+		{int32(dota.EDemoCommands_DEM_SyncTick), &dota.CDemoSyncTick{}},
+	}
+	for _, m := range messages {
+		buf, err := proto.Marshal(m.m)
+		if err != nil {
+			return err
+		}
+		if err := writeOuterMessage(&b.signon, m.t, 0, buf, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendTables returns the data of a CDemoSendTables message holding a
+// serializer for each class, with a nested serializer for each of their
+// tables.
+func (b *ReplayBuilder) sendTables() ([]byte, error) {
+	msg := &dota.CSVCMsg_FlattenedSerializer{}
+	symbols := make(map[string]int32)
+	symbol := func(s string) *int32 {
+		if i, ok := symbols[s]; ok {
+			return proto.Int32(i)
+		}
+		i := int32(len(msg.Symbols))
+		msg.Symbols = append(msg.Symbols, s)
+		symbols[s] = i
+		return proto.Int32(i)
+	}
+
+	// Nested serializers are written first, as fields refer to serializers
+	// written before them.
+	var add func(name string, fields []BuilderField) error
+	add = func(name string, fields []BuilderField) error {
+		var names []string
+		types := make(map[string]string)
+		tables := make(map[string][]BuilderField)
+		for _, f := range fields {
+			if i := strings.Index(f.Name, "."); i >= 0 {
+				table := f.Name[:i]
+				if _, ok := tables[table]; !ok {
+					names = append(names, table)
+				}
+				tables[table] = append(tables[table], BuilderField{f.Name[i+1:], f.Type})
+				continue
+			}
+			names = append(names, f.Name)
+			types[f.Name] = f.Type
+		}
+
+		var index []int32
+		seen := make(map[string]bool)
+		for _, n := range names {
+			if seen[n] || (types[n] != "" && tables[n] != nil) {
+				return _errorf("builder: field %s.%s declared twice", name, n)
+			}
+			seen[n] = true
+
+			field := &dota.ProtoFlattenedSerializerFieldT{
+				VarNameSym:  symbol(n),
+				SendNodeSym: symbol("(root)"),
+			}
+			if nested, ok := tables[n]; ok {
+				table := name + "." + n
+				if err := add(table, nested); err != nil {
+					return err
+				}
+				field.VarTypeSym = symbol(table + "*")
+				field.FieldSerializerNameSym = symbol(table)
+				field.FieldSerializerVersion = proto.Int32(0)
+			} else {
+				field.VarTypeSym = symbol(types[n])
+			}
+
+			index = append(index, int32(len(msg.Fields)))
+			msg.Fields = append(msg.Fields, field)
+		}
+
+		msg.Serializers = append(msg.Serializers, &dota.ProtoFlattenedSerializerT{
+			SerializerNameSym: symbol(name),
+			SerializerVersion: proto.Int32(0),
+			FieldsIndex:       index,
+		})
+		return nil
+	}
+
+	for _, c := range b.classes {
+		if err := add(c.name, c.fields); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	w := newWriter()
+	w.writeVarUint32(uint32(len(buf)))
+	w.writeBytes(buf)
+	return w.bytes(), nil
+}
+
+// writeTable writes the creation of a string table, or an update with the
+// items changed since it was last written.
+func (b *ReplayBuilder) writeTable(w *writer, t *builderTable) error {
+	if !t.created {
+		t.created = true
+		for index := range t.changed {
+			delete(t.changed, index)
+		}
+		return writeInnerMessage(w, int32(dota.SVC_Messages_svc_CreateStringTable), &dota.CSVCMsg_CreateStringTable{
+			Name:                 proto.String(t.name),
+			NumEntries:           proto.Int32(int32(len(t.items))),
+			StringData:           writeStringTable(t.items, false, 0, 0, true),
+			UsingVarintBitcounts: proto.Bool(true),
+		})
+	}
+
+	if len(t.changed) == 0 {
+		return nil
+	}
+	var items []*stringTableItem
+	for index := range t.changed {
+		items = append(items, t.items[index])
+		delete(t.changed, index)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Index < items[j].Index })
+
+	return writeInnerMessage(w, int32(dota.SVC_Messages_svc_UpdateStringTable), &dota.CSVCMsg_UpdateStringTable{
+		TableId:           proto.Int32(t.index),
+		NumChangedEntries: proto.Int32(int32(len(items))),
+		StringData:        writeStringTable(items, false, 0, 0, true),
+	})
+}
+
+// reserve makes room in the pending packet for a message of the given type,
+// writing the packet first when the parser would otherwise process the
+// message before ones written earlier.
+func (b *ReplayBuilder) reserve(t int) {
+	priority := (&pendingMessage{t: int32(t)}).priority()
+	if b.pending && (priority < b.priority || len(b.messages) >= builderPacketMessages) {
+		b.flush()
+	}
+	if !b.pending || priority > b.priority {
+		b.priority = priority
+	}
+	b.pending = true
+}
+
+// addOp adds an entity operation to the pending packet. Packets change
+// entities in the order of their indexes, so the packet is written first
+// unless the entity comes after those it already changes.
+func (b *ReplayBuilder) addOp(op *builderEntityOp) {
+	b.reserve(int(dota.SVC_Messages_svc_PacketEntities))
+	if n := len(b.ops); n > 0 && b.ops[n-1].e.index >= op.e.index {
+		b.flush()
+		b.reserve(int(dota.SVC_Messages_svc_PacketEntities))
+	}
+	b.ops = append(b.ops, op)
+}
+
+// flush writes the pending packet of the current tick.
+func (b *ReplayBuilder) flush() {
+	if b.err != nil {
+		return
+	}
+
+	changed := false
+	for _, t := range b.tables {
+		changed = changed || !t.created || len(t.changed) > 0
+	}
+	if b.written && !changed && len(b.messages) == 0 && len(b.ops) == 0 {
+		b.pending = false
+		return
+	}
+
+	w := newWriter()
+	if err := b.writePacket(w); err != nil {
+		b.err = err
+		return
+	}
+	buf, err := proto.Marshal(&dota.CDemoPacket{Data: w.bytes()})
+	if err != nil {
+		b.err = err
+		return
+	}
+	if err := writeOuterMessage(&b.packets, int32(dota.EDemoCommands_DEM_Packet), b.tick, buf, false); err != nil {
+		b.err = err
+		return
+	}
+
+	b.written = true
+	b.pending = false
+	b.messages = b.messages[:0]
+	b.ops = b.ops[:0]
+}
+
+// writePacket writes the inner messages of the pending packet.
+func (b *ReplayBuilder) writePacket(w *writer) error {
+	if !b.written {
+		err := writeInnerMessage(w, int32(dota.NET_Messages_net_Tick), &dota.CNETMsg_Tick{
+			Tick: proto.Uint32(b.tick),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, t := range b.tables {
+		if err := b.writeTable(w, t); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range b.messages {
+		w.writeUBitVar(uint32(m.t))
+		w.writeVarUint32(uint32(len(m.buf)))
+		w.writeBytes(m.buf)
+	}
+
+	if len(b.ops) == 0 {
+		return nil
+	}
+
+	data := newWriter()
+	index := int32(-1)
+	for _, op := range b.ops {
+		data.writeUBitVar(uint32(op.e.index - index - 1))
+		index = op.e.index
+
+		data.writeBits(op.cmd, 2)
+		if op.cmd == 0x02 {
+			data.writeBits(uint32(op.e.class.id), b.classIdSize)
+			data.writeBits(uint32(op.e.serial), 17)
+			data.writeVarUint32(0)
+		}
+		if op.cmd&0x01 == 0 {
+			data.writeBitsFrom(op.fields.buf, op.fields.n)
+		}
+	}
+
+	return writeInnerMessage(w, int32(dota.SVC_Messages_svc_PacketEntities), &dota.CSVCMsg_PacketEntities{
+		UpdatedEntries: proto.Int32(int32(len(b.ops))),
+		LegacyIsDelta:  proto.Bool(true),
+		EntityData:     data.bytes(),
+	})
+}
+
+// writeInnerMessage writes a message of a packet.
+func writeInnerMessage(w *writer, t int32, m proto.Message) error {
+	buf, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	w.writeUBitVar(uint32(t))
+	w.writeVarUint32(uint32(len(buf)))
+	w.writeBytes(buf)
+	return nil
+}
+
+// bytes returns the bits, padded to a whole number of bytes.
+func (b fieldBits) bytes() []byte {
+	w := newWriter()
+	w.writeBitsFrom(b.buf, b.n)
+	return w.bytes()
+}
+
+// builderZeroValues returns a nil value, standing for the zero value, for
+// every field of a serializer, including the fields of its tables.
+func builderZeroValues(s *serializer) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	var walk func(prefix string, s *serializer)
+	walk = func(prefix string, s *serializer) {
+		for _, f := range s.fields {
+			name := prefix + f.varName
+			switch f.model {
+			case fieldModelFixedTable:
+				values[name] = true
+				walk(name+".", f.serializer)
+			case fieldModelFixedArray:
+				for i := 0; i < f.fieldType.count; i++ {
+					values[fmt.Sprintf("%s.%04d", name, i)] = nil
+				}
+			default:
+				values[name] = nil
+			}
+		}
+	}
+	walk("", s)
+
+	return values
+}
+
+// writeBuilderValues encodes field paths and values for the given named values
+// of a serializer, in the format read by readFields.
+func writeBuilderValues(s *serializer, values map[string]interface{}) (fieldBits, error) {
+	type value struct {
+		fp *fieldPath
+		v  interface{}
+	}
+
+	var vs []value
+	defer func() {
+		for _, x := range vs {
+			x.fp.release()
+		}
+	}()
+
+	for name, v := range values {
+		fp := newFieldPath()
+		if !builderFieldPath(s, fp, name) {
+			fp.release()
+			return fieldBits{}, _errorf("unknown field %s", name)
+		}
+		vs = append(vs, value{fp, v})
+	}
+
+	sort.Slice(vs, func(i, j int) bool {
+		a, b := vs[i].fp, vs[j].fp
+		for k := 0; k <= a.last && k <= b.last; k++ {
+			if a.path[k] != b.path[k] {
+				return a.path[k] < b.path[k]
+			}
+		}
+		return a.last < b.last
+	})
+
+	paths := make([]*fieldPath, len(vs))
+	for i, x := range vs {
+		paths[i] = x.fp
+	}
+
+	w := newWriter()
+	writeFieldPaths(w, paths)
+	for _, x := range vs {
+		f := s.getFieldForFieldPath(x.fp, 0)
+		t := s.getTypeForFieldPath(x.fp, 0)
+		if err := writeBuilderValue(w, f, t, x.v); err != nil {
+			name := strings.Join(s.getNameForFieldPath(x.fp, 0), ".")
+			return fieldBits{}, _errorf("field %s: %s", name, err)
+		}
+	}
+
+	return fieldBits{w.bytes(), w.bitLen()}, nil
+}
+
+// builderFieldPath looks up the field path of a name, which may not match
+// the shape of the serializer.
+func builderFieldPath(s *serializer, fp *fieldPath, name string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return s.getFieldPathForName(fp, name)
+}
+
+// writeBuilderValue encodes a value of a field of the given type, as read by
+// the decoder of the field. A nil value stands for the zero value.
+func writeBuilderValue(w *writer, f *field, t *fieldType, v interface{}) error {
+	// Nested tables are always present.
+	if t.pointer {
+		w.writeBoolean(true)
+		return nil
+	}
+
+	if f.encoder != "" && f.encoder != "fixed64" {
+		return _errorf("unsupported encoder %s", f.encoder)
+	}
+
+	mismatch := func() error {
+		return _errorf("unsupported value %#v for type %s", v, t)
+	}
+
+	switch t.baseType {
+	case "bool":
+		x, ok := v.(bool)
+		if !ok && v != nil {
+			return mismatch()
+		}
+		w.writeBoolean(x)
+
+	case "float32", "GameTime_t":
+		if f.bitCount != nil && *f.bitCount > 0 && *f.bitCount < 32 {
+			return _errorf("unsupported quantized float")
+		}
+		x, ok := builderFloat(v)
+		if !ok {
+			return mismatch()
+		}
+		w.writeBits(math.Float32bits(x), 32)
+
+	case "Vector", "Vector2D", "Vector4D", "VectorWS":
+		n := map[string]int{"Vector": 3, "Vector2D": 2, "Vector4D": 4, "VectorWS": 3}[t.baseType]
+		x, ok := v.([]float32)
+		if v == nil {
+			x, ok = make([]float32, n), true
+		}
+		if !ok || len(x) != n {
+			return mismatch()
+		}
+		for _, c := range x {
+			w.writeBits(math.Float32bits(c), 32)
+		}
+
+	case "char", "CUtlString", "CUtlSymbolLarge":
+		x, ok := v.(string)
+		if !ok && v != nil {
+			return mismatch()
+		}
+		w.writeString(x)
+
+	case "int8", "int16", "int32", "int64":
+		x, ok := builderInt(v)
+		if !ok {
+			return mismatch()
+		}
+		w.writeVarInt32(int32(x))
+
+	case "uint64", "CStrongHandle", "HeroFacetKey_t":
+		x, ok := builderInt(v)
+		if !ok {
+			return mismatch()
+		}
+		if f.encoder == "fixed64" {
+			w.writeLeUint64(uint64(x))
+		} else {
+			w.writeVarUint64(uint64(x))
+		}
+
+	case "CNetworkedQuantizedFloat", "QAngle", "CBodyComponent", "CPhysicsComponent", "CRenderComponent":
+		return _errorf("unsupported type %s", t)
+
+	default:
+		x, ok := builderInt(v)
+		if !ok {
+			return mismatch()
+		}
+		w.writeVarUint32(uint32(x))
+	}
+
+	return nil
+}
+
+// builderInt returns an integer value as an int64, with nil as zero.
+func builderInt(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case nil:
+		return 0, true
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint:
+		return int64(x), true
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), true
+	}
+	return 0, false
+}
+
+// builderFloat returns a number as a float32, with nil as zero.
+func builderFloat(v interface{}) (float32, bool) {
+	switch x := v.(type) {
+	case float32:
+		return x, true
+	case float64:
+		return float32(x), true
+	}
+	x, ok := builderInt(v)
+	return float32(x), ok
+}
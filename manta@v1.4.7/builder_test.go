@@ -0,0 +1,308 @@
+package manta
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// _builder_classes declares the classes of a small match.
+func _builder_classes(b *ReplayBuilder) {
+	b.Class("CDOTAGamerulesProxy",
+		BuilderField{"m_pGameRules.m_fGameTime", "float32"},
+		BuilderField{"m_pGameRules.m_nGameState", "int32"},
+	)
+	b.Class("CDOTA_PlayerResource",
+		BuilderField{"m_iszPlayerNames", "CUtlSymbolLarge[24]"},
+		BuilderField{"m_iPlayerSteamIDs", "uint64[24]"},
+	)
+	b.Class("CDOTA_Unit_Hero_Juggernaut",
+		BuilderField{"m_iHealth", "int32"},
+		BuilderField{"m_flMana", "float32"},
+		BuilderField{"m_flMaxMana", "float32"},
+		BuilderField{"m_iPlayerID", "int32"},
+		BuilderField{"m_vecOrigin", "Vector"},
+		BuilderField{"m_iszUnitName", "char[128]"},
+	)
+	b.Class("CDOTA_Item_PowerTreads",
+		BuilderField{"m_iStat", "int32"},
+		BuilderField{"m_hOwnerEntity", "CHandle< CBaseEntity >"},
+		BuilderField{"m_bToggleState", "bool"},
+	)
+}
+
+// _builder_event is an entity operation or combat log entry seen by a parser.
+type _builder_event struct {
+	tick   uint32
+	what   string
+	values map[string]interface{}
+}
+
+// _builder_events parses a replay, collecting entity operations and combat
+// log entries.
+func _builder_events(t *testing.T, data []byte) ([]_builder_event, *Parser) {
+	p, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []_builder_event
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		events = append(events, _builder_event{
+			tick:   p.Tick,
+			what:   fmt.Sprintf("%s %s", op, e),
+			values: e.Map(),
+		})
+		return nil
+	})
+	p.Callbacks.OnCMsgDOTACombatLogEntry(func(m *dota.CMsgDOTACombatLogEntry) error {
+		name, _ := p.LookupStringByIndex("CombatLogNames", int32(m.GetInflictorName()))
+		events = append(events, _builder_event{
+			tick: p.Tick,
+			what: fmt.Sprintf("%s %s", m.GetType(), name),
+		})
+		return nil
+	})
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return events, p
+}
+
+func TestReplayBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.CombatLogName("npc_dota_hero_juggernaut")
+
+	b.Create(0, "CDOTAGamerulesProxy", map[string]interface{}{
+		"m_pGameRules.m_nGameState": 5,
+	})
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{
+		"m_iszPlayerNames.0006":  "yurnero",
+		"m_iPlayerSteamIDs.0006": uint64(76561198000000000),
+	})
+	hero := b.Create(10, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{
+		"m_iHealth":     620,
+		"m_flMana":      float32(300),
+		"m_flMaxMana":   float32(400),
+		"m_iPlayerID":   6,
+		"m_vecOrigin":   []float32{1, 2, 3},
+		"m_iszUnitName": "npc_dota_hero_juggernaut",
+	})
+	b.Create(20, "CDOTA_Item_PowerTreads", map[string]interface{}{
+		"m_iStat":        0,
+		"m_hOwnerEntity": hero,
+	})
+
+	// Power treads switched to intelligence, then a spell cast.
+	b.Advance(30)
+	b.Update(20, map[string]interface{}{"m_iStat": 1})
+	b.Update(10, map[string]interface{}{"m_flMana": 320.0})
+	b.Advance(60)
+	b.Update(0, map[string]interface{}{"m_pGameRules.m_fGameTime": 3.0})
+	b.Update(10, map[string]interface{}{"m_flMana": 220.0})
+	b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+		Type:          dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ABILITY.Enum(),
+		InflictorName: proto.Uint32(b.CombatLogName("juggernaut_blade_fury")),
+	})
+	b.Advance(30)
+	b.Delete(20)
+
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	events, p := _builder_events(t, data)
+	var whats []string
+	var ticks []uint32
+	for _, e := range events {
+		whats = append(whats, e.what)
+		ticks = append(ticks, e.tick)
+	}
+	assert.Equal([]string{
+		"Created+Entered 0 <CDOTAGamerulesProxy>",
+		"Created+Entered 1 <CDOTA_PlayerResource>",
+		"Created+Entered 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"Created+Entered 20 <CDOTA_Item_PowerTreads>",
+		"Updated 20 <CDOTA_Item_PowerTreads>",
+		"Updated 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"Updated 0 <CDOTAGamerulesProxy>",
+		"Updated 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"DOTA_COMBATLOG_ABILITY juggernaut_blade_fury",
+		"Deleted+Left 20 <CDOTA_Item_PowerTreads>",
+	}, whats)
+	assert.Equal([]uint32{0, 0, 0, 0, 30, 30, 90, 90, 90, 120}, ticks)
+	assert.Equal(uint32(120), p.Tick)
+
+	// Values which were not set keep their zero value.
+	assert.Equal(map[string]interface{}{
+		"m_pGameRules.m_fGameTime":  float32(0),
+		"m_pGameRules.m_nGameState": int32(5),
+	}, events[0].values)
+	assert.Equal("yurnero", events[1].values["m_iszPlayerNames.0006"])
+	assert.Equal("", events[1].values["m_iszPlayerNames.0000"])
+	assert.Equal(uint64(76561198000000000), events[1].values["m_iPlayerSteamIDs.0006"])
+	assert.Equal(map[string]interface{}{
+		"m_iHealth":     int32(620),
+		"m_flMana":      float32(300),
+		"m_flMaxMana":   float32(400),
+		"m_iPlayerID":   int32(6),
+		"m_vecOrigin":   []float32{1, 2, 3},
+		"m_iszUnitName": "npc_dota_hero_juggernaut",
+	}, events[2].values)
+	assert.Equal(hero, events[3].values["m_hOwnerEntity"])
+	assert.Equal(false, events[3].values["m_bToggleState"])
+	assert.Equal(int32(1), events[4].values["m_iStat"])
+	assert.Equal(float32(320), events[5].values["m_flMana"])
+	assert.Equal(float32(3), events[6].values["m_pGameRules.m_fGameTime"])
+	assert.Equal(float32(220), events[7].values["m_flMana"])
+
+	// Handles resolve to the entities created.
+	assert.Equal("CDOTA_Unit_Hero_Juggernaut", p.FindEntityByHandle(hero).GetClassName())
+	assert.Nil(p.FindEntity(20))
+
+	name, ok := p.LookupStringByIndex("CombatLogNames", 0)
+	assert.True(ok)
+	assert.Equal("npc_dota_hero_juggernaut", name)
+}
+
+func TestReplayBuilderOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+
+	// Everything written at a tick is seen in the order it was written, even
+	// where the parser processes some messages of a packet first.
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", nil)
+	b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+		Type:          dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ABILITY.Enum(),
+		InflictorName: proto.Uint32(b.CombatLogName("juggernaut_omni_slash")),
+	})
+	b.Update(10, map[string]interface{}{"m_iHealth": 100})
+	b.Update(10, map[string]interface{}{"m_iHealth": 50})
+	b.Delete(10)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", nil)
+	for i := 0; i < 20; i++ {
+		b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+			Type:          dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DAMAGE.Enum(),
+			InflictorName: proto.Uint32(b.CombatLogName(fmt.Sprintf("damage_%02d", i))),
+		})
+	}
+
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	events, p := _builder_events(t, data)
+	var whats []string
+	for _, e := range events {
+		whats = append(whats, e.what)
+	}
+	want := []string{
+		"Created+Entered 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"DOTA_COMBATLOG_ABILITY juggernaut_omni_slash",
+		"Updated 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"Updated 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"Deleted+Left 10 <CDOTA_Unit_Hero_Juggernaut>",
+		"Created+Entered 10 <CDOTA_Unit_Hero_Juggernaut>",
+	}
+	for i := 0; i < 20; i++ {
+		want = append(want, fmt.Sprintf("DOTA_COMBATLOG_DAMAGE damage_%02d", i))
+	}
+	assert.Equal(want, whats)
+	assert.Equal(int32(50), events[3].values["m_iHealth"])
+	assert.Equal(int32(0), events[5].values["m_iHealth"])
+	assert.Equal(int32(2), p.FindEntity(10).GetSerial())
+}
+
+func TestReplayBuilderStringTables(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	b.StringTable("ActiveModifiers", "a", []byte{1})
+	b.Advance(1)
+	b.StringTable("ActiveModifiers", "a", []byte{2})
+	b.StringTable("ActiveModifiers", "b", []byte{3})
+	b.StringTable("EconItems", "c", []byte{4})
+
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	var values [][]byte
+	p.Callbacks.OnCSVCMsg_UpdateStringTable(func(m *dota.CSVCMsg_UpdateStringTable) error {
+		values = append(values, m.GetStringData())
+		return nil
+	})
+	if !assert.Nil(p.Start()) {
+		return
+	}
+	assert.Len(values, 1)
+
+	modifiers, ok := p.stringTables.GetTableByName("ActiveModifiers")
+	if assert.True(ok) {
+		assert.Equal([]byte{2}, modifiers.Items[0].Value)
+		assert.Equal("b", modifiers.Items[1].Key)
+		assert.Equal([]byte{3}, modifiers.Items[1].Value)
+	}
+	items, ok := p.stringTables.GetTableByName("EconItems")
+	if assert.True(ok) {
+		assert.Equal("c", items.Items[0].Key)
+	}
+}
+
+func TestReplayBuilderErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, script := range map[string]func(b *ReplayBuilder){
+		"unknown class": func(b *ReplayBuilder) {
+			b.Create(1, "CDOTA_Unit_Hero_Axe", nil)
+		},
+		"unknown field": func(b *ReplayBuilder) {
+			b.Create(1, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iMana": 1})
+		},
+		"field of a value": func(b *ReplayBuilder) {
+			b.Create(1, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iHealth.m_x": 1})
+		},
+		"bad value": func(b *ReplayBuilder) {
+			b.Create(1, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iHealth": "full"})
+		},
+		"missing entity": func(b *ReplayBuilder) {
+			b.Update(1, map[string]interface{}{"m_iHealth": 1})
+		},
+		"existing entity": func(b *ReplayBuilder) {
+			b.Create(1, "CDOTA_Unit_Hero_Juggernaut", nil)
+			b.Create(1, "CDOTA_Unit_Hero_Juggernaut", nil)
+		},
+		"late class": func(b *ReplayBuilder) {
+			b.Advance(1)
+			b.Class("CDOTA_Unit_Hero_Axe")
+		},
+		"duplicate field": func(b *ReplayBuilder) {
+			b.Class("CDOTA_Unit_Hero_Axe", BuilderField{"m_iHealth", "int32"}, BuilderField{"m_iHealth", "int32"})
+		},
+		"unsupported type": func(b *ReplayBuilder) {
+			b.Class("CDOTA_Unit_Hero_Axe", BuilderField{"m_angRotation", "QAngle"})
+		},
+	} {
+		b := NewReplayBuilder()
+		_builder_classes(b)
+		script(b)
+		_, err := b.Bytes()
+		assert.NotNil(err, name)
+	}
+}
@@ -5,8 +5,17 @@ import (
 	"github.com/golang/protobuf/proto"
 )
 
+// RawMessageHandler receives a message by type id, together with the tick it
+// belongs to and its undecoded protobuf contents. The buffer is only valid
+// for the duration of the call unless the Parser uses immutable buffers, see
+// RetainBytes and UseImmutableBuffers.
+type RawMessageHandler func(t int32, tick uint32, buf []byte) error
+
 // Callbacks decodes and routes replay events to callback functions
 type Callbacks struct {
+	onAnyDemoMessage                                       []RawMessageHandler
+	onAnyPacketMessage                                     []RawMessageHandler
+	onUnknownMessage                                       []RawMessageHandler
 	onCDemoStop                                            []func(*dota.CDemoStop) error
 	onCDemoFileHeader                                      []func(*dota.CDemoFileHeader) error
 	onCDemoFileInfo                                        []func(*dota.CDemoFileInfo) error
@@ -289,6 +298,24 @@ func newCallbacks() *Callbacks {
 	}
 }
 
+// OnAnyDemoMessage registers a callback for every outer demo message, called
+// before any type specific callback.
+func (c *Callbacks) OnAnyDemoMessage(fn RawMessageHandler) {
+	c.onAnyDemoMessage = append(c.onAnyDemoMessage, fn)
+}
+
+// OnAnyPacketMessage registers a callback for every message contained in a
+// packet, called before any type specific callback.
+func (c *Callbacks) OnAnyPacketMessage(fn RawMessageHandler) {
+	c.onAnyPacketMessage = append(c.onAnyPacketMessage, fn)
+}
+
+// OnUnknownMessage registers a callback for packet messages whose type id
+// has no known protobuf type.
+func (c *Callbacks) OnUnknownMessage(fn RawMessageHandler) {
+	c.onUnknownMessage = append(c.onUnknownMessage, fn)
+}
+
 // OnCDemoStop registers a callback EDemoCommands_DEM_Stop
 func (c *Callbacks) OnCDemoStop(fn func(*dota.CDemoStop) error) {
 	c.onCDemoStop = append(c.onCDemoStop, fn)
@@ -1649,7 +1676,13 @@ func (c *Callbacks) OnCDOTAUserMsg_MadstoneAlert(fn func(*dota.CDOTAUserMsg_Mads
 	c.onCDOTAUserMsg_MadstoneAlert = append(c.onCDOTAUserMsg_MadstoneAlert, fn)
 }
 
-func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
+func (c *Callbacks) callByDemoType(t int32, tick uint32, buf []byte) error {
+	for _, fn := range c.onAnyDemoMessage {
+		if err := fn(t, tick, buf); err != nil {
+			return err
+		}
+	}
+
 	switch t {
 	case 0: // dota.EDemoCommands_DEM_Stop
 		if c.onCDemoStop == nil {
@@ -1659,7 +1692,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoStop{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoStop {
@@ -1678,7 +1711,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoFileHeader{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoFileHeader {
@@ -1697,7 +1730,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoFileInfo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoFileInfo {
@@ -1716,7 +1749,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoSyncTick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoSyncTick {
@@ -1735,7 +1768,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoSendTables{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoSendTables {
@@ -1754,7 +1787,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoClassInfo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoClassInfo {
@@ -1773,7 +1806,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoStringTables{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoStringTables {
@@ -1792,7 +1825,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoPacket{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoPacket {
@@ -1811,7 +1844,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoPacket{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoSignonPacket {
@@ -1830,7 +1863,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoConsoleCmd{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoConsoleCmd {
@@ -1849,7 +1882,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoCustomData{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoCustomData {
@@ -1868,7 +1901,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoCustomDataCallbacks{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoCustomDataCallbacks {
@@ -1887,7 +1920,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoUserCmd{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoUserCmd {
@@ -1906,7 +1939,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoFullPacket{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoFullPacket {
@@ -1925,7 +1958,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoSaveGame{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoSaveGame {
@@ -1944,7 +1977,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoSpawnGroups{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoSpawnGroups {
@@ -1963,7 +1996,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoAnimationData{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoAnimationData {
@@ -1982,7 +2015,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoAnimationHeader{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoAnimationHeader {
@@ -2001,7 +2034,7 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 		msg := &dota.CDemoRecovery{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDemoRecovery {
@@ -2021,7 +2054,13 @@ func (c *Callbacks) callByDemoType(t int32, buf []byte) error {
 	return nil
 }
 
-func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
+func (c *Callbacks) callByPacketType(t int32, tick uint32, buf []byte) error {
+	for _, fn := range c.onAnyPacketMessage {
+		if err := fn(t, tick, buf); err != nil {
+			return err
+		}
+	}
+
 	switch t {
 	case 0: // dota.NET_Messages_net_NOP
 		if c.onCNETMsg_NOP == nil {
@@ -2031,7 +2070,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_NOP{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_NOP {
@@ -2050,7 +2089,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SplitScreenUser{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SplitScreenUser {
@@ -2069,7 +2108,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_Tick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_Tick {
@@ -2088,7 +2127,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_StringCmd{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_StringCmd {
@@ -2107,7 +2146,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SetConVar{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SetConVar {
@@ -2126,7 +2165,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SignonState{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SignonState {
@@ -2145,7 +2184,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SpawnGroup_Load{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SpawnGroup_Load {
@@ -2164,7 +2203,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SpawnGroup_ManifestUpdate{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SpawnGroup_ManifestUpdate {
@@ -2183,7 +2222,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SpawnGroup_SetCreationTick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SpawnGroup_SetCreationTick {
@@ -2202,7 +2241,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SpawnGroup_Unload{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SpawnGroup_Unload {
@@ -2221,7 +2260,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_SpawnGroup_LoadCompleted{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_SpawnGroup_LoadCompleted {
@@ -2240,7 +2279,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CNETMsg_DebugOverlay{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCNETMsg_DebugOverlay {
@@ -2259,7 +2298,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_ServerInfo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_ServerInfo {
@@ -2278,7 +2317,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_FlattenedSerializer{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_FlattenedSerializer {
@@ -2297,7 +2336,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_ClassInfo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_ClassInfo {
@@ -2316,7 +2355,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_SetPause{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_SetPause {
@@ -2335,7 +2374,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_CreateStringTable{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_CreateStringTable {
@@ -2354,7 +2393,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_UpdateStringTable{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_UpdateStringTable {
@@ -2373,7 +2412,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_VoiceInit{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_VoiceInit {
@@ -2392,7 +2431,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_VoiceData{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_VoiceData {
@@ -2411,7 +2450,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_Print{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_Print {
@@ -2430,7 +2469,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_Sounds{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_Sounds {
@@ -2449,7 +2488,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_SetView{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_SetView {
@@ -2468,7 +2507,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_ClearAllStringTables{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_ClearAllStringTables {
@@ -2487,7 +2526,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_CmdKeyValues{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_CmdKeyValues {
@@ -2506,7 +2545,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_BSPDecal{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_BSPDecal {
@@ -2525,7 +2564,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_SplitScreen{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_SplitScreen {
@@ -2544,7 +2583,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_PacketEntities{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_PacketEntities {
@@ -2563,7 +2602,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_Prefetch{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_Prefetch {
@@ -2582,7 +2621,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_Menu{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_Menu {
@@ -2601,7 +2640,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_GetCvarValue{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_GetCvarValue {
@@ -2620,7 +2659,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_StopSound{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_StopSound {
@@ -2639,7 +2678,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_PeerList{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_PeerList {
@@ -2658,7 +2697,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_PacketReliable{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_PacketReliable {
@@ -2677,7 +2716,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_HLTVStatus{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_HLTVStatus {
@@ -2696,7 +2735,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_ServerSteamID{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_ServerSteamID {
@@ -2715,7 +2754,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_FullFrameSplit{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_FullFrameSplit {
@@ -2734,7 +2773,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_RconServerDetails{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_RconServerDetails {
@@ -2753,7 +2792,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_UserMessage{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_UserMessage {
@@ -2772,7 +2811,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_Broadcast_Command{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_Broadcast_Command {
@@ -2791,7 +2830,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CSVCMsg_HltvFixupOperatorStatus{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCSVCMsg_HltvFixupOperatorStatus {
@@ -2810,7 +2849,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageAchievementEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageAchievementEvent {
@@ -2829,7 +2868,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageCloseCaption{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageCloseCaption {
@@ -2848,7 +2887,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageCloseCaptionDirect{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageCloseCaptionDirect {
@@ -2867,7 +2906,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageCurrentTimescale{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageCurrentTimescale {
@@ -2886,7 +2925,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageDesiredTimescale{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageDesiredTimescale {
@@ -2905,7 +2944,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageFade{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageFade {
@@ -2924,7 +2963,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageGameTitle{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageGameTitle {
@@ -2943,7 +2982,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageHudMsg{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageHudMsg {
@@ -2962,7 +3001,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageHudText{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageHudText {
@@ -2981,7 +3020,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageColoredText{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageColoredText {
@@ -3000,7 +3039,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageRequestState{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageRequestState {
@@ -3019,7 +3058,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageResetHUD{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageResetHUD {
@@ -3038,7 +3077,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageRumble{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageRumble {
@@ -3057,7 +3096,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageSayText{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageSayText {
@@ -3076,7 +3115,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageSayText2{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageSayText2 {
@@ -3095,7 +3134,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageSayTextChannel{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageSayTextChannel {
@@ -3114,7 +3153,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageShake{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageShake {
@@ -3133,7 +3172,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageShakeDir{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageShakeDir {
@@ -3152,7 +3191,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageWaterShake{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageWaterShake {
@@ -3171,7 +3210,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageTextMsg{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageTextMsg {
@@ -3190,7 +3229,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageScreenTilt{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageScreenTilt {
@@ -3209,7 +3248,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageVoiceMask{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageVoiceMask {
@@ -3228,7 +3267,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageSendAudio{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageSendAudio {
@@ -3247,7 +3286,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageItemPickup{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageItemPickup {
@@ -3266,7 +3305,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageAmmoDenied{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageAmmoDenied {
@@ -3285,7 +3324,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageShowMenu{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageShowMenu {
@@ -3304,7 +3343,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageCreditsMsg{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageCreditsMsg {
@@ -3323,7 +3362,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CEntityMessagePlayJingle{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCEntityMessagePlayJingle {
@@ -3342,7 +3381,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CEntityMessageScreenOverlay{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCEntityMessageScreenOverlay {
@@ -3361,7 +3400,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CEntityMessageRemoveAllDecals{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCEntityMessageRemoveAllDecals {
@@ -3380,7 +3419,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CEntityMessagePropagateForce{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCEntityMessagePropagateForce {
@@ -3399,7 +3438,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CEntityMessageDoSpark{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCEntityMessageDoSpark {
@@ -3418,7 +3457,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CEntityMessageFixAngle{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCEntityMessageFixAngle {
@@ -3437,7 +3476,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageCloseCaptionPlaceholder{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageCloseCaptionPlaceholder {
@@ -3456,7 +3495,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageCameraTransition{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageCameraTransition {
@@ -3475,7 +3514,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageAudioParameter{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageAudioParameter {
@@ -3494,7 +3533,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageHapticsManagerPulse{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageHapticsManagerPulse {
@@ -3513,7 +3552,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageHapticsManagerEffect{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageHapticsManagerEffect {
@@ -3532,7 +3571,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageUpdateCssClasses{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageUpdateCssClasses {
@@ -3551,7 +3590,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageServerFrameTime{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageServerFrameTime {
@@ -3570,7 +3609,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageLagCompensationError{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageLagCompensationError {
@@ -3589,7 +3628,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageRequestDllStatus{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageRequestDllStatus {
@@ -3608,7 +3647,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageRequestUtilAction{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageRequestUtilAction {
@@ -3627,7 +3666,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageRequestInventory{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageRequestInventory {
@@ -3646,7 +3685,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CUserMessageRequestDiagnostic{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCUserMessageRequestDiagnostic {
@@ -3665,7 +3704,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgVDebugGameSessionIDEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgVDebugGameSessionIDEvent {
@@ -3684,7 +3723,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgPlaceDecalEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgPlaceDecalEvent {
@@ -3703,7 +3742,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgClearWorldDecalsEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgClearWorldDecalsEvent {
@@ -3722,7 +3761,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgClearEntityDecalsEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgClearEntityDecalsEvent {
@@ -3741,7 +3780,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgClearDecalsForSkeletonInstanceEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgClearDecalsForSkeletonInstanceEvent {
@@ -3760,7 +3799,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSource1LegacyGameEventList{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSource1LegacyGameEventList {
@@ -3779,7 +3818,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSource1LegacyListenEvents{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSource1LegacyListenEvents {
@@ -3798,7 +3837,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSource1LegacyGameEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSource1LegacyGameEvent {
@@ -3817,7 +3856,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSosStartSoundEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSosStartSoundEvent {
@@ -3836,7 +3875,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSosStopSoundEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSosStopSoundEvent {
@@ -3855,7 +3894,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSosSetSoundEventParams{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSosSetSoundEventParams {
@@ -3874,7 +3913,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSosSetLibraryStackFields{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSosSetLibraryStackFields {
@@ -3893,7 +3932,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgSosStopSoundEventHash{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgSosStopSoundEventHash {
@@ -3912,7 +3951,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AIDebugLine{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AIDebugLine {
@@ -3931,7 +3970,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ChatEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ChatEvent {
@@ -3950,7 +3989,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CombatHeroPositions{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CombatHeroPositions {
@@ -3969,7 +4008,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CombatLogBulkData{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CombatLogBulkData {
@@ -3988,7 +4027,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CreateLinearProjectile{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CreateLinearProjectile {
@@ -4007,7 +4046,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DestroyLinearProjectile{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DestroyLinearProjectile {
@@ -4026,7 +4065,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DodgeTrackingProjectiles{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DodgeTrackingProjectiles {
@@ -4045,7 +4084,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_GlobalLightColor{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_GlobalLightColor {
@@ -4064,7 +4103,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_GlobalLightDirection{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_GlobalLightDirection {
@@ -4083,7 +4122,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_InvalidCommand{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_InvalidCommand {
@@ -4102,7 +4141,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_LocationPing{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_LocationPing {
@@ -4121,7 +4160,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MapLine{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MapLine {
@@ -4140,7 +4179,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MiniKillCamInfo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MiniKillCamInfo {
@@ -4159,7 +4198,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MinimapDebugPoint{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MinimapDebugPoint {
@@ -4178,7 +4217,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MinimapEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MinimapEvent {
@@ -4197,7 +4236,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_NevermoreRequiem{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_NevermoreRequiem {
@@ -4216,7 +4255,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_OverheadEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_OverheadEvent {
@@ -4235,7 +4274,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SetNextAutobuyItem{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SetNextAutobuyItem {
@@ -4254,7 +4293,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SharedCooldown{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SharedCooldown {
@@ -4273,7 +4312,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SpectatorPlayerClick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SpectatorPlayerClick {
@@ -4292,7 +4331,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TutorialTipInfo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TutorialTipInfo {
@@ -4311,7 +4350,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_UnitEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_UnitEvent {
@@ -4330,7 +4369,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_BotChat{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_BotChat {
@@ -4349,7 +4388,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HudError{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HudError {
@@ -4368,7 +4407,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ItemPurchased{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ItemPurchased {
@@ -4387,7 +4426,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_Ping{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_Ping {
@@ -4406,7 +4445,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ItemFound{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ItemFound {
@@ -4425,7 +4464,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SwapVerify{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SwapVerify {
@@ -4444,7 +4483,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_WorldLine{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_WorldLine {
@@ -4463,7 +4502,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgGCToClientTournamentItemDrop{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgGCToClientTournamentItemDrop {
@@ -4482,7 +4521,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ItemAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ItemAlert {
@@ -4501,7 +4540,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HalloweenDrops{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HalloweenDrops {
@@ -4520,7 +4559,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ChatWheel{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ChatWheel {
@@ -4539,7 +4578,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ReceivedXmasGift{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ReceivedXmasGift {
@@ -4558,7 +4597,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_UpdateSharedContent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_UpdateSharedContent {
@@ -4577,7 +4616,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TutorialRequestExp{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TutorialRequestExp {
@@ -4596,7 +4635,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TutorialPingMinimap{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TutorialPingMinimap {
@@ -4615,7 +4654,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_GamerulesStateChanged{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_GamerulesStateChanged {
@@ -4634,7 +4673,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ShowSurvey{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ShowSurvey {
@@ -4653,7 +4692,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TutorialFade{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TutorialFade {
@@ -4672,7 +4711,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AddQuestLogEntry{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AddQuestLogEntry {
@@ -4691,7 +4730,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SendStatPopup{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SendStatPopup {
@@ -4710,7 +4749,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TutorialFinish{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TutorialFinish {
@@ -4729,7 +4768,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SendRoshanPopup{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SendRoshanPopup {
@@ -4748,7 +4787,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SendGenericToolTip{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SendGenericToolTip {
@@ -4767,7 +4806,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SendFinalGold{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SendFinalGold {
@@ -4786,7 +4825,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CustomMsg{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CustomMsg {
@@ -4805,7 +4844,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CoachHUDPing{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CoachHUDPing {
@@ -4824,7 +4863,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ClientLoadGridNav{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ClientLoadGridNav {
@@ -4843,7 +4882,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TE_Projectile{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TE_Projectile {
@@ -4862,7 +4901,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TE_ProjectileLoc{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TE_ProjectileLoc {
@@ -4881,7 +4920,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TE_DotaBloodImpact{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TE_DotaBloodImpact {
@@ -4900,7 +4939,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TE_UnitAnimation{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TE_UnitAnimation {
@@ -4919,7 +4958,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TE_UnitAnimationEnd{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TE_UnitAnimationEnd {
@@ -4938,7 +4977,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AbilityPing{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AbilityPing {
@@ -4957,7 +4996,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ShowGenericPopup{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ShowGenericPopup {
@@ -4976,7 +5015,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_VoteStart{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_VoteStart {
@@ -4995,7 +5034,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_VoteUpdate{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_VoteUpdate {
@@ -5014,7 +5053,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_VoteEnd{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_VoteEnd {
@@ -5033,7 +5072,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_BoosterState{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_BoosterState {
@@ -5052,7 +5091,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_WillPurchaseAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_WillPurchaseAlert {
@@ -5071,7 +5110,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TutorialMinimapPosition{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TutorialMinimapPosition {
@@ -5090,7 +5129,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AbilitySteal{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AbilitySteal {
@@ -5109,7 +5148,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CourierKilledAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CourierKilledAlert {
@@ -5128,7 +5167,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_EnemyItemAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_EnemyItemAlert {
@@ -5147,7 +5186,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_StatsMatchDetails{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_StatsMatchDetails {
@@ -5166,7 +5205,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MiniTaunt{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MiniTaunt {
@@ -5185,7 +5224,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_BuyBackStateAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_BuyBackStateAlert {
@@ -5204,7 +5243,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SpeechBubble{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SpeechBubble {
@@ -5223,7 +5262,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CustomHeaderMessage{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CustomHeaderMessage {
@@ -5242,7 +5281,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_QuickBuyAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_QuickBuyAlert {
@@ -5261,7 +5300,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_StatsHeroMinuteDetails{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_StatsHeroMinuteDetails {
@@ -5280,7 +5319,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ModifierAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ModifierAlert {
@@ -5299,7 +5338,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HPManaAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HPManaAlert {
@@ -5318,7 +5357,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_GlyphAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_GlyphAlert {
@@ -5337,7 +5376,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_BeastChat{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_BeastChat {
@@ -5356,7 +5395,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SpectatorPlayerUnitOrders{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SpectatorPlayerUnitOrders {
@@ -5375,7 +5414,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CustomHudElement_Create{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CustomHudElement_Create {
@@ -5394,7 +5433,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CustomHudElement_Modify{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CustomHudElement_Modify {
@@ -5413,7 +5452,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CustomHudElement_Destroy{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CustomHudElement_Destroy {
@@ -5432,7 +5471,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_CompendiumState{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_CompendiumState {
@@ -5451,7 +5490,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ProjectionAbility{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ProjectionAbility {
@@ -5470,7 +5509,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ProjectionEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ProjectionEvent {
@@ -5489,7 +5528,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CMsgDOTACombatLogEntry{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCMsgDOTACombatLogEntry {
@@ -5508,7 +5547,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_XPAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_XPAlert {
@@ -5527,7 +5566,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_UpdateQuestProgress{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_UpdateQuestProgress {
@@ -5546,7 +5585,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAMatchMetadataFile{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAMatchMetadataFile {
@@ -5565,7 +5604,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_QuestStatus{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_QuestStatus {
@@ -5584,7 +5623,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SuggestHeroPick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SuggestHeroPick {
@@ -5603,7 +5642,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SuggestHeroRole{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SuggestHeroRole {
@@ -5622,7 +5661,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_KillcamDamageTaken{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_KillcamDamageTaken {
@@ -5641,7 +5680,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SelectPenaltyGold{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SelectPenaltyGold {
@@ -5660,7 +5699,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_RollDiceResult{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_RollDiceResult {
@@ -5679,7 +5718,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_FlipCoinResult{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_FlipCoinResult {
@@ -5698,7 +5737,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SendRoshanSpectatorPhase{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SendRoshanSpectatorPhase {
@@ -5717,7 +5756,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ChatWheelCooldown{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ChatWheelCooldown {
@@ -5736,7 +5775,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DismissAllStatPopups{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DismissAllStatPopups {
@@ -5755,7 +5794,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TE_DestroyProjectile{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TE_DestroyProjectile {
@@ -5774,7 +5813,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HeroRelicProgress{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HeroRelicProgress {
@@ -5793,7 +5832,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AbilityDraftRequestAbility{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AbilityDraftRequestAbility {
@@ -5812,7 +5851,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ItemSold{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ItemSold {
@@ -5831,7 +5870,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DamageReport{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DamageReport {
@@ -5850,7 +5889,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_SalutePlayer{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_SalutePlayer {
@@ -5869,7 +5908,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TipAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TipAlert {
@@ -5888,7 +5927,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ReplaceQueryUnit{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ReplaceQueryUnit {
@@ -5907,7 +5946,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_EmptyTeleportAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_EmptyTeleportAlert {
@@ -5926,7 +5965,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MarsArenaOfBloodAttack{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MarsArenaOfBloodAttack {
@@ -5945,7 +5984,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ESArcanaCombo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ESArcanaCombo {
@@ -5964,7 +6003,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ESArcanaComboSummary{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ESArcanaComboSummary {
@@ -5983,7 +6022,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HighFiveLeftHanging{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HighFiveLeftHanging {
@@ -6002,7 +6041,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HighFiveCompleted{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HighFiveCompleted {
@@ -6021,7 +6060,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ShovelUnearth{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ShovelUnearth {
@@ -6040,7 +6079,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_RadarAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_RadarAlert {
@@ -6059,7 +6098,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AllStarEvent{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AllStarEvent {
@@ -6078,7 +6117,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TalentTreeAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TalentTreeAlert {
@@ -6097,7 +6136,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_QueuedOrderRemoved{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_QueuedOrderRemoved {
@@ -6116,7 +6155,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DebugChallenge{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DebugChallenge {
@@ -6135,7 +6174,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_OMArcanaCombo{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_OMArcanaCombo {
@@ -6154,7 +6193,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_FoundNeutralItem{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_FoundNeutralItem {
@@ -6173,7 +6212,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_OutpostCaptured{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_OutpostCaptured {
@@ -6192,7 +6231,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_OutpostGrantedXP{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_OutpostGrantedXP {
@@ -6211,7 +6250,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MoveCameraToUnit{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MoveCameraToUnit {
@@ -6230,7 +6269,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_PauseMinigameData{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_PauseMinigameData {
@@ -6249,7 +6288,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_VersusScene_PlayerBehavior{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_VersusScene_PlayerBehavior {
@@ -6268,7 +6307,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_QoP_ArcanaSummary{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_QoP_ArcanaSummary {
@@ -6287,7 +6326,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HotPotato_Created{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HotPotato_Created {
@@ -6306,7 +6345,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_HotPotato_Exploded{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_HotPotato_Exploded {
@@ -6325,7 +6364,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_WK_Arcana_Progress{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_WK_Arcana_Progress {
@@ -6344,7 +6383,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_GuildChallenge_Progress{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_GuildChallenge_Progress {
@@ -6363,7 +6402,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_WRArcanaProgress{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_WRArcanaProgress {
@@ -6382,7 +6421,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_WRArcanaSummary{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_WRArcanaSummary {
@@ -6401,7 +6440,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_EmptyItemSlotAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_EmptyItemSlotAlert {
@@ -6420,7 +6459,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_AghsStatusAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_AghsStatusAlert {
@@ -6439,7 +6478,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_PingConfirmation{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_PingConfirmation {
@@ -6458,7 +6497,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MutedPlayers{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MutedPlayers {
@@ -6477,7 +6516,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ContextualTip{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ContextualTip {
@@ -6496,7 +6535,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_ChatMessage{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_ChatMessage {
@@ -6515,7 +6554,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_NeutralCampAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_NeutralCampAlert {
@@ -6534,7 +6573,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_RockPaperScissorsStarted{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_RockPaperScissorsStarted {
@@ -6553,7 +6592,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_RockPaperScissorsFinished{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_RockPaperScissorsFinished {
@@ -6572,7 +6611,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DuelOpponentKilled{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DuelOpponentKilled {
@@ -6591,7 +6630,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DuelAccepted{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DuelAccepted {
@@ -6610,7 +6649,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_DuelRequested{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_DuelRequested {
@@ -6629,7 +6668,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MuertaReleaseEvent_AssignedTargetKilled{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MuertaReleaseEvent_AssignedTargetKilled {
@@ -6648,7 +6687,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_PlayerDraftSuggestPick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_PlayerDraftSuggestPick {
@@ -6667,7 +6706,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_PlayerDraftPick{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_PlayerDraftPick {
@@ -6686,7 +6725,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_UpdateLinearProjectileCPData{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_UpdateLinearProjectileCPData {
@@ -6705,7 +6744,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_GiftPlayer{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_GiftPlayer {
@@ -6724,7 +6763,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_FacetPing{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_FacetPing {
@@ -6743,7 +6782,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_InnatePing{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_InnatePing {
@@ -6762,7 +6801,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_RoshanTimer{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_RoshanTimer {
@@ -6781,7 +6820,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_NeutralCraftAvailable{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_NeutralCraftAvailable {
@@ -6800,7 +6839,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_TimerAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_TimerAlert {
@@ -6819,7 +6858,7 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 		msg := &dota.CDOTAUserMsg_MadstoneAlert{}
 		c.pb.SetBuf(buf)
 		if err := c.pb.Unmarshal(msg); err != nil {
-			return err
+			return &decodeError{err}
 		}
 
 		for _, fn := range c.onCDOTAUserMsg_MadstoneAlert {
@@ -6832,5 +6871,11 @@ func (c *Callbacks) callByPacketType(t int32, buf []byte) error {
 
 	}
 
+	for _, fn := range c.onUnknownMessage {
+		if err := fn(t, tick, buf); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,57 @@
+package manta
+
+import (
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbacksRawHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newCallbacks()
+
+	type call struct {
+		hook string
+		t    int32
+		tick uint32
+	}
+	var calls []call
+	hook := func(name string) RawMessageHandler {
+		return func(t int32, tick uint32, buf []byte) error {
+			calls = append(calls, call{name, t, tick})
+			return nil
+		}
+	}
+	c.OnAnyDemoMessage(hook("demo"))
+	c.OnAnyPacketMessage(hook("packet"))
+	c.OnUnknownMessage(hook("unknown"))
+
+	var ticks []uint32
+	c.OnCNETMsg_Tick(func(m *dota.CNETMsg_Tick) error {
+		ticks = append(ticks, m.GetTick())
+		return nil
+	})
+
+	buf := _proto_marshal(&dota.CNETMsg_Tick{Tick: proto.Uint32(42)})
+	assert.Nil(c.callByPacketType(int32(dota.NET_Messages_net_Tick), 42, buf))
+	assert.Nil(c.callByPacketType(9999, 43, []byte{1}))
+	assert.Nil(c.callByDemoType(int32(dota.EDemoCommands_DEM_SyncTick), 44, nil))
+
+	assert.Equal([]call{
+		{"packet", int32(dota.NET_Messages_net_Tick), 42},
+		{"packet", 9999, 43},
+		{"unknown", 9999, 43},
+		{"demo", int32(dota.EDemoCommands_DEM_SyncTick), 44},
+	}, calls)
+	assert.Equal([]uint32{42}, ticks)
+
+	// Errors returned by a raw hook abort dispatch.
+	c.OnAnyPacketMessage(func(t int32, tick uint32, buf []byte) error {
+		return _errorf("stop")
+	})
+	assert.NotNil(c.callByPacketType(int32(dota.NET_Messages_net_Tick), 45, buf))
+	assert.Equal([]uint32{42}, ticks)
+}
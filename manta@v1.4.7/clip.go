@@ -0,0 +1,244 @@
+package manta
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+// WriteClip writes a standalone replay holding the ticks from start to end
+// of the replay read from r. The clip holds the signon messages of the
+// replay, with its send tables, class info and string tables, then a full
+// packet synthesized at the start tick from the entities and string tables
+// at that point, followed by the original packets up to the end tick.
+// Compressed messages are written compressed.
+func WriteClip(r io.Reader, w io.Writer, start, end uint32) error {
+	p, err := NewStreamParser(r)
+	if err != nil {
+		return err
+	}
+	p.recordFieldBits = true
+
+	c := &clipWriter{
+		p:     p,
+		w:     bufio.NewWriter(w),
+		start: start,
+		end:   end,
+	}
+
+	if _, err := c.w.Write(magicSource2); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(make([]byte, 8)); err != nil {
+		return err
+	}
+
+	p.Callbacks.OnAnyDemoMessage(c.onDemoMessage)
+	if err := p.Start(); err != nil {
+		return err
+	}
+	if !c.started {
+		return _errorf("clip: replay ends before tick %d", start)
+	}
+
+	stop, err := proto.Marshal(&dota.CDemoStop{})
+	if err != nil {
+		return err
+	}
+	if err := c.writeMessage(int32(dota.EDemoCommands_DEM_Stop), c.tick, stop, false); err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
+
+// clipWriter copies the outer messages of a clip as the parser reads them.
+type clipWriter struct {
+	p       *Parser
+	w       *bufio.Writer
+	start   uint32
+	end     uint32
+	synced  bool
+	started bool
+	tick    uint32
+}
+
+func (c *clipWriter) onDemoMessage(t int32, tick uint32, buf []byte) error {
+	compressed := c.p.lastOuterMessage != nil && c.p.lastOuterMessage.compressed
+
+	// Signon messages are kept as they are.
+	if !c.synced {
+		c.synced = t == int32(dota.EDemoCommands_DEM_SyncTick)
+		return c.writeMessage(t, tick, buf, compressed)
+	}
+
+	switch dota.EDemoCommands(t) {
+	case dota.EDemoCommands_DEM_Stop, dota.EDemoCommands_DEM_FileInfo:
+		return nil
+	}
+
+	if !c.started {
+		if tick < c.start {
+			return nil
+		}
+
+		// Entity and string table state is captured before the first
+		// message of the clip is processed.
+		full, err := c.fullPacket()
+		if err != nil {
+			return err
+		}
+		if err := c.writeMessage(int32(dota.EDemoCommands_DEM_FullPacket), tick, full, true); err != nil {
+			return err
+		}
+		c.started = true
+		c.stopRecording()
+	}
+
+	if tick > c.end {
+		c.p.Stop()
+		return nil
+	}
+
+	c.tick = tick
+	return c.writeMessage(t, tick, buf, compressed)
+}
+
+// writeMessage writes an outer message.
+func (c *clipWriter) writeMessage(t int32, tick uint32, buf []byte, compress bool) error {
+	return writeOuterMessage(c.w, t, tick, buf, compress)
+}
+
+// writeOuterMessage writes an outer message in the format read by
+// readOuterMessage, compressing it with snappy when asked to.
+func writeOuterMessage(w io.Writer, t int32, tick uint32, buf []byte, compress bool) error {
+	cmd := uint64(t)
+	if compress {
+		cmd |= uint64(dota.EDemoCommands_DEM_IsCompressed)
+		buf = snappy.Encode(nil, buf)
+	}
+
+	header := make([]byte, 3*binary.MaxVarintLen32)
+	n := binary.PutUvarint(header, cmd)
+	n += binary.PutUvarint(header[n:], uint64(tick))
+	n += binary.PutUvarint(header[n:], uint64(len(buf)))
+
+	if _, err := w.Write(header[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// stopRecording drops the encoded field values kept for the full packet.
+func (c *clipWriter) stopRecording() {
+	c.p.recordFieldBits = false
+	for _, e := range c.p.entities {
+		if e != nil && e.raw != nil {
+			e.raw.release()
+			e.raw = nil
+		}
+	}
+}
+
+// fullPacket builds a CDemoFullPacket holding the current string tables and
+// entities.
+func (c *clipWriter) fullPacket() ([]byte, error) {
+	inner := newWriter()
+	writeInner := func(t int32, m proto.Message) error {
+		buf, err := proto.Marshal(m)
+		if err != nil {
+			return err
+		}
+		inner.writeUBitVar(uint32(t))
+		inner.writeVarUint32(uint32(len(buf)))
+		inner.writeBytes(buf)
+		return nil
+	}
+
+	var tables []*stringTable
+	for _, t := range c.p.stringTables.Tables {
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].index < tables[j].index })
+
+	for _, t := range tables {
+		items := make([]*stringTableItem, 0, len(t.Items))
+		for _, item := range t.Items {
+			items = append(items, item)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Index < items[j].Index })
+
+		err := writeInner(int32(dota.SVC_Messages_svc_UpdateStringTable), &dota.CSVCMsg_UpdateStringTable{
+			TableId:           proto.Int32(t.index),
+			NumChangedEntries: proto.Int32(int32(len(items))),
+			StringData:        writeStringTable(items, t.userDataFixedSize, t.userDataSizeBits, t.flags, t.varintBitCounts),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, count := c.entityData()
+	err := writeInner(int32(dota.SVC_Messages_svc_PacketEntities), &dota.CSVCMsg_PacketEntities{
+		UpdatedEntries: proto.Int32(int32(count)),
+		LegacyIsDelta:  proto.Bool(false),
+		EntityData:     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&dota.CDemoFullPacket{
+		Packet: &dota.CDemoPacket{Data: inner.bytes()},
+	})
+}
+
+// entityData encodes the creation of every current entity with all of its
+// fields, returning the data and the number of entities.
+func (c *clipWriter) entityData() ([]byte, int) {
+	var entities []*Entity
+	for _, e := range c.p.entities {
+		if e != nil && e.raw != nil {
+			entities = append(entities, e)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].index < entities[j].index })
+
+	w := newWriter()
+	index := int32(-1)
+	for _, e := range entities {
+		w.writeUBitVar(uint32(e.index - index - 1))
+		index = e.index
+
+		// Create the entity.
+		w.writeBits(2, 2)
+		w.writeBits(uint32(e.class.classId), c.p.classIdSize)
+		w.writeBits(uint32(e.serial), 17)
+		w.writeVarUint32(0)
+
+		var paths []*fieldPath
+		var values []fieldBits
+		fp := newFieldPath()
+		e.raw.walk(fp, func(fp *fieldPath, v interface{}) {
+			if b, ok := v.(fieldBits); ok {
+				paths = append(paths, fp.copy())
+				values = append(values, b)
+			}
+		})
+		fp.release()
+
+		writeFieldPaths(w, paths)
+		for i, fp := range paths {
+			w.writeBitsFrom(values[i].buf, values[i].n)
+			fp.release()
+		}
+	}
+
+	return w.bytes(), len(entities)
+}
@@ -0,0 +1,303 @@
+package manta
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+	values := make([]uint32, 1000)
+	for i := range values {
+		values[i] = rnd.Uint32() >> uint(rnd.Intn(32))
+	}
+
+	w := newWriter()
+	for _, x := range values {
+		w.writeUBitVar(x)
+		w.writeUBitVarFP(x >> 1)
+		w.writeVarUint32(x)
+		w.writeVarInt32(int32(x) - 1<<30)
+		w.writeBits(x, 7)
+		w.writeBoolean(x&1 == 1)
+	}
+	w.writeString("npc_dota_hero_phantom_assassin")
+	w.writeBytes([]byte{1, 2, 3})
+
+	r := newReader(w.bytes())
+	for _, x := range values {
+		assert.Equal(x, r.readUBitVar())
+		assert.Equal(int(x>>1), r.readUBitVarFieldPath())
+		assert.Equal(x, r.readVarUint32())
+		assert.Equal(int32(x)-1<<30, r.readVarInt32())
+		assert.Equal(x&127, r.readBits(7))
+		assert.Equal(x&1 == 1, r.readBoolean())
+	}
+	assert.Equal("npc_dota_hero_phantom_assassin", r.readString())
+	assert.Equal([]byte{1, 2, 3}, r.readBytes(3))
+	assert.True(r.remBits() < 8)
+}
+
+func TestWriterBitRange(t *testing.T) {
+	assert := assert.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+	buf := make([]byte, 64)
+	rnd.Read(buf)
+
+	// Any range of bits copied into another stream reads back the same, at
+	// any alignment.
+	for i := 0; i < 500; i++ {
+		start := uint32(rnd.Intn(256))
+		end := start + uint32(rnd.Intn(200))
+		offset := uint32(rnd.Intn(8))
+
+		w := newWriter()
+		w.writeBits(0, offset)
+		w.writeBitsFrom(readBitRange(buf, start, end), end-start)
+		assert.Equal(offset+end-start, w.bitLen())
+
+		want := newReader(buf)
+		for n := uint32(0); n < start; n++ {
+			want.readBoolean()
+		}
+		got := newReader(w.bytes())
+		got.readBits(offset)
+		for n := start; n < end; n++ {
+			if !assert.Equal(want.readBoolean(), got.readBoolean(), "range %d-%d bit %d", start, end, n) {
+				break
+			}
+		}
+	}
+}
+
+// _field_path_round_trip encodes the given field paths and decodes them back.
+func _field_path_round_trip(paths []*fieldPath) []string {
+	w := newWriter()
+	writeFieldPaths(w, paths)
+
+	var got []string
+	for _, fp := range readFieldPaths(newReader(w.bytes())) {
+		got = append(got, fp.String())
+		fp.release()
+	}
+	return got
+}
+
+func TestWriteFieldPathsBaselines(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, buf := range _instance_baselines(t) {
+		paths := readFieldPaths(newReader(buf))
+		var want []string
+		for _, fp := range paths {
+			want = append(want, fp.String())
+		}
+		assert.Equal(want, _field_path_round_trip(paths), name)
+		for _, fp := range paths {
+			fp.release()
+		}
+	}
+}
+
+func TestWriteFieldPathsRandom(t *testing.T) {
+	assert := assert.New(t)
+
+	// Random paths need every kind of op, including moving backwards.
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var paths []*fieldPath
+		var want []string
+		for n := rnd.Intn(40); n > 0; n-- {
+			fp := newFieldPath()
+			fp.last = rnd.Intn(len(fp.path))
+			for j := 0; j <= fp.last; j++ {
+				fp.path[j] = rnd.Intn(1 << uint(1+rnd.Intn(18)))
+			}
+			paths = append(paths, fp)
+			want = append(want, fp.String())
+		}
+		assert.Equal(want, _field_path_round_trip(paths), "paths %d", i)
+	}
+}
+
+func TestWriteStringTable(t *testing.T) {
+	assert := assert.New(t)
+
+	items := []*stringTableItem{
+		{0, "npc_dota_hero_phantom_assassin", []byte{1, 2, 3}},
+		{1, "", []byte{4}},
+		{5, "dota_ability_phantom_strike", nil},
+		{6, "item_blink", bytes.Repeat([]byte{7}, 300)},
+	}
+
+	for _, varint := range []bool{false, true} {
+		buf := writeStringTable(items, false, 0, 1, varint)
+		got := parseStringTable(buf, int32(len(items)), "test", false, 0, 1, varint)
+		if assert.Len(got, len(items)) {
+			for i, item := range items {
+				assert.Equal(item.Index, got[i].Index)
+				assert.Equal(item.Key, got[i].Key)
+				assert.Equal(len(item.Value), len(got[i].Value))
+				if len(item.Value) > 0 {
+					assert.Equal(item.Value, got[i].Value)
+				}
+			}
+		}
+	}
+
+	// Fixed size values keep their exact number of bits.
+	fixed := []*stringTableItem{{0, "a", []byte{0xff, 0x01}}, {1, "b", []byte{0x12, 0x00}}}
+	got := parseStringTable(writeStringTable(fixed, true, 9, 0, false), 2, "test", true, 9, 0, false)
+	if assert.Len(got, 2) {
+		assert.Equal([]byte{0xff, 0x01}, got[0].Value)
+		assert.Equal([]byte{0x12, 0x00}, got[1].Value)
+	}
+}
+
+func TestWriteClip(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _demo_stream(12)
+	out := &bytes.Buffer{}
+	if !assert.Nil(WriteClip(bytes.NewReader(data), out, 8, 14)) {
+		return
+	}
+
+	// The clip holds the signon messages, a full packet at the start tick,
+	// the messages from the start to the end tick, then a stop.
+	var ticks []uint32
+	var types []int32
+	for _, m := range _collect_demo_messages(t, out.Bytes(), 0) {
+		ticks = append(ticks, m.tick)
+		types = append(types, m.t)
+	}
+	assert.Equal([]uint32{0, 8, 8, 10, 12, 14, 14}, ticks)
+	assert.Equal(int32(dota.EDemoCommands_DEM_FullPacket), types[1])
+	assert.Equal(int32(dota.EDemoCommands_DEM_Stop), types[6])
+
+	// Messages are copied with their payloads and compression.
+	original := _outer_messages(t, data)
+	clip := _outer_messages(t, out.Bytes())
+	assert.Equal(original[0], clip[0])
+	assert.Equal(original[4:8], clip[2:6])
+
+	assert.NotNil(WriteClip(bytes.NewReader(data), &bytes.Buffer{}, 100, 200))
+}
+
+// _entity_snapshots parses a replay, taking a snapshot of every entity before
+// the first message of each tick from start to end.
+func _entity_snapshots(t *testing.T, data []byte, start, end uint32) map[uint32]map[int32]map[string]interface{} {
+	p, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots := make(map[uint32]map[int32]map[string]interface{})
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		if tick <= start || tick > end || snapshots[tick] != nil {
+			return nil
+		}
+		entities := make(map[int32]map[string]interface{})
+		for index, e := range p.entities {
+			if e != nil {
+				entities[index] = e.Map()
+			}
+		}
+		snapshots[tick] = entities
+		return nil
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return snapshots
+}
+
+func TestWriteClipReplay(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping test in CI environment")
+	}
+	if testing.Short() {
+		t.Skip("skipping replays in short mode")
+	}
+
+	assert := assert.New(t)
+
+	data := mustGetReplayData("2159568145", "https://s3-us-west-2.amazonaws.com/manta.dotabuff/2159568145.dem")
+
+	const start, end = 60000, 61000
+	out := &bytes.Buffer{}
+	if !assert.Nil(WriteClip(bytes.NewReader(data), out, start, end)) {
+		return
+	}
+	assert.True(out.Len() < len(data)/10)
+
+	want := _entity_snapshots(t, data, start, end)
+	got := _entity_snapshots(t, out.Bytes(), start, end)
+	assert.NotEmpty(want)
+	assert.Equal(len(want), len(got))
+	for tick, entities := range want {
+		assert.Equal(len(entities), len(got[tick]), "tick %d", tick)
+		for index, values := range entities {
+			if !assert.Equal(values, got[tick][index], "tick %d entity %d", tick, index) {
+				return
+			}
+		}
+	}
+}
+
+func TestWriteClipBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	// A replay whose entities and string tables change at every tick.
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.StringTable("ActiveModifiers", "modifier_juggernaut_blade_fury", []byte{1})
+
+	rnd := rand.New(rand.NewSource(1))
+	b.Create(0, "CDOTAGamerulesProxy", nil)
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{"m_iszPlayerNames.0006": "yurnero"})
+	hero := b.Create(10, "CDOTA_Unit_Hero_Juggernaut", nil)
+	for tick := 0; tick < 300; tick += 10 {
+		b.Update(0, map[string]interface{}{"m_pGameRules.m_fGameTime": float32(tick) / 30})
+		b.Update(10, map[string]interface{}{
+			"m_iHealth": rnd.Intn(1000),
+			"m_flMana":  rnd.Float32() * 400,
+		})
+		if tick%70 == 0 {
+			if b.Handle(20) != 0 {
+				b.Delete(20)
+			} else {
+				b.Create(20, "CDOTA_Item_PowerTreads", map[string]interface{}{
+					"m_iStat":        rnd.Intn(3),
+					"m_hOwnerEntity": hero,
+				})
+			}
+		}
+		b.StringTable("ActiveModifiers", fmt.Sprintf("modifier_%d", tick), []byte{byte(tick)})
+		b.Advance(10)
+	}
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	const start, end = 100, 200
+	out := &bytes.Buffer{}
+	if !assert.Nil(WriteClip(bytes.NewReader(data), out, start, end)) {
+		return
+	}
+
+	want := _entity_snapshots(t, data, start, end)
+	got := _entity_snapshots(t, out.Bytes(), start, end)
+	assert.Len(want, 10)
+	assert.Equal(want, got)
+}
@@ -2,6 +2,7 @@ package manta
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/dotabuff/manta/dota"
 )
@@ -44,7 +45,11 @@ func (o EntityOp) String() string {
 	return entityOpNames[o]
 }
 
-// EntityHandler is a function that receives Entity updates
+// EntityHandler is a function that receives Entity updates. The Entity may
+// be kept after the handler returns: it follows later updates while it
+// exists, and keeps its last values once deleted until a new entity is
+// created at its index. From then on it has no values anymore, Get returns
+// nil and Map an empty map, as its state is reused by the new entity.
 type EntityHandler func(*Entity, EntityOp) error
 
 // Entity represents a single game entity in the replay
@@ -54,23 +59,82 @@ type Entity struct {
 	class   *class
 	active  bool
 	state   *fieldState
+	raw     *fieldState
+	changes []fieldChange
 	fpCache map[string]*fieldPath
 	fpNoop  map[string]bool
+	values  map[string]interface{}
 }
 
-// newEntity returns a new entity for the given index, serial and class
+// newEntity returns a new entity for the given index, serial and class. The
+// field path caches are allocated by Get, which most entities never see.
 func newEntity(index, serial int32, class *class) *Entity {
 	return &Entity{
-		index:   index,
-		serial:  serial,
-		class:   class,
-		active:  true,
-		state:   newFieldState(),
-		fpCache: make(map[string]*fieldPath),
-		fpNoop:  make(map[string]bool),
+		index:  index,
+		serial: serial,
+		class:  class,
+		active: true,
+		state:  newFieldState(),
 	}
 }
 
+// newSkippedEntity returns a new entity of a class not materialized by the
+// parser, which keeps no state.
+func newSkippedEntity(index, serial int32, class *class) *Entity {
+	return &Entity{
+		index:  index,
+		serial: serial,
+		class:  class,
+		active: true,
+	}
+}
+
+// skipped returns whether the entity keeps no state.
+func (e *Entity) skipped() bool {
+	return e.state == nil && e.values == nil
+}
+
+// changes returns where the fields set by an update of an entity are
+// collected, or nil if no handler needs them.
+func (p *Parser) changes(e *Entity) *[]fieldChange {
+	if !p.recordChanges || e.state == nil {
+		return nil
+	}
+	e.changes = e.changes[:0]
+	return &e.changes
+}
+
+// newDetachedEntity returns a new entity whose state is held as named values
+// rather than decoded field state, as used when replaying an event log.
+func newDetachedEntity(index, serial int32, class *class) *Entity {
+	return &Entity{
+		index:  index,
+		serial: serial,
+		class:  class,
+		active: true,
+		values: make(map[string]interface{}),
+	}
+}
+
+// release returns the state of a deleted entity for reuse by new entities.
+// The entity has no values afterwards.
+func (e *Entity) release() {
+	if e.state != nil {
+		e.state.release()
+		e.state = nil
+	}
+	if e.raw != nil {
+		e.raw.release()
+		e.raw = nil
+	}
+	for _, fp := range e.fpCache {
+		fp.release()
+	}
+	e.fpCache = nil
+	e.fpNoop = nil
+	e.changes = nil
+}
+
 // String returns a human identifiable string for the Entity
 func (e *Entity) String() string {
 	return fmt.Sprintf("%d <%s>", e.index, e.class.name)
@@ -79,6 +143,15 @@ func (e *Entity) String() string {
 // Map returns a map of current entity state as key-value pairs
 func (e *Entity) Map() map[string]interface{} {
 	values := make(map[string]interface{})
+	if e.values != nil {
+		for k, v := range e.values {
+			values[k] = v
+		}
+		return values
+	}
+	if e.state == nil {
+		return values
+	}
 	for _, fp := range e.class.getFieldPaths(newFieldPath(), e.state) {
 		values[e.class.getNameForFieldPath(fp)] = e.state.get(fp)
 	}
@@ -92,6 +165,12 @@ func (e *Entity) Dump() {
 
 // Get returns the current value of the Entity state for the given key
 func (e *Entity) Get(name string) interface{} {
+	if e.values != nil {
+		return e.values[name]
+	}
+	if e.state == nil {
+		return nil
+	}
 	if fp, ok := e.fpCache[name]; ok {
 		return e.state.get(fp)
 	}
@@ -99,6 +178,11 @@ func (e *Entity) Get(name string) interface{} {
 		return nil
 	}
 
+	if e.fpCache == nil {
+		e.fpCache = make(map[string]*fieldPath)
+		e.fpNoop = make(map[string]bool)
+	}
+
 	fp := newFieldPath()
 	if !e.class.getFieldPathForName(fp, name) {
 		e.fpNoop[name] = true
@@ -110,6 +194,33 @@ func (e *Entity) Get(name string) interface{} {
 	return e.state.get(fp)
 }
 
+// FieldType returns the networked type of the given field, such as "int32",
+// "CNetworkedQuantizedFloat" or "CHandle<CBaseEntity>", or an empty string
+// if the Entity has no such field.
+func (e *Entity) FieldType(name string) string {
+	if e.class == nil || e.class.serializer == nil {
+		return ""
+	}
+
+	fp := newFieldPath()
+	defer fp.release()
+	if !e.class.getFieldPathForName(fp, name) {
+		return ""
+	}
+	t := e.class.getTypeForFieldPath(fp)
+	if t == nil {
+		return ""
+	}
+
+	// Elements of fixed arrays are typed as the whole array.
+	if f := e.class.serializer.getFieldForFieldPath(fp, 0); f.model == fieldModelFixedArray && t.count > 0 {
+		elem := *t
+		elem.count = 0
+		return elem.String()
+	}
+	return t.String()
+}
+
 // Exists returns true if the given key exists in the Entity state
 func (e *Entity) Exists(name string) bool {
 	return e.Get(name) != nil
@@ -178,6 +289,11 @@ func (e *Entity) GetIndex() int32 {
 	return e.index
 }
 
+// GetHandle returns the handle of this Entity, combining its serial and index
+func (e *Entity) GetHandle() uint64 {
+	return uint64(e.serial)<<indexBits | uint64(e.index)
+}
+
 // FindEntity finds a given Entity by index
 func (p *Parser) FindEntity(index int32) *Entity {
 	return p.entities[index]
@@ -230,6 +346,10 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 	var e *Entity
 	var op EntityOp
 
+	if p.subsystems&SubsystemEntities == 0 {
+		return nil
+	}
+
 	if !m.GetLegacyIsDelta() {
 		if p.entityFullPackets > 0 {
 			return nil
@@ -243,6 +363,10 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 	}
 	tuples := make([]tuple, 0, updates)
 
+	// Deleted entities whose index is reused are released once every
+	// handler has seen this packet.
+	var released []*Entity
+
 	for ; updates > 0; updates-- {
 		index += int32(r.readUBitVar()) + 1
 		op = EntityOpNone
@@ -254,6 +378,11 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 				serial = int32(r.readBits(17))
 				r.readVarUint32()
 
+				if d := p.deletedEntities[index]; d != nil {
+					released = append(released, d)
+					delete(p.deletedEntities, index)
+				}
+
 				class := p.classesById[classId]
 				if class == nil {
 					_panicf("unable to find new class %d", classId)
@@ -264,10 +393,19 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 					_panicf("unable to find new baseline %d", classId)
 				}
 
-				e = newEntity(index, serial, class)
+				// Entities of unwanted classes have no state, their fields
+				// are read only to move past them.
+				if p.materializes(class) {
+					e = newEntity(index, serial, class)
+					if p.recordFieldBits {
+						e.raw = newFieldState()
+					}
+					readFields(newReader(baseline), class.serializer, e.state, e.raw, nil)
+				} else {
+					e = newSkippedEntity(index, serial, class)
+				}
 				p.entities[index] = e
-				readFields(newReader(baseline), class.serializer, e.state)
-				readFields(r, class.serializer, e.state)
+				readFields(r, class.serializer, e.state, e.raw, nil)
 				op = EntityOpCreated | EntityOpEntered
 
 			} else {
@@ -281,7 +419,7 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 					op |= EntityOpEntered
 				}
 
-				readFields(r, e.class.serializer, e.state)
+				readFields(r, e.class.serializer, e.state, e.raw, p.changes(e))
 			}
 
 		} else {
@@ -297,6 +435,7 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 			if cmd&0x02 != 0 {
 				op |= EntityOpDeleted
 				p.entities[index] = nil
+				p.deletedEntities[index] = e
 			}
 		}
 
@@ -305,17 +444,60 @@ func (p *Parser) onCSVCMsg_PacketEntities(m *dota.CSVCMsg_PacketEntities) error
 
 	for _, h := range p.entityHandlers {
 		for _, t := range tuples {
+			if t.e.skipped() {
+				continue
+			}
 			if err := h(t.e, t.op); err != nil {
 				return err
 			}
 		}
 	}
 
+	for _, e := range released {
+		e.release()
+	}
+
+	return nil
+}
+
+// dropEntities deletes all entities, offering them to entity handlers as
+// deleted, so that the next full packet creates them again.
+func (p *Parser) dropEntities() error {
+	var deleted []*Entity
+	for index, e := range p.entities {
+		if e != nil {
+			deleted = append(deleted, e)
+		}
+		delete(p.entities, index)
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].index < deleted[j].index })
+
+	for _, h := range p.entityHandlers {
+		for _, e := range deleted {
+			if e.skipped() {
+				continue
+			}
+			if err := h(e, EntityOpDeletedLeft); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range deleted {
+		if d := p.deletedEntities[e.index]; d != nil {
+			d.release()
+		}
+		p.deletedEntities[e.index] = e
+	}
+	p.entityFullPackets = 0
+
 	return nil
 }
 
 // OnEntity registers an EntityHandler that will be called when an entity
-// is created, updated, deleted, etc.
+// is created, updated, deleted, etc. Entities kept by handlers keep their
+// values once deleted, until a new entity is created at their index, see
+// EntityHandler.
 func (p *Parser) OnEntity(h EntityHandler) {
 	p.entityHandlers = append(p.entityHandlers, h)
 }
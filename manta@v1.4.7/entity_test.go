@@ -162,3 +162,152 @@ func (s entityTestScenario) test(t *testing.T) {
 		assert.True(found, "unable to find entity %s at tick %d", ee.class, s.tick)
 	}
 }
+
+func TestEntityRelease(t *testing.T) {
+	assert := assert.New(t)
+
+	fp := newFieldPath()
+	fp.path[0] = 2
+	fp.path[1] = 1
+	fp.last = 1
+
+	e := newEntity(1, 1, &class{name: "CDOTA_Unit_Hero_Puck"})
+	e.state.set(fp, int32(7))
+	assert.Equal(int32(7), e.state.get(fp))
+
+	e.release()
+	assert.Nil(e.state)
+	assert.Nil(e.Get("m_iHealth"))
+	assert.Empty(e.Map())
+
+	// Reused states come back empty.
+	for i := 0; i < 10; i++ {
+		s := newFieldState()
+		assert.Nil(s.get(fp))
+		s.set(fp, int32(i))
+		s.release()
+	}
+	fp.release()
+}
+
+func TestEntityRetained(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iHealth": 620})
+	b.Advance(1)
+	b.Update(10, map[string]interface{}{"m_iHealth": 500})
+	b.Advance(1)
+	b.Delete(10)
+	b.Advance(1)
+	b.Create(11, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iHealth": 300})
+	b.Advance(1)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iHealth": 100})
+	b.Advance(1)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	var kept *Entity
+	var health []interface{}
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		if kept == nil && e.GetIndex() == 10 {
+			kept = e
+		}
+		if kept != nil {
+			health = append(health, kept.Get("m_iHealth"))
+		}
+		return nil
+	})
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	// A kept entity follows its updates and keeps its last values once
+	// deleted, until a new entity is created at its index.
+	assert.Equal([]interface{}{int32(620), int32(500), int32(500), int32(500), int32(500)}, health)
+	assert.Nil(kept.Get("m_iHealth"))
+	assert.Empty(kept.Map())
+	assert.Equal(int32(100), p.FindEntity(10).Get("m_iHealth"))
+}
+
+func TestEntityFieldType(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.Create(0, "CDOTAGamerulesProxy", nil)
+	b.Create(1, "CDOTA_PlayerResource", nil)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", nil)
+	b.Create(20, "CDOTA_Item_PowerTreads", nil)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	types := make(map[string]string)
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		for _, name := range []string{
+			"m_pGameRules.m_fGameTime",
+			"m_iPlayerSteamIDs.0006",
+			"m_iszPlayerNames.0006",
+			"m_iHealth",
+			"m_vecOrigin",
+			"m_iszUnitName",
+			"m_hOwnerEntity",
+			"m_bToggleState",
+		} {
+			if e.Exists(name) {
+				types[name] = e.FieldType(name)
+			}
+		}
+		assert.Equal("", e.FieldType("m_flNotAField"))
+		return nil
+	})
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	assert.Equal(map[string]string{
+		"m_pGameRules.m_fGameTime": "float32",
+		"m_iPlayerSteamIDs.0006":   "uint64",
+		"m_iszPlayerNames.0006":    "CUtlSymbolLarge",
+		"m_iHealth":                "int32",
+		"m_vecOrigin":              "Vector",
+		"m_iszUnitName":            "char[128]",
+		"m_hOwnerEntity":           "CHandle<CBaseEntity>",
+		"m_bToggleState":           "bool",
+	}, types)
+}
+
+func benchmarkEntityLifecycle(b *testing.B, release bool) {
+	fp := newFieldPath()
+	fp.last = 1
+	class := &class{name: "CDOTA_Unit_Hero_Puck"}
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		e := newEntity(1, 1, class)
+		for i := 0; i < 32; i++ {
+			fp.path[0] = i
+			fp.path[1] = i % 4
+			e.state.set(fp, int32(i))
+		}
+		if release {
+			e.release()
+		}
+	}
+}
+
+func BenchmarkEntityLifecycle(b *testing.B)       { benchmarkEntityLifecycle(b, false) }
+func BenchmarkEntityLifecyclePooled(b *testing.B) { benchmarkEntityLifecycle(b, true) }
@@ -0,0 +1,604 @@
+package manta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/dotabuff/manta/dota"
+)
+
+// The first 8 bytes of an event log.
+var magicEventLog = []byte{'M', 'E', 'V', 'L', 'O', 'G', '1', '\000'}
+
+// Record kinds written to an event log.
+const (
+	eventLogTick = iota + 1
+	eventLogDemo
+	eventLogPacket
+	eventLogClass
+	eventLogField
+	eventLogEntity
+)
+
+// Value tags for entity field values written to an event log.
+const (
+	eventLogValueNil = iota
+	eventLogValueBool
+	eventLogValueInt32
+	eventLogValueUint32
+	eventLogValueUint64
+	eventLogValueFloat32
+	eventLogValueString
+	eventLogValueVector
+)
+
+// DefaultRecordedDemoMessages are the outer demo messages written by a
+// Recorder when no other demo messages are configured.
+var DefaultRecordedDemoMessages = []int32{
+	int32(dota.EDemoCommands_DEM_FileHeader),
+	int32(dota.EDemoCommands_DEM_FileInfo),
+}
+
+// DefaultRecordedPacketMessages are the inner packet messages written by a
+// Recorder when no other packet messages are configured. They cover ticks,
+// string tables, game events, chat and the combat log.
+var DefaultRecordedPacketMessages = []int32{
+	int32(dota.NET_Messages_net_Tick),
+	int32(dota.SVC_Messages_svc_ServerInfo),
+	int32(dota.SVC_Messages_svc_SetPause),
+	int32(dota.SVC_Messages_svc_CreateStringTable),
+	int32(dota.SVC_Messages_svc_UpdateStringTable),
+	int32(dota.EBaseGameEvents_GE_Source1LegacyGameEventList),
+	int32(dota.EBaseGameEvents_GE_Source1LegacyGameEvent),
+	int32(dota.EBaseUserMessages_UM_SayText2),
+	int32(dota.EDotaUserMessages_DOTA_UM_ChatEvent),
+	int32(dota.EDotaUserMessages_DOTA_UM_ChatMessage),
+	int32(dota.EDotaUserMessages_DOTA_UM_ChatWheel),
+	int32(dota.EDotaUserMessages_DOTA_UM_LocationPing),
+	int32(dota.EDotaUserMessages_DOTA_UM_MapLine),
+	int32(dota.EDotaUserMessages_DOTA_UM_CombatLogDataHLTV),
+}
+
+// RecorderConfig selects what a Recorder writes to the event log.
+type RecorderConfig struct {
+	// Classes selects the entities whose field changes are recorded, using
+	// the same patterns as NewHistory. No entities are recorded if empty.
+	Classes map[string][]string
+
+	// DemoMessages lists the outer demo message types to record. Defaults to
+	// DefaultRecordedDemoMessages.
+	DemoMessages []int32
+
+	// PacketMessages lists the inner packet message types to record. Defaults
+	// to DefaultRecordedPacketMessages.
+	PacketMessages []int32
+}
+
+// Recorder writes the resolved event stream of a parse to an event log, which
+// can later be replayed with NewEventLogParser without decoding the replay.
+type Recorder struct {
+	parser   *Parser
+	w        *eventLogWriter
+	selector *entitySelector
+	demo     map[int32]bool
+	packet   map[int32]bool
+	last     map[uint64]map[string]interface{}
+}
+
+// NewRecorder creates a Recorder writing to w and registers it with the
+// parser. Call Flush once parsing has finished.
+func NewRecorder(p *Parser, w io.Writer, config RecorderConfig) (*Recorder, error) {
+	if config.DemoMessages == nil {
+		config.DemoMessages = DefaultRecordedDemoMessages
+	}
+	if config.PacketMessages == nil {
+		config.PacketMessages = DefaultRecordedPacketMessages
+	}
+
+	r := &Recorder{
+		parser: p,
+		w:      newEventLogWriter(w),
+		demo:   make(map[int32]bool),
+		packet: make(map[int32]bool),
+		last:   make(map[uint64]map[string]interface{}),
+	}
+	for _, t := range config.DemoMessages {
+		r.demo[t] = true
+	}
+	for _, t := range config.PacketMessages {
+		r.packet[t] = true
+	}
+
+	if err := r.w.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	p.Callbacks.OnAnyDemoMessage(r.onDemoMessage)
+	p.Callbacks.OnAnyPacketMessage(r.onPacketMessage)
+
+	if len(config.Classes) > 0 {
+		r.selector = newEntitySelector(config.Classes)
+		p.OnEntity(r.onEntity)
+	}
+
+	return r, nil
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (r *Recorder) Flush() error {
+	return r.w.Flush()
+}
+
+func (r *Recorder) onDemoMessage(t int32, tick uint32, buf []byte) error {
+	if !r.demo[t] {
+		return nil
+	}
+	return r.w.writeMessage(eventLogDemo, tick, t, buf)
+}
+
+func (r *Recorder) onPacketMessage(t int32, tick uint32, buf []byte) error {
+	if !r.packet[t] {
+		return nil
+	}
+	return r.w.writeMessage(eventLogPacket, tick, t, buf)
+}
+
+// onEntity writes the fields of a selected entity which changed since the
+// entity was last recorded.
+func (r *Recorder) onEntity(e *Entity, op EntityOp) error {
+	fields, ok := r.selector.fields(e.GetClassName())
+	if !ok {
+		return nil
+	}
+
+	handle := e.GetHandle()
+	last, ok := r.last[handle]
+	if !ok || op.Flag(EntityOpCreated) {
+		last = make(map[string]interface{})
+		r.last[handle] = last
+	}
+
+	changed := make(map[string]interface{})
+	if op.Flag(EntityOpDeleted) {
+		delete(r.last, handle)
+	} else if len(fields) == 0 {
+		for name, v := range e.Map() {
+			if v != nil && !fieldValueEqual(last[name], v) {
+				changed[name] = v
+			}
+		}
+	} else {
+		for _, name := range fields {
+			if v := e.Get(name); v != nil && !fieldValueEqual(last[name], v) {
+				changed[name] = v
+			}
+		}
+	}
+
+	if op == EntityOpUpdated && len(changed) == 0 {
+		return nil
+	}
+
+	for name, v := range changed {
+		last[name] = v
+	}
+
+	return r.w.writeEntity(r.parser.Tick, e.index, e.serial, e.class, op, changed)
+}
+
+// eventLogWriter encodes event log records.
+type eventLogWriter struct {
+	*bufio.Writer
+	tick    uint32
+	started bool
+	classes map[int32]bool
+	fields  map[string]uint32
+	scratch []byte
+}
+
+// newEventLogWriter creates an eventLogWriter writing to w.
+func newEventLogWriter(w io.Writer) *eventLogWriter {
+	return &eventLogWriter{
+		Writer:  bufio.NewWriter(w),
+		classes: make(map[int32]bool),
+		fields:  make(map[string]uint32),
+		scratch: make([]byte, binary.MaxVarintLen64),
+	}
+}
+
+func (w *eventLogWriter) writeHeader() error {
+	_, err := w.Write(magicEventLog)
+	return err
+}
+
+func (w *eventLogWriter) writeUvarint(x uint64) error {
+	n := binary.PutUvarint(w.scratch, x)
+	_, err := w.Write(w.scratch[:n])
+	return err
+}
+
+func (w *eventLogWriter) writeVarint(x int64) error {
+	n := binary.PutVarint(w.scratch, x)
+	_, err := w.Write(w.scratch[:n])
+	return err
+}
+
+func (w *eventLogWriter) writeBytes(buf []byte) error {
+	if err := w.writeUvarint(uint64(len(buf))); err != nil {
+		return err
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// writeTick writes a tick record if the tick differs from the last one.
+func (w *eventLogWriter) writeTick(tick uint32) error {
+	if w.started && tick == w.tick {
+		return nil
+	}
+	w.tick = tick
+	w.started = true
+
+	if err := w.writeUvarint(eventLogTick); err != nil {
+		return err
+	}
+	return w.writeUvarint(uint64(tick))
+}
+
+// writeMessage writes a raw demo or packet message.
+func (w *eventLogWriter) writeMessage(kind int, tick uint32, t int32, buf []byte) error {
+	if err := w.writeTick(tick); err != nil {
+		return err
+	}
+	if err := w.writeUvarint(uint64(kind)); err != nil {
+		return err
+	}
+	if err := w.writeUvarint(uint64(t)); err != nil {
+		return err
+	}
+	return w.writeBytes(buf)
+}
+
+// writeEntity writes an entity operation along with changed field values,
+// defining the class and any new field names first.
+func (w *eventLogWriter) writeEntity(tick uint32, index, serial int32, c *class, op EntityOp, values map[string]interface{}) error {
+	if err := w.writeTick(tick); err != nil {
+		return err
+	}
+
+	if !w.classes[c.classId] {
+		w.classes[c.classId] = true
+		if err := w.writeUvarint(eventLogClass); err != nil {
+			return err
+		}
+		if err := w.writeUvarint(uint64(c.classId)); err != nil {
+			return err
+		}
+		if err := w.writeBytes([]byte(c.name)); err != nil {
+			return err
+		}
+	}
+
+	// Names are sorted so that field ids and values are written in the same
+	// order on every run.
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := w.fields[name]; ok {
+			continue
+		}
+		id := uint32(len(w.fields))
+		w.fields[name] = id
+		if err := w.writeUvarint(eventLogField); err != nil {
+			return err
+		}
+		if err := w.writeUvarint(uint64(id)); err != nil {
+			return err
+		}
+		if err := w.writeBytes([]byte(name)); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range []uint64{eventLogEntity, uint64(index), uint64(serial), uint64(c.classId), uint64(op), uint64(len(values))} {
+		if err := w.writeUvarint(x); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		if err := w.writeUvarint(uint64(w.fields[name])); err != nil {
+			return err
+		}
+		if err := w.writeValue(values[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeValue writes a tagged entity field value. Values of types field
+// decoders never produce are an error rather than silently recorded as nil.
+func (w *eventLogWriter) writeValue(v interface{}) error {
+	var err error
+
+	switch x := v.(type) {
+	case bool:
+		b := byte(0)
+		if x {
+			b = 1
+		}
+		_, err = w.Write([]byte{eventLogValueBool, b})
+	case int32:
+		if err = w.WriteByte(eventLogValueInt32); err == nil {
+			err = w.writeVarint(int64(x))
+		}
+	case uint32:
+		if err = w.WriteByte(eventLogValueUint32); err == nil {
+			err = w.writeUvarint(uint64(x))
+		}
+	case uint64:
+		if err = w.WriteByte(eventLogValueUint64); err == nil {
+			err = w.writeUvarint(x)
+		}
+	case float32:
+		if err = w.WriteByte(eventLogValueFloat32); err == nil {
+			binary.LittleEndian.PutUint32(w.scratch, math.Float32bits(x))
+			_, err = w.Write(w.scratch[:4])
+		}
+	case string:
+		if err = w.WriteByte(eventLogValueString); err == nil {
+			err = w.writeBytes([]byte(x))
+		}
+	case []float32:
+		if err = w.WriteByte(eventLogValueVector); err == nil {
+			err = w.writeUvarint(uint64(len(x)))
+			for i := 0; err == nil && i < len(x); i++ {
+				binary.LittleEndian.PutUint32(w.scratch, math.Float32bits(x[i]))
+				_, err = w.Write(w.scratch[:4])
+			}
+		}
+	case nil:
+		err = w.WriteByte(eventLogValueNil)
+	default:
+		err = _errorf("event log: unsupported entity field value %T", v)
+	}
+
+	return err
+}
+
+// NewEventLogParser creates a Parser that replays an event log written by a
+// Recorder. Registered callbacks, entity and game event handlers receive the
+// recorded events as they would from the original replay. Entities only carry
+// the recorded fields.
+func NewEventLogParser(r io.Reader) (*Parser, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(magicEventLog))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, magicEventLog) {
+		return nil, _errorf("unexpected magic: expected %s, got %s", magicEventLog, magic)
+	}
+
+	parser := newParser(br)
+	parser.eventLog = &eventLogReader{
+		Reader:  br,
+		classes: make(map[int32]*class),
+		fields:  make(map[uint32]string),
+	}
+
+	return parser, nil
+}
+
+// eventLogReader decodes event log records and applies them to a Parser.
+type eventLogReader struct {
+	*bufio.Reader
+	classes map[int32]*class
+	fields  map[uint32]string
+}
+
+func (r *eventLogReader) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+func (r *eventLogReader) readBytes() ([]byte, error) {
+	n, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// next reads a single record and applies it to the parser. Returns io.EOF
+// once the log is exhausted.
+func (r *eventLogReader) next(p *Parser) error {
+	kind, err := r.readUvarint()
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case eventLogTick:
+		tick, err := r.readUvarint()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		p.Tick = uint32(tick)
+		return nil
+
+	case eventLogDemo, eventLogPacket:
+		t, err := r.readUvarint()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		buf, err := r.readBytes()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		if kind == eventLogDemo {
+			return p.Callbacks.callByDemoType(int32(t), p.Tick, buf)
+		}
+		return p.Callbacks.callByPacketType(int32(t), p.Tick, buf)
+
+	case eventLogClass:
+		id, err := r.readUvarint()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		name, err := r.readBytes()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		r.classes[int32(id)] = &class{classId: int32(id), name: string(name)}
+		return nil
+
+	case eventLogField:
+		id, err := r.readUvarint()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		name, err := r.readBytes()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		r.fields[uint32(id)] = string(name)
+		return nil
+
+	case eventLogEntity:
+		return r.readEntity(p)
+	}
+
+	return _errorf("unknown event log record kind %d", kind)
+}
+
+// readEntity reads an entity record, updates the parser entity state and
+// notifies entity handlers.
+func (r *eventLogReader) readEntity(p *Parser) error {
+	hdr := make([]uint64, 5)
+	for i := range hdr {
+		x, err := r.readUvarint()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		hdr[i] = x
+	}
+	index, serial, classId, op, count := int32(hdr[0]), int32(hdr[1]), int32(hdr[2]), EntityOp(hdr[3]), int(hdr[4])
+
+	c, ok := r.classes[classId]
+	if !ok {
+		return _errorf("unknown event log class %d", classId)
+	}
+
+	e := p.entities[index]
+	if op.Flag(EntityOpCreated) || e == nil {
+		e = newDetachedEntity(index, serial, c)
+		p.entities[index] = e
+	}
+
+	e.changes = e.changes[:0]
+	for i := 0; i < count; i++ {
+		id, err := r.readUvarint()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		name, ok := r.fields[uint32(id)]
+		if !ok {
+			return _errorf("unknown event log field %d", id)
+		}
+		v, err := r.readValue()
+		if err != nil {
+			return r.unexpected(err)
+		}
+		e.values[name] = v
+		if p.recordChanges {
+			e.changes = append(e.changes, fieldChange{name, v})
+		}
+	}
+
+	if op.Flag(EntityOpDeleted) {
+		p.entities[index] = nil
+	}
+
+	for _, h := range p.entityHandlers {
+		if err := h(e, op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readValue reads a tagged entity field value.
+func (r *eventLogReader) readValue() (interface{}, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case eventLogValueNil:
+		return nil, nil
+	case eventLogValueBool:
+		b, err := r.ReadByte()
+		return b == 1, err
+	case eventLogValueInt32:
+		x, err := binary.ReadVarint(r)
+		return int32(x), err
+	case eventLogValueUint32:
+		x, err := r.readUvarint()
+		return uint32(x), err
+	case eventLogValueUint64:
+		return r.readUvarint()
+	case eventLogValueFloat32:
+		return r.readFloat32()
+	case eventLogValueString:
+		buf, err := r.readBytes()
+		return string(buf), err
+	case eventLogValueVector:
+		n, err := r.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		x := make([]float32, n)
+		for i := range x {
+			if x[i], err = r.readFloat32(); err != nil {
+				return nil, err
+			}
+		}
+		return x, nil
+	}
+
+	return nil, _errorf("unknown event log value tag %d", tag)
+}
+
+func (r *eventLogReader) readFloat32() (float32, error) {
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(buf)), nil
+}
+
+// unexpected converts an io.EOF in the middle of a record into an
+// io.ErrUnexpectedEOF.
+func (r *eventLogReader) unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package manta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLogRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	// Record a small event stream through a recorder attached to an idle parser.
+	buf := &bytes.Buffer{}
+	source := newParser(bytes.NewReader(nil))
+	rec, err := NewRecorder(source, buf, RecorderConfig{
+		Classes: map[string][]string{"CDOTA_Unit_Hero_*": {"m_flMana", "m_vecOrigin"}},
+	})
+	if !assert.Nil(err) {
+		return
+	}
+
+	// CombatLogNames string table, needed to resolve combat log entries.
+	source.Tick = 0
+	assert.Nil(rec.onPacketMessage(int32(dota.SVC_Messages_svc_CreateStringTable), source.Tick, _read_fixture("string_tables/17_335_uncompressed.pbmsg")))
+
+	// A combat log entry and a message type which is not recorded.
+	source.Tick = 100
+	entry := &dota.CMsgDOTACombatLogEntry{
+		Type:          dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ITEM.Enum(),
+		InflictorName: proto.Uint32(23),
+	}
+	assert.Nil(rec.onPacketMessage(int32(dota.EDotaUserMessages_DOTA_UM_CombatLogDataHLTV), source.Tick, _proto_marshal(entry)))
+	assert.Nil(rec.onPacketMessage(int32(dota.SVC_Messages_svc_PacketEntities), source.Tick, []byte{1, 2, 3}))
+
+	// A hero which is created, updated twice (once without changes) and deleted,
+	// and an entity of a class which is not recorded.
+	hero := newDetachedEntity(7, 3, &class{classId: 11, name: "CDOTA_Unit_Hero_Puck"})
+	hero.values["m_flMana"] = float32(300)
+	hero.values["m_vecOrigin"] = []float32{1, 2, 3}
+	hero.values["m_iHealth"] = int32(600)
+	assert.Nil(rec.onEntity(hero, EntityOpCreatedEntered))
+
+	source.Tick = 101
+	hero.values["m_flMana"] = float32(250)
+	assert.Nil(rec.onEntity(hero, EntityOpUpdated))
+	assert.Nil(rec.onEntity(hero, EntityOpUpdated))
+
+	source.Tick = 102
+	assert.Nil(rec.onEntity(hero, EntityOpDeleted))
+
+	other := newDetachedEntity(8, 1, &class{classId: 12, name: "CDOTA_PlayerResource"})
+	assert.Nil(rec.onEntity(other, EntityOpCreated))
+
+	assert.Nil(rec.Flush())
+
+	// Replay the log and observe it through the usual handlers.
+	parser, err := NewEventLogParser(bytes.NewReader(buf.Bytes()))
+	if !assert.Nil(err) {
+		return
+	}
+
+	var combatLog []string
+	parser.Callbacks.OnCMsgDOTACombatLogEntry(func(m *dota.CMsgDOTACombatLogEntry) error {
+		name, _ := parser.LookupStringByIndex("CombatLogNames", int32(m.GetInflictorName()))
+		combatLog = append(combatLog, name)
+		assert.Equal(uint32(100), parser.Tick)
+		return nil
+	})
+
+	parser.Callbacks.OnCSVCMsg_PacketEntities(func(m *dota.CSVCMsg_PacketEntities) error {
+		t.Error("unexpected packet entities message")
+		return nil
+	})
+
+	type update struct {
+		tick uint32
+		op   EntityOp
+		mana interface{}
+	}
+	var updates []update
+	parser.OnEntity(func(e *Entity, op EntityOp) error {
+		assert.Equal("CDOTA_Unit_Hero_Puck", e.GetClassName())
+		assert.Equal(hero.GetHandle(), e.GetHandle())
+		assert.Equal([]float32{1, 2, 3}, e.Get("m_vecOrigin"))
+		assert.Nil(e.Get("m_iHealth"))
+		updates = append(updates, update{parser.Tick, op, e.Get("m_flMana")})
+		return nil
+	})
+
+	assert.Nil(parser.Start())
+
+	assert.Equal([]string{"item_flask"}, combatLog)
+	assert.Equal([]update{
+		{100, EntityOpCreatedEntered, float32(300)},
+		{101, EntityOpUpdated, float32(250)},
+		{102, EntityOpDeleted, float32(250)},
+	}, updates)
+	assert.Nil(parser.FindEntity(7))
+}
+
+func TestEventLogWriteValue(t *testing.T) {
+	assert := assert.New(t)
+
+	w := newEventLogWriter(&bytes.Buffer{})
+	assert.Nil(w.writeValue(nil))
+	assert.Nil(w.writeValue(uint64(1)))
+	assert.NotNil(w.writeValue(int64(1)))
+	assert.NotNil(w.writeValue([]int32{1}))
+}
+
+func TestEventLogTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	w := newEventLogWriter(buf)
+	assert.Nil(w.writeHeader())
+	assert.Nil(w.writeMessage(eventLogPacket, 5, int32(dota.NET_Messages_net_Tick), _proto_marshal(&dota.CNETMsg_Tick{Tick: proto.Uint32(5)})))
+	assert.Nil(w.Flush())
+
+	data := buf.Bytes()
+	parser, err := NewEventLogParser(bytes.NewReader(data[:len(data)-1]))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(io.ErrUnexpectedEOF, parser.Start())
+
+	_, err = NewEventLogParser(bytes.NewReader([]byte("PBDEMS2\000")))
+	assert.NotNil(err)
+}
+
+func TestEventLogDeterministic(t *testing.T) {
+	assert := assert.New(t)
+
+	// Map iteration order differs between runs, so enough fields make an
+	// unsorted log all but certain to differ.
+	write := func() []byte {
+		buf := &bytes.Buffer{}
+		rec, err := NewRecorder(newParser(bytes.NewReader(nil)), buf, RecorderConfig{
+			Classes: map[string][]string{"CDOTA_Unit_Hero_*": nil},
+		})
+		if !assert.Nil(err) {
+			return nil
+		}
+		e := newDetachedEntity(7, 3, &class{classId: 11, name: "CDOTA_Unit_Hero_Puck"})
+		for i := 0; i < 32; i++ {
+			e.values[fmt.Sprintf("m_field%02d", i)] = int32(i)
+		}
+		assert.Nil(rec.onEntity(e, EntityOpCreated))
+		assert.Nil(rec.Flush())
+		return buf.Bytes()
+	}
+
+	first := write()
+	for i := 0; i < 10; i++ {
+		assert.Equal(first, write())
+	}
+}
@@ -8,6 +8,10 @@ import (
 
 var huffTree = newHuffmanTree()
 
+// huffTable decodes field path ops from huffTree, most of them in a single
+// lookup.
+var huffTable = newHuffmanTable(huffTree, 12)
+
 type fieldPath struct {
 	path []int
 	last int
@@ -309,6 +313,25 @@ func (fp *fieldPath) release() {
 func readFieldPaths(r *reader) []*fieldPath {
 	fp := newFieldPath()
 
+	paths := []*fieldPath{}
+
+	for !fp.done {
+		fieldPathTable[huffTable.decode(r)].fn(r, fp)
+		if !fp.done {
+			paths = append(paths, fp.copy())
+		}
+	}
+
+	fp.release()
+
+	return paths
+}
+
+// readFieldPathsTree reads field paths like readFieldPaths, walking huffTree
+// bit by bit. It is kept as the reference for the table decoder.
+func readFieldPathsTree(r *reader) []*fieldPath {
+	fp := newFieldPath()
+
 	node, next := huffTree, huffTree
 
 	paths := []*fieldPath{}
@@ -0,0 +1,245 @@
+package manta
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// _field_path_strings decodes field paths with the given decoder, returning
+// them as strings along with the number of unread bits, or the value of the
+// panic which stopped decoding.
+func _field_path_strings(buf []byte, decode func(*reader) []*fieldPath) (paths []string, rem uint32, failure interface{}) {
+	defer func() {
+		failure = recover()
+	}()
+
+	r := newReader(buf)
+	for _, fp := range decode(r) {
+		paths = append(paths, fp.String())
+		fp.release()
+	}
+	return paths, r.remBits(), nil
+}
+
+func _instance_baselines(t testing.TB) map[string][]byte {
+	files, err := ioutil.ReadDir("fixtures/instancebaseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baselines := make(map[string][]byte, len(files))
+	for _, f := range files {
+		buf := _read_fixture("instancebaseline/" + f.Name())
+
+		// Baselines start with the field paths of their serializer.
+		baselines[strings.TrimSuffix(f.Name(), ".rawbuf")] = buf
+	}
+	return baselines
+}
+
+func TestHuffmanTableMatchesTree(t *testing.T) {
+	assert := assert.New(t)
+
+	// Every index of the table leads to the value found by walking the tree
+	// with the same bits.
+	for i, e := range huffTable.entries {
+		node := huffTree
+		for n := uint32(0); n < e.bits; n++ {
+			if (i>>n)&1 == 1 {
+				node = node.Right()
+			} else {
+				node = node.Left()
+			}
+		}
+		if e.node == nil {
+			assert.True(node.IsLeaf())
+			assert.Equal(node.Value(), e.value)
+		} else {
+			assert.Equal(uint32(12), e.bits)
+			assert.False(node.IsLeaf())
+		}
+	}
+}
+
+func TestReadFieldPathsBaselines(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, buf := range _instance_baselines(t) {
+		want, wantRem, wantFailure := _field_path_strings(buf, readFieldPathsTree)
+		got, gotRem, gotFailure := _field_path_strings(buf, readFieldPaths)
+		assert.Equal(want, got, name)
+		assert.Equal(wantRem, gotRem, name)
+		assert.Equal(wantFailure == nil, gotFailure == nil, name)
+	}
+}
+
+func TestReadFieldPathsRandom(t *testing.T) {
+	assert := assert.New(t)
+
+	// Random data exercises every op, long codes and running out of bits.
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		buf := make([]byte, 1+rnd.Intn(64))
+		rnd.Read(buf)
+
+		want, wantRem, wantFailure := _field_path_strings(buf, readFieldPathsTree)
+		got, gotRem, gotFailure := _field_path_strings(buf, readFieldPaths)
+		assert.Equal(want, got, "buffer %d", i)
+		assert.Equal(wantRem, gotRem, "buffer %d", i)
+		assert.Equal(wantFailure == nil, gotFailure == nil, "buffer %d", i)
+	}
+}
+
+// TestReadFieldPathsReplays compares the decoders on every entity update of
+// the test replays.
+func TestReadFieldPathsReplays(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping replays in short mode")
+	}
+
+	var ids []int64
+	for id := range testScenarios {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	defer func() {
+		fieldPathDecoder = readFieldPaths
+	}()
+
+	for _, id := range ids {
+		s := testScenarios[id]
+		if s.skipInCI && os.Getenv("CI") != "" {
+			continue
+		}
+
+		updates, mismatches := 0, 0
+		fieldPathDecoder = func(r *reader) []*fieldPath {
+			tr := *r
+			want := readFieldPathsTree(&tr)
+			got := readFieldPaths(r)
+
+			updates++
+			same := len(want) == len(got) && tr.remBits() == r.remBits()
+			for i := 0; same && i < len(want); i++ {
+				same = want[i].String() == got[i].String()
+			}
+			if !same {
+				mismatches++
+			}
+
+			for _, fp := range want {
+				fp.release()
+			}
+			return got
+		}
+
+		r := mustGetReplayReader(s.matchId, s.replayUrl)
+		parser, err := NewStreamParser(r)
+		if err != nil {
+			r.Close()
+			t.Fatal(err)
+		}
+		if err := parser.Start(); err != nil {
+			t.Errorf("match %s: %s", s.matchId, err)
+		}
+		r.Close()
+
+		if mismatches > 0 {
+			t.Errorf("match %s: %d of %d updates decoded differently", s.matchId, mismatches, updates)
+		}
+	}
+}
+
+func BenchmarkReadFieldPaths(b *testing.B) {
+	baselines := _instance_baselines(b)
+	bufs := make([][]byte, 0, len(baselines))
+	for _, buf := range baselines {
+		bufs = append(bufs, buf)
+	}
+
+	for _, d := range []struct {
+		name   string
+		decode func(*reader) []*fieldPath
+	}{
+		{"tree", readFieldPathsTree},
+		{"table", readFieldPaths},
+	} {
+		b.Run(d.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				for _, fp := range d.decode(newReader(bufs[n%len(bufs)])) {
+					fp.release()
+				}
+			}
+		})
+	}
+}
+
+// _huffman_tree_decode reads the next value by walking the tree bit by bit,
+// as readFieldPathsTree does.
+func _huffman_tree_decode(r *reader) int {
+	node := huffTree
+	for {
+		if r.readBits(1) == 1 {
+			node = node.Right()
+		} else {
+			node = node.Left()
+		}
+		if node.IsLeaf() {
+			return node.Value()
+		}
+	}
+}
+
+func BenchmarkHuffmanDecode(b *testing.B) {
+	// A stream of ops in their usual proportions.
+	var codes []uint32
+	var lens []uint32
+	for i := 0; i < 1<<huffTable.bits; i++ {
+		if e := huffTable.entries[i]; e.node == nil && i < 1<<e.bits {
+			for w := fieldPathTable[e.value].weight / 100; w >= 0; w-- {
+				codes = append(codes, uint32(i))
+				lens = append(lens, e.bits)
+			}
+		}
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var buf []byte
+	var acc uint64
+	var n uint32
+	for i := 0; i < 1<<16; i++ {
+		k := rnd.Intn(len(codes))
+		acc |= uint64(codes[k]) << n
+		n += lens[k]
+		for n >= 8 {
+			buf = append(buf, byte(acc))
+			acc >>= 8
+			n -= 8
+		}
+	}
+	ops := 1 << 15
+
+	for _, d := range []struct {
+		name   string
+		decode func(*reader) int
+	}{
+		{"tree", _huffman_tree_decode},
+		{"table", huffTable.decode},
+	} {
+		b.Run(d.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				r := newReader(buf)
+				for i := 0; i < ops; i++ {
+					d.decode(r)
+				}
+			}
+		})
+	}
+}
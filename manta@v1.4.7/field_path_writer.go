@@ -0,0 +1,91 @@
+package manta
+
+// huffCodes holds the code of each field path op in huffTree.
+var huffCodes = newHuffmanCodes(huffTree, len(fieldPathTable))
+
+// fieldPathOps maps the names of field path ops to their index in
+// fieldPathTable.
+var fieldPathOps = func() map[string]int {
+	ops := make(map[string]int, len(fieldPathTable))
+	for i, op := range fieldPathTable {
+		ops[op.name] = i
+	}
+	return ops
+}()
+
+// writeFieldPathOp writes the code of the named field path op.
+func writeFieldPathOp(w *writer, name string) {
+	c := huffCodes[fieldPathOps[name]]
+	w.writeBits(c.bits, c.len)
+}
+
+// writeFieldPaths writes the given field paths in the format read by
+// readFieldPaths, each encoded relative to the one before it.
+func writeFieldPaths(w *writer, paths []*fieldPath) {
+	fp := newFieldPath()
+
+	for _, next := range paths {
+		writeFieldPath(w, fp, next)
+
+		copy(fp.path, next.path)
+		fp.last = next.last
+	}
+	writeFieldPathOp(w, "FieldPathEncodeFinish")
+
+	fp.release()
+}
+
+// writeFieldPath writes the ops turning the field path prev into next.
+func writeFieldPath(w *writer, prev, next *fieldPath) {
+	switch {
+	case next.last < prev.last:
+		writeFieldPathOp(w, "PopNAndNonTopographical")
+		w.writeUBitVarFP(uint32(prev.last - next.last))
+		for i := 0; i <= next.last; i++ {
+			d := next.path[i] - prev.path[i]
+			w.writeBoolean(d != 0)
+			if d != 0 {
+				w.writeVarInt32(int32(d))
+			}
+		}
+
+	case next.last > prev.last:
+		writeFieldPathOp(w, "PushNAndNonTopological")
+		for i := 0; i <= prev.last; i++ {
+			d := next.path[i] - prev.path[i]
+			w.writeBoolean(d != 0)
+			if d != 0 {
+				w.writeVarInt32(int32(d - 1))
+			}
+		}
+		w.writeUBitVar(uint32(next.last - prev.last))
+		for i := prev.last + 1; i <= next.last; i++ {
+			w.writeUBitVarFP(uint32(next.path[i]))
+		}
+
+	default:
+		// Most paths only move forward in their last component.
+		topological := true
+		for i := 0; i < next.last; i++ {
+			topological = topological && next.path[i] == prev.path[i]
+		}
+		d := next.path[next.last] - prev.path[next.last]
+
+		switch {
+		case topological && d >= 1 && d <= 4:
+			writeFieldPathOp(w, [...]string{"PlusOne", "PlusTwo", "PlusThree", "PlusFour"}[d-1])
+		case topological && d > 4:
+			writeFieldPathOp(w, "PlusN")
+			w.writeUBitVarFP(uint32(d - 5))
+		default:
+			writeFieldPathOp(w, "NonTopoComplex")
+			for i := 0; i <= next.last; i++ {
+				d := next.path[i] - prev.path[i]
+				w.writeBoolean(d != 0)
+				if d != 0 {
+					w.writeVarInt32(int32(d))
+				}
+			}
+		}
+	}
+}
@@ -4,8 +4,27 @@ import (
 	"strings"
 )
 
-func readFields(r *reader, s *serializer, state *fieldState) {
-	fps := readFieldPaths(r)
+// fieldPathDecoder reads the field paths of an entity update. Tests replace it
+// to compare decoders on real replays.
+var fieldPathDecoder = readFieldPaths
+
+// fieldBits holds the encoded bits of a field value.
+type fieldBits struct {
+	buf []byte
+	n   uint32
+}
+
+// fieldChange is a field value set by an entity update.
+type fieldChange struct {
+	name  string
+	value interface{}
+}
+
+// readFields reads field updates into the given state, or reads past them when
+// the state is nil. When raw is not nil, it also receives the encoded bits of
+// each value, and when changes is not nil, the names and values of the fields.
+func readFields(r *reader, s *serializer, state *fieldState, raw *fieldState, changes *[]fieldChange) {
+	fps := fieldPathDecoder(r)
 
 	for _, fp := range fps {
 		decoder := s.getDecoderForFieldPath(fp, 0)
@@ -17,8 +36,18 @@ func readFields(r *reader, s *serializer, state *fieldState) {
 			_debugf("NEW reading ser=%s path=%s pos=%s name=%s type=%s decoder=%s model=%s", s.name, fp.String(), r.position(), name, typ, _nameof(decoder), field.modelString())
 		}
 
+		start := r.pos*8 - r.bitCount
 		val := decoder(r)
-		state.set(fp, val)
+		if state != nil {
+			state.set(fp, val)
+		}
+		if raw != nil {
+			end := r.pos*8 - r.bitCount
+			raw.set(fp, fieldBits{readBitRange(r.buf, start, end), end - start})
+		}
+		if changes != nil {
+			*changes = append(*changes, fieldChange{strings.Join(s.getNameForFieldPath(fp, 0), "."), val})
+		}
 
 		if v(6) {
 			name := strings.Join(s.getNameForFieldPath(fp, 0), ".")
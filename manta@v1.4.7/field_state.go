@@ -1,12 +1,52 @@
 package manta
 
+import "sync"
+
 type fieldState struct {
 	state []interface{}
 }
 
+// fieldStatePool holds the states of deleted entities for reuse.
+var fieldStatePool = &sync.Pool{
+	New: func() interface{} {
+		return &fieldState{
+			state: make([]interface{}, 8),
+		}
+	},
+}
+
 func newFieldState() *fieldState {
-	return &fieldState{
-		state: make([]interface{}, 8),
+	return fieldStatePool.Get().(*fieldState)
+}
+
+// release clears the state, including nested states, and returns it to the
+// pool. It must not be used afterwards.
+func (s *fieldState) release() {
+	for i, v := range s.state {
+		if x, ok := v.(*fieldState); ok {
+			x.release()
+		}
+		s.state[i] = nil
+	}
+	fieldStatePool.Put(s)
+}
+
+// walk calls fn with the path and value of every value in the state, in
+// order. The path is only valid during the call.
+func (s *fieldState) walk(fp *fieldPath, fn func(*fieldPath, interface{})) {
+	for i, v := range s.state {
+		if v == nil {
+			continue
+		}
+		fp.path[fp.last] = i
+		if x, ok := v.(*fieldState); ok {
+			fp.last++
+			x.walk(fp, fn)
+			fp.path[fp.last] = 0
+			fp.last--
+			continue
+		}
+		fn(fp, v)
 	}
 }
 
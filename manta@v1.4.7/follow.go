@@ -0,0 +1,109 @@
+package manta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/dotabuff/manta/dota"
+)
+
+// ErrFollowTimeout is returned by a FollowReader when no data has been
+// written for longer than its timeout.
+var ErrFollowTimeout = errors.New("manta: timed out waiting for replay data")
+
+// defaultFollowPoll is how often a FollowReader checks for new data.
+const defaultFollowPoll = 100 * time.Millisecond
+
+// FollowReader reads a replay which is still being written, such as the
+// .dem of a game in progress. Rather than ending at the current end of the
+// file, reads wait for more data to be written, so that a Parser reading
+// from it never sees a partially written message.
+type FollowReader struct {
+	// Timeout is how long to wait for new data before failing with
+	// ErrFollowTimeout, zero waits until the context is done.
+	Timeout time.Duration
+
+	// Poll is how often to check for new data.
+	Poll time.Duration
+
+	r        io.Reader
+	ctx      context.Context
+	finished int32
+}
+
+// NewFollowReader returns a FollowReader reading from r until ctx is done.
+func NewFollowReader(ctx context.Context, r io.Reader, timeout time.Duration) *FollowReader {
+	return &FollowReader{
+		Timeout: timeout,
+		Poll:    defaultFollowPoll,
+		r:       r,
+		ctx:     ctx,
+	}
+}
+
+// Finish stops waiting for more data, reads end at the end of the
+// underlying reader from then on. It may be called from any goroutine.
+func (f *FollowReader) Finish() {
+	atomic.StoreInt32(&f.finished, 1)
+}
+
+// Read reads from the underlying reader, waiting for more data at its end
+// until Finish is called. It fails with the error of the context once it is
+// done.
+func (f *FollowReader) Read(p []byte) (int, error) {
+	var timeout <-chan time.Time
+	if f.Timeout > 0 {
+		timer := time.NewTimer(f.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		if err := f.ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		n, err := f.r.Read(p)
+		if n > 0 || len(p) == 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if atomic.LoadInt32(&f.finished) != 0 {
+			return 0, io.EOF
+		}
+
+		poll := time.NewTimer(f.Poll)
+		select {
+		case <-poll.C:
+		case <-timeout:
+			poll.Stop()
+			return 0, ErrFollowTimeout
+		case <-f.ctx.Done():
+			poll.Stop()
+			return 0, f.ctx.Err()
+		}
+	}
+}
+
+// NewFollowParser creates a Parser for a replay which is still being
+// written, read through the given FollowReader. Parsing ends normally at the
+// end of the data following the CDemoStop message written at the end of a
+// game, or fails once the reader times out or its context is done.
+func NewFollowParser(f *FollowReader) (*Parser, error) {
+	p, err := NewStreamParser(f)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Callbacks.OnCDemoStop(func(m *dota.CDemoStop) error {
+		f.Finish()
+		return nil
+	})
+
+	return p, nil
+}
@@ -0,0 +1,189 @@
+package manta
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/stretchr/testify/assert"
+)
+
+// _demo_stream_stopped builds a demo stream of n messages followed by the
+// CDemoStop and CDemoFileInfo messages ending a replay.
+func _demo_stream_stopped(n int) []byte {
+	data := _demo_stream(n)
+	for _, cmd := range []dota.EDemoCommands{dota.EDemoCommands_DEM_Stop, dota.EDemoCommands_DEM_FileInfo} {
+		b := make([]byte, binary.MaxVarintLen64)
+		data = append(data, b[:binary.PutUvarint(b, uint64(cmd))]...)
+		data = append(data, b[:binary.PutUvarint(b, uint64(n*2))]...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+// _write_in_chunks appends data to the given file in small chunks, splitting
+// messages, and closes done once it is written.
+func _write_in_chunks(t *testing.T, path string, data []byte, chunk int, done chan struct{}) {
+	defer close(done)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer f.Close()
+
+	for len(data) > 0 {
+		n := chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := f.Write(data[:n]); err != nil {
+			t.Error(err)
+			return
+		}
+		data = data[n:]
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// _follow parses the file at path with a FollowReader while data is written to
+// it, returning the demo messages seen.
+func _follow(t *testing.T, ctx context.Context, path string, timeout time.Duration) ([]_raw_message, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	f := NewFollowReader(ctx, r, timeout)
+	f.Poll = time.Millisecond
+
+	p, err := NewFollowParser(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var ms []_raw_message
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		ms = append(ms, _raw_message{t, tick, append([]byte(nil), buf...)})
+		return nil
+	})
+	return ms, p.Start()
+}
+
+func _follow_file(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "manta-follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "live.dem")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFollowParser(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _demo_stream_stopped(30)
+	want := _collect_demo_messages(t, data, 0)
+
+	path := _follow_file(t)
+	done := make(chan struct{})
+	go _write_in_chunks(t, path, data, 37, done)
+
+	got, err := _follow(t, context.Background(), path, 5*time.Second)
+	<-done
+	assert.Nil(err)
+	assert.Equal(want, got)
+}
+
+func TestFollowParserTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	// The writer stops in the middle of a message and never ends the replay.
+	data := _demo_stream(10)
+	path := _follow_file(t)
+	done := make(chan struct{})
+	go _write_in_chunks(t, path, data[:len(data)-50], 100, done)
+
+	ms, err := _follow(t, context.Background(), path, 50*time.Millisecond)
+	<-done
+	assert.Equal(ErrFollowTimeout, err)
+	assert.NotEmpty(ms)
+	assert.True(len(ms) < 10)
+}
+
+func TestFollowParserCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	path := _follow_file(t)
+	if err := ioutil.WriteFile(path, _demo_stream(3), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	ms, err := _follow(t, ctx, path, 0)
+	assert.Equal(context.Canceled, err)
+	assert.Len(ms, 3)
+}
+
+func TestFollowParserReplay(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping test in CI environment")
+	}
+
+	assert := assert.New(t)
+
+	data := mustGetReplayData("2159568145", "https://s3-us-west-2.amazonaws.com/manta.dotabuff/2159568145.dem")
+	path := _follow_file(t)
+	done := make(chan struct{})
+	go _write_in_chunks(t, path, data, 256*1024, done)
+
+	r, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	f := NewFollowReader(context.Background(), r, 10*time.Second)
+	f.Poll = time.Millisecond
+	p, err := NewFollowParser(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities := 0
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		entities++
+		return nil
+	})
+	assert.Nil(p.Start())
+	<-done
+
+	// Following the replay as it is written sees everything parsing the
+	// whole file does.
+	whole, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wholeEntities := 0
+	whole.OnEntity(func(e *Entity, op EntityOp) error {
+		wholeEntities++
+		return nil
+	})
+	assert.Nil(whole.Start())
+	assert.Equal(wholeEntities, entities)
+	assert.Equal(whole.Tick, p.Tick)
+}
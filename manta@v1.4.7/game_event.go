@@ -215,6 +215,10 @@ func (p *Parser) onCMsgSource1LegacyGameEventList(m *dota.CMsgSource1LegacyGameE
 // Internal handler for callback OnCMsgSource1LegacyGameEvent.
 // Looks up the name and type of an event and offers it to registered handlers.
 func (p *Parser) onCMsgSource1LegacyGameEvent(m *dota.CMsgSource1LegacyGameEvent) error {
+	if p.subsystems&SubsystemGameEvents == 0 {
+		return nil
+	}
+
 	// Look up the handler name by event id.
 	name, ok := p.gameEventNames[m.GetEventid()]
 	if !ok {
@@ -0,0 +1,473 @@
+package manta
+
+import (
+	"sort"
+	"strings"
+)
+
+// History records field level changes for selected entity classes while a
+// replay is parsed and answers point-in-time queries once parsing finishes.
+// Values are stored in typed columns per entity and field, only appending
+// when a value actually changes.
+type History struct {
+	selector *entitySelector
+	entities map[uint64]*historyEntity
+	columns  map[historyKey]*historyColumn
+	keys     []historyKey
+}
+
+// HistoryChange is a single recorded field change.
+type HistoryChange struct {
+	Tick   uint32
+	Handle uint64
+	Class  string
+	Field  string
+	Value  interface{}
+}
+
+// NewHistory creates a History that records changes for the given classes
+// and registers it with the parser. Keys are class names, optionally ending
+// in '*' to match a class name prefix (ex. "CDOTA_Unit_Hero_*"). Values list
+// the fields to record; an empty list records every field of the class.
+func NewHistory(p *Parser, classes map[string][]string) *History {
+	h := newHistory(classes)
+
+	p.recordChanges = true
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		h.onEntity(p.Tick, e, op)
+		return nil
+	})
+
+	return h
+}
+
+// newHistory creates an empty History for the given class patterns.
+func newHistory(classes map[string][]string) *History {
+	return &History{
+		selector: newEntitySelector(classes),
+		entities: make(map[uint64]*historyEntity),
+		columns:  make(map[historyKey]*historyColumn),
+		keys:     make([]historyKey, 0),
+	}
+}
+
+// Handles returns the handles of recorded entities matching the given class
+// pattern, in the order they were first seen.
+func (h *History) Handles(class string) []uint64 {
+	sel := newEntitySelector(map[string][]string{class: nil})
+	handles := make([]uint64, 0)
+	for _, k := range h.keys {
+		if k.field != "" {
+			continue
+		}
+		if _, ok := sel.fields(h.entities[k.handle].class); ok {
+			handles = append(handles, k.handle)
+		}
+	}
+	return handles
+}
+
+// ClassName returns the class name of a recorded entity.
+func (h *History) ClassName(handle uint64) (string, bool) {
+	he, ok := h.entities[handle]
+	if !ok {
+		return "", false
+	}
+	return he.class, true
+}
+
+// Lifetime returns the ticks at which a recorded entity was created and
+// deleted. A deleted tick of zero means the entity was never deleted.
+func (h *History) Lifetime(handle uint64) (created, deleted uint32, ok bool) {
+	he, ok := h.entities[handle]
+	if !ok {
+		return 0, 0, false
+	}
+	return he.created, he.deleted, true
+}
+
+// ValueAt returns the value of a field for the given entity as it was at the
+// given tick.
+func (h *History) ValueAt(handle uint64, field string, tick uint32) (interface{}, bool) {
+	he, ok := h.entities[handle]
+	if !ok || tick < he.created || (he.deleted > 0 && tick >= he.deleted) {
+		return nil, false
+	}
+
+	c, ok := h.columns[historyKey{handle, field}]
+	if !ok {
+		return nil, false
+	}
+
+	i := sort.Search(len(c.ticks), func(i int) bool { return c.ticks[i] > tick }) - 1
+	if i < 0 {
+		return nil, false
+	}
+
+	return c.value(i), true
+}
+
+// Range returns the changes of a field for the given entity that happened
+// between from and to, inclusive. Use ValueAt to find the value in effect at
+// the start of the range.
+func (h *History) Range(handle uint64, field string, from, to uint32) []HistoryChange {
+	changes := make([]HistoryChange, 0)
+
+	c, ok := h.columns[historyKey{handle, field}]
+	if !ok {
+		return changes
+	}
+
+	class := h.entities[handle].class
+	i := sort.Search(len(c.ticks), func(i int) bool { return c.ticks[i] >= from })
+	for ; i < len(c.ticks) && c.ticks[i] <= to; i++ {
+		changes = append(changes, HistoryChange{c.ticks[i], handle, class, field, c.value(i)})
+	}
+
+	return changes
+}
+
+// Changes returns every recorded change, ordered by tick.
+func (h *History) Changes() []HistoryChange {
+	changes := make([]HistoryChange, 0)
+	for _, k := range h.keys {
+		if k.field == "" {
+			continue
+		}
+		c := h.columns[k]
+		class := h.entities[k.handle].class
+		for i, t := range c.ticks {
+			changes = append(changes, HistoryChange{t, k.handle, class, k.field, c.value(i)})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Tick < changes[j].Tick
+	})
+
+	return changes
+}
+
+// onEntity records the changed fields of a selected entity. Created entities
+// have all their fields read, updates only those set by the update.
+func (h *History) onEntity(tick uint32, e *Entity, op EntityOp) {
+	fields, ok := h.selector.fields(e.GetClassName())
+	if !ok {
+		return
+	}
+
+	handle := e.GetHandle()
+	he := h.track(tick, handle, e.GetClassName())
+
+	if op.Flag(EntityOpDeleted) {
+		he.deleted = tick
+		return
+	}
+
+	if !op.Flag(EntityOpCreated) {
+		if op.Flag(EntityOpUpdated) {
+			h.recordChanges(tick, handle, fields, e.changes)
+		}
+		return
+	}
+
+	if len(fields) == 0 {
+		for name, v := range e.Map() {
+			h.record(tick, handle, name, v)
+		}
+		return
+	}
+
+	for _, name := range fields {
+		if v := e.Get(name); v != nil {
+			h.record(tick, handle, name, v)
+		}
+	}
+}
+
+// recordChanges records the field changes of an update which are among the
+// selected fields, or all of them if none are selected.
+func (h *History) recordChanges(tick uint32, handle uint64, fields []string, changes []fieldChange) {
+	for _, c := range changes {
+		if c.value == nil || (len(fields) > 0 && !containsString(fields, c.name)) {
+			continue
+		}
+		h.record(tick, handle, c.name, c.value)
+	}
+}
+
+// containsString determines whether a slice holds the given string.
+func containsString(s []string, x string) bool {
+	for _, y := range s {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
+
+// track returns the recorded entity for a handle, adding it if necessary.
+func (h *History) track(tick uint32, handle uint64, class string) *historyEntity {
+	he, ok := h.entities[handle]
+	if !ok {
+		he = &historyEntity{class: class, created: tick}
+		h.entities[handle] = he
+		h.keys = append(h.keys, historyKey{handle: handle})
+	}
+	return he
+}
+
+// record appends a value for the given entity and field if it differs from
+// the last recorded value.
+func (h *History) record(tick uint32, handle uint64, field string, v interface{}) {
+	k := historyKey{handle, field}
+	c, ok := h.columns[k]
+	if !ok {
+		c = newHistoryColumn(v)
+		h.columns[k] = c
+		h.keys = append(h.keys, k)
+	}
+
+	if n := len(c.ticks); n > 0 && c.equal(n-1, v) {
+		return
+	}
+
+	c.append(tick, v)
+}
+
+// historyKey identifies a column by entity handle and field name. A key
+// without a field identifies the entity itself.
+type historyKey struct {
+	handle uint64
+	field  string
+}
+
+// historyEntity describes the lifetime of a recorded entity.
+type historyEntity struct {
+	class   string
+	created uint32
+	deleted uint32
+}
+
+const (
+	historyKindAny = iota
+	historyKindBool
+	historyKindInt32
+	historyKindUint32
+	historyKindUint64
+	historyKindFloat32
+	historyKindString
+	historyKindVector
+)
+
+// historyColumn stores the changes of one field as parallel slices of ticks
+// and typed values. Columns fall back to untyped storage if a field changes
+// type during the replay.
+type historyColumn struct {
+	kind    int
+	width   int
+	ticks   []uint32
+	bools   []bool
+	ints    []int32
+	uints   []uint64
+	floats  []float32
+	strings []string
+	values  []interface{}
+}
+
+// newHistoryColumn returns an empty column typed for the given value.
+func newHistoryColumn(v interface{}) *historyColumn {
+	c := &historyColumn{}
+
+	switch x := v.(type) {
+	case bool:
+		c.kind = historyKindBool
+	case int32:
+		c.kind = historyKindInt32
+	case uint32:
+		c.kind = historyKindUint32
+	case uint64:
+		c.kind = historyKindUint64
+	case float32:
+		c.kind = historyKindFloat32
+	case string:
+		c.kind = historyKindString
+	case []float32:
+		c.kind = historyKindVector
+		c.width = len(x)
+	default:
+		c.kind = historyKindAny
+	}
+
+	return c
+}
+
+// value returns the i-th value of the column.
+func (c *historyColumn) value(i int) interface{} {
+	switch c.kind {
+	case historyKindBool:
+		return c.bools[i]
+	case historyKindInt32:
+		return c.ints[i]
+	case historyKindUint32:
+		return uint32(c.uints[i])
+	case historyKindUint64:
+		return c.uints[i]
+	case historyKindFloat32:
+		return c.floats[i]
+	case historyKindString:
+		return c.strings[i]
+	case historyKindVector:
+		return c.floats[i*c.width : (i+1)*c.width : (i+1)*c.width]
+	}
+	return c.values[i]
+}
+
+// equal determines whether the i-th value of the column equals v.
+func (c *historyColumn) equal(i int, v interface{}) bool {
+	return fieldValueEqual(c.value(i), v)
+}
+
+// append adds a value to the column, converting the column to untyped
+// storage if the value does not match its type.
+func (c *historyColumn) append(tick uint32, v interface{}) {
+	if !c.accepts(v) {
+		c.untype()
+	}
+
+	c.ticks = append(c.ticks, tick)
+
+	switch c.kind {
+	case historyKindBool:
+		c.bools = append(c.bools, v.(bool))
+	case historyKindInt32:
+		c.ints = append(c.ints, v.(int32))
+	case historyKindUint32:
+		c.uints = append(c.uints, uint64(v.(uint32)))
+	case historyKindUint64:
+		c.uints = append(c.uints, v.(uint64))
+	case historyKindFloat32:
+		c.floats = append(c.floats, v.(float32))
+	case historyKindString:
+		c.strings = append(c.strings, v.(string))
+	case historyKindVector:
+		c.floats = append(c.floats, v.([]float32)...)
+	default:
+		c.values = append(c.values, v)
+	}
+}
+
+// accepts determines whether v can be stored in the typed column.
+func (c *historyColumn) accepts(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return c.kind == historyKindBool
+	case int32:
+		return c.kind == historyKindInt32
+	case uint32:
+		return c.kind == historyKindUint32
+	case uint64:
+		return c.kind == historyKindUint64
+	case float32:
+		return c.kind == historyKindFloat32
+	case string:
+		return c.kind == historyKindString
+	case []float32:
+		return c.kind == historyKindVector && len(x) == c.width
+	}
+	return c.kind == historyKindAny
+}
+
+// untype converts the column to untyped storage.
+func (c *historyColumn) untype() {
+	if c.kind == historyKindAny {
+		return
+	}
+
+	values := make([]interface{}, len(c.ticks))
+	for i := range c.ticks {
+		values[i] = c.value(i)
+	}
+
+	*c = historyColumn{
+		kind:   historyKindAny,
+		ticks:  c.ticks,
+		values: values,
+	}
+}
+
+// entitySelector matches entity class names against a set of exact names and
+// prefix patterns, returning the fields selected for a class.
+type entitySelector struct {
+	patterns map[string][]string
+	cache    map[string][]string
+	misses   map[string]bool
+}
+
+// newEntitySelector creates an entitySelector for the given class patterns.
+func newEntitySelector(classes map[string][]string) *entitySelector {
+	return &entitySelector{
+		patterns: classes,
+		cache:    make(map[string][]string),
+		misses:   make(map[string]bool),
+	}
+}
+
+// fields returns the fields selected for the given class name, and whether
+// the class is selected at all. A nil selector selects every class.
+func (s *entitySelector) fields(class string) ([]string, bool) {
+	if s == nil || s.patterns == nil {
+		return nil, true
+	}
+	if fields, ok := s.cache[class]; ok {
+		return fields, true
+	}
+	if s.misses[class] {
+		return nil, false
+	}
+
+	matched := false
+	var fields []string
+	for pattern, fs := range s.patterns {
+		if pattern == class || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(class, pattern[:len(pattern)-1])) {
+			if matched && (len(fields) == 0 || len(fs) == 0) {
+				fields = nil
+			} else {
+				fields = append(fields, fs...)
+			}
+			matched = true
+		}
+	}
+
+	if !matched {
+		s.misses[class] = true
+		return nil, false
+	}
+
+	s.cache[class] = fields
+	return fields, true
+}
+
+// fieldValueEqual determines whether two decoded field values are equal.
+// Vectors are compared element by element.
+func fieldValueEqual(a, b interface{}) bool {
+	if x, ok := a.([]float32); ok {
+		y, ok := b.([]float32)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i] != y[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch a.(type) {
+	case bool, int32, uint32, uint64, float32, string:
+		return a == b
+	}
+
+	return false
+}
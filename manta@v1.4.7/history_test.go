@@ -0,0 +1,172 @@
+package manta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryQueries(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newHistory(map[string][]string{"CDOTA_Unit_Hero_*": {"m_flMana"}})
+	hero := uint64(5)<<indexBits | 100
+
+	h.track(10, hero, "CDOTA_Unit_Hero_Puck")
+	h.record(10, hero, "m_flMana", float32(300))
+	h.record(11, hero, "m_flMana", float32(300))
+	h.record(20, hero, "m_flMana", float32(250))
+	h.record(30, hero, "m_flMana", float32(400))
+
+	_, ok := h.ValueAt(hero, "m_flMana", 9)
+	assert.False(ok)
+
+	v, ok := h.ValueAt(hero, "m_flMana", 15)
+	assert.True(ok)
+	assert.Equal(float32(300), v)
+
+	v, ok = h.ValueAt(hero, "m_flMana", 20)
+	assert.True(ok)
+	assert.Equal(float32(250), v)
+
+	v, ok = h.ValueAt(hero, "m_flMana", 1000)
+	assert.True(ok)
+	assert.Equal(float32(400), v)
+
+	_, ok = h.ValueAt(hero, "m_iHealth", 20)
+	assert.False(ok)
+
+	changes := h.Range(hero, "m_flMana", 11, 30)
+	if assert.Len(changes, 2) {
+		assert.Equal(uint32(20), changes[0].Tick)
+		assert.Equal(float32(400), changes[1].Value)
+		assert.Equal("CDOTA_Unit_Hero_Puck", changes[1].Class)
+	}
+
+	h.entities[hero].deleted = 40
+	_, ok = h.ValueAt(hero, "m_flMana", 40)
+	assert.False(ok)
+
+	assert.Equal([]uint64{hero}, h.Handles("CDOTA_Unit_Hero_*"))
+	assert.Empty(h.Handles("CDOTA_Item_PowerTreads"))
+}
+
+func TestHistoryChangesOrdered(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newHistory(nil)
+	a := uint64(1)<<indexBits | 1
+	b := uint64(1)<<indexBits | 2
+
+	h.track(1, a, "CDOTA_Item_PowerTreads")
+	h.track(1, b, "CDOTA_Unit_Hero_Puck")
+	h.record(1, a, "m_iStat", int32(0))
+	h.record(5, a, "m_iStat", int32(1))
+	h.record(3, b, "CBodyComponent.m_vecX", float32(12.5))
+	h.record(3, b, "m_vecOrigin", []float32{1, 2, 3})
+	h.record(4, b, "m_vecOrigin", []float32{1, 2, 3})
+	h.record(6, b, "m_vecOrigin", []float32{1, 2, 4})
+
+	changes := h.Changes()
+	ticks := make([]uint32, len(changes))
+	for i, c := range changes {
+		ticks[i] = c.Tick
+	}
+	assert.Equal([]uint32{1, 3, 3, 5, 6}, ticks)
+
+	v, _ := h.ValueAt(b, "m_vecOrigin", 5)
+	assert.Equal([]float32{1, 2, 3}, v)
+	v, _ = h.ValueAt(b, "m_vecOrigin", 6)
+	assert.Equal([]float32{1, 2, 4}, v)
+}
+
+func TestHistoryUpdates(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", map[string]interface{}{"m_iHealth": 620, "m_flMana": float32(300)})
+	b.Advance(1)
+	b.Update(10, map[string]interface{}{"m_flMana": float32(250)})
+	b.Advance(1)
+	b.Update(10, map[string]interface{}{"m_iHealth": 500})
+	b.Advance(1)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	h := NewHistory(p, map[string][]string{"CDOTA_Unit_Hero_*": nil})
+
+	// Updates carry only the fields they set.
+	var updated [][]string
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		if op == EntityOpUpdated {
+			var names []string
+			for _, c := range e.changes {
+				names = append(names, c.name)
+			}
+			updated = append(updated, names)
+		}
+		return nil
+	})
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	assert.Equal([][]string{{"m_flMana"}, {"m_iHealth"}}, updated)
+
+	hero := p.FindEntity(10).GetHandle()
+	mana := h.Range(hero, "m_flMana", 0, 10)
+	if assert.Len(mana, 2) {
+		assert.Equal(float32(300), mana[0].Value)
+		assert.Equal(float32(250), mana[1].Value)
+	}
+	health := h.Range(hero, "m_iHealth", 0, 10)
+	if assert.Len(health, 2) {
+		assert.Equal(int32(620), health[0].Value)
+		assert.Equal(int32(500), health[1].Value)
+		assert.Equal(mana[1].Tick+1, health[1].Tick)
+	}
+}
+
+func TestHistoryColumnUntype(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newHistoryColumn(uint32(1))
+	c.append(1, uint32(1))
+	c.append(2, uint64(2))
+
+	assert.Equal(historyKindAny, c.kind)
+	assert.Equal(uint32(1), c.value(0))
+	assert.Equal(uint64(2), c.value(1))
+}
+
+func TestEntitySelector(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newEntitySelector(map[string][]string{
+		"CDOTA_Unit_Hero_*":      {"m_flMana"},
+		"CDOTA_Unit_Hero_Puck":   {"m_iHealth"},
+		"CDOTA_Item_PowerTreads": nil,
+	})
+
+	fields, ok := s.fields("CDOTA_Unit_Hero_Lina")
+	assert.True(ok)
+	assert.Equal([]string{"m_flMana"}, fields)
+
+	fields, ok = s.fields("CDOTA_Unit_Hero_Puck")
+	assert.True(ok)
+	assert.ElementsMatch([]string{"m_flMana", "m_iHealth"}, fields)
+
+	fields, ok = s.fields("CDOTA_Item_PowerTreads")
+	assert.True(ok)
+	assert.Empty(fields)
+
+	_, ok = s.fields("CDOTA_PlayerResource")
+	assert.False(ok)
+}
@@ -134,6 +134,101 @@ func buildHuffmanTree(symFreqs []int) huffmanTree {
 	return heap.Pop(&trees).(huffmanTree)
 }
 
+// huffmanEntry is the result of looking up a number of bits in a
+// huffmanTable: either a value and the length of its code, or the node
+// reached after all the bits when the code is longer.
+type huffmanEntry struct {
+	value int
+	bits  uint32
+	node  huffmanTree
+}
+
+// huffmanTable decodes values of a huffmanTree by looking up several bits at
+// once rather than walking the tree one bit at a time.
+type huffmanTable struct {
+	bits    uint32
+	entries []huffmanEntry
+}
+
+// newHuffmanTable builds a table looking up the given number of bits of codes
+// of the given tree.
+func newHuffmanTable(tree huffmanTree, bits uint32) *huffmanTable {
+	t := &huffmanTable{
+		bits:    bits,
+		entries: make([]huffmanEntry, 1<<bits),
+	}
+
+	// Codes are read least significant bit first, the low bits of an index
+	// are the first bits of the code.
+	for i := range t.entries {
+		node := tree
+		n := uint32(0)
+		for n < bits && !node.IsLeaf() {
+			if (i>>n)&1 == 1 {
+				node = node.Right()
+			} else {
+				node = node.Left()
+			}
+			n++
+		}
+
+		if node.IsLeaf() {
+			t.entries[i] = huffmanEntry{value: node.Value(), bits: n}
+		} else {
+			t.entries[i] = huffmanEntry{value: -1, bits: n, node: node}
+		}
+	}
+
+	return t
+}
+
+// decode reads the next value from the reader.
+func (t *huffmanTable) decode(r *reader) int {
+	e := &t.entries[r.peekBits(t.bits)]
+	r.readBits(e.bits)
+	if e.node == nil {
+		return e.value
+	}
+
+	// Finish long codes bit by bit.
+	node := e.node
+	for {
+		if r.readBits(1) == 1 {
+			node = node.Right()
+		} else {
+			node = node.Left()
+		}
+		if node.IsLeaf() {
+			return node.Value()
+		}
+	}
+}
+
+// huffmanCode is the code of a value in a huffmanTree, with its first bit as
+// the least significant.
+type huffmanCode struct {
+	bits uint32
+	len  uint32
+}
+
+// newHuffmanCodes returns the codes of the values 0 to n-1 of the tree.
+func newHuffmanCodes(tree huffmanTree, n int) []huffmanCode {
+	codes := make([]huffmanCode, n)
+
+	var walk func(node huffmanTree, c huffmanCode)
+	walk = func(node huffmanTree, c huffmanCode) {
+		if node.IsLeaf() {
+			codes[node.Value()] = c
+			return
+		}
+		walk(node.Left(), huffmanCode{c.bits, c.len + 1})
+		walk(node.Right(), huffmanCode{c.bits | 1<<c.len, c.len + 1})
+	}
+	walk(tree, huffmanCode{})
+
+	return codes
+}
+
 // Swap two nodes based on the given path
 func swapNodes(tree huffmanTree, path uint32, len uint32) {
 	for len > 0 {
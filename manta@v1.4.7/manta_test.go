@@ -8,7 +8,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func BenchmarkMatch2159568145(b *testing.B) { testScenarios[2159568145].bench(b) }
+func BenchmarkMatch2159568145(b *testing.B) { testScenarios[2159568145].bench(b, 0) }
+func BenchmarkMatch2159568145Pipelined(b *testing.B) {
+	testScenarios[2159568145].bench(b, 64)
+}
 
 // Test client
 func TestMatchNew8552595443(t *testing.T) { testScenarios[8552595443].test(t) }
@@ -606,7 +609,7 @@ var testScenarios = map[int64]testScenario{
 	},
 }
 
-func (s testScenario) bench(b *testing.B) {
+func (s testScenario) bench(b *testing.B, pipelineDepth int) {
 	for n := 0; n < b.N; n++ {
 		r := mustGetReplayReader(s.matchId, s.replayUrl)
 
@@ -614,6 +617,7 @@ func (s testScenario) bench(b *testing.B) {
 		if err != nil {
 			b.Fatalf("unable to instantiate parser: %s", err)
 		}
+		parser.EnablePipeline(pipelineDepth)
 
 		parser.Callbacks.OnCDOTAUserMsg_SpectatorPlayerUnitOrders(func(m *dota.CDOTAUserMsg_SpectatorPlayerUnitOrders) error { return nil })
 		parser.Callbacks.OnCDemoFileInfo(func(m *dota.CDemoFileInfo) error { return nil })
@@ -2,6 +2,7 @@ package manta
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
@@ -31,23 +32,37 @@ type Parser struct {
 	// AfterStopCallback is a function to be called when the parser stops.
 	AfterStopCallback func()
 
+	allowTruncated             bool
+	broadcast                  *broadcastReader
 	classBaselines             map[int32][]byte
 	classesById                map[int32]*class
 	classesByName              map[string]*class
 	classIdSize                uint32
 	classInfo                  bool
 	entities                   map[int32]*Entity
+	deletedEntities            map[int32]*Entity
 	entityFullPackets          int
+	entityClassFilter          func(string) bool
 	entityHandlers             []EntityHandler
 	gameEventHandlers          map[string][]GameEventHandler
 	gameEventNames             map[int32]string
 	gameEventTypes             map[string]*gameEventType
+	immutableBuffers           bool
 	isStopping                 bool
+	lastOuterMessage           *outerMessage
+	eventLog                   *eventLogReader
 	modifierTableEntryHandlers []ModifierTableEntryHandler
+	pipelineDepth              int
+	recordChanges              bool
+	recordFieldBits            bool
 	serializers                map[string]*serializer
+	serializerCache            *SerializerCache
 	stream                     *stream
 	stringTables               *stringTables
 	stopAtTick                 uint32
+	subsystems                 Subsystem
+	stopped                    bool
+	truncated                  bool
 }
 
 // Create a new parser from a byte slice.
@@ -59,6 +74,18 @@ func NewParser(buf []byte) (*Parser, error) {
 // Create a new Parser from an io.Reader
 func NewStreamParser(r io.Reader) (*Parser, error) {
 	// Create a new parser with an internal reader for the given buffer.
+	parser := newParser(r)
+
+	if err := parser.readHeader(); err != nil {
+		return nil, err
+	}
+
+	return parser, nil
+}
+
+// newParser creates a Parser reading from the given io.Reader and registers
+// the internal handlers that maintain parser state.
+func newParser(r io.Reader) *Parser {
 	parser := &Parser{
 		Callbacks: newCallbacks(),
 		Tick:      0,
@@ -69,6 +96,7 @@ func NewStreamParser(r io.Reader) (*Parser, error) {
 		classesById:       make(map[int32]*class),
 		classesByName:     make(map[string]*class),
 		entities:          make(map[int32]*Entity),
+		deletedEntities:   make(map[int32]*Entity),
 		entityHandlers:    make([]EntityHandler, 0),
 		gameEventHandlers: make(map[string][]GameEventHandler),
 		gameEventNames:    make(map[int32]string),
@@ -77,57 +105,138 @@ func NewStreamParser(r io.Reader) (*Parser, error) {
 		serializers:       make(map[string]*serializer),
 		stream:            newStream(r),
 		stringTables:      newStringTables(),
+		subsystems:        SubsystemAll,
 	}
 
+	parser.registerInternalHandlers()
+
+	return parser
+}
+
+// registerInternalHandlers registers the callbacks that maintain parser state.
+func (p *Parser) registerInternalHandlers() {
+	p.Callbacks.OnCDemoPacket(p.onCDemoPacket)
+	p.Callbacks.OnCDemoSignonPacket(p.onCDemoPacket)
+	p.Callbacks.OnCDemoFullPacket(p.onCDemoFullPacket)
+	p.Callbacks.OnCSVCMsg_CreateStringTable(p.onCSVCMsg_CreateStringTable)
+	p.Callbacks.OnCSVCMsg_UpdateStringTable(p.onCSVCMsg_UpdateStringTable)
+	p.Callbacks.OnCSVCMsg_ServerInfo(p.onCSVCMsg_ServerInfo)
+	p.Callbacks.OnCMsgSource1LegacyGameEventList(p.onCMsgSource1LegacyGameEventList)
+	p.Callbacks.OnCMsgSource1LegacyGameEvent(p.onCMsgSource1LegacyGameEvent)
+
+	p.Callbacks.OnCDemoClassInfo(p.onCDemoClassInfo)
+	p.Callbacks.OnCDemoSendTables(p.onCDemoSendTables)
+	p.Callbacks.OnCSVCMsg_PacketEntities(p.onCSVCMsg_PacketEntities)
+
+	// Maintains the value of parser.Tick
+	p.Callbacks.OnCNETMsg_Tick(func(m *dota.CNETMsg_Tick) error {
+		p.NetTick = m.GetTick()
+		return nil
+	})
+}
+
+// readHeader reads and validates the header of a replay.
+func (p *Parser) readHeader() error {
 	// Parse out the header, ensuring that it's valid.
-	magic, err := parser.stream.readBytes(8)
+	magic, err := p.stream.readBytes(8)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if !bytes.Equal(magic, magicSource2) {
-		return nil, _errorf("unexpected magic: expected %s, got %s", magicSource2, magic)
+		return _errorf("unexpected magic: expected %s, got %s", magicSource2, magic)
 	}
 
 	// Skip the next 8 bytes, which appear to be two int32s related to the size
 	// of the demo file. We may need them in the future, but not so far.
-	parser.stream.readBytes(8)
-
-	// Internal handlers
-	parser.Callbacks.OnCDemoPacket(parser.onCDemoPacket)
-	parser.Callbacks.OnCDemoSignonPacket(parser.onCDemoPacket)
-	parser.Callbacks.OnCDemoFullPacket(parser.onCDemoFullPacket)
-	parser.Callbacks.OnCSVCMsg_CreateStringTable(parser.onCSVCMsg_CreateStringTable)
-	parser.Callbacks.OnCSVCMsg_UpdateStringTable(parser.onCSVCMsg_UpdateStringTable)
-	parser.Callbacks.OnCSVCMsg_ServerInfo(parser.onCSVCMsg_ServerInfo)
-	parser.Callbacks.OnCMsgSource1LegacyGameEventList(parser.onCMsgSource1LegacyGameEventList)
-	parser.Callbacks.OnCMsgSource1LegacyGameEvent(parser.onCMsgSource1LegacyGameEvent)
-
-	parser.Callbacks.OnCDemoClassInfo(parser.onCDemoClassInfo)
-	parser.Callbacks.OnCDemoSendTables(parser.onCDemoSendTables)
-	parser.Callbacks.OnCSVCMsg_PacketEntities(parser.onCSVCMsg_PacketEntities)
+	p.stream.readBytes(8)
 
-	// Maintains the value of parser.Tick
-	parser.Callbacks.OnCNETMsg_Tick(func(m *dota.CNETMsg_Tick) error {
-		parser.NetTick = m.GetTick()
-		return nil
-	})
+	return nil
+}
 
-	return parser, nil
+// Reset prepares the Parser to parse another replay from the given reader,
+// as if it had been created by NewStreamParser, but reusing the memory held
+// for the previous replay. All callbacks and handlers are removed, while
+// options such as EnablePipeline, UseSerializerCache, UseImmutableBuffers and
+// SetSubsystems are kept. Entities of the previous replay must not be used anymore.
+func (p *Parser) Reset(r io.Reader) error {
+	for _, e := range p.entities {
+		if e != nil {
+			e.release()
+		}
+	}
+	for k, e := range p.deletedEntities {
+		e.release()
+		delete(p.deletedEntities, k)
+	}
+
+	p.Callbacks = newCallbacks()
+	p.Tick = 0
+	p.NetTick = 0
+	p.GameBuild = 0
+	p.AfterStopCallback = nil
+
+	for k := range p.classBaselines {
+		delete(p.classBaselines, k)
+	}
+	for k := range p.classesById {
+		delete(p.classesById, k)
+	}
+	for k := range p.classesByName {
+		delete(p.classesByName, k)
+	}
+	for k := range p.entities {
+		delete(p.entities, k)
+	}
+	for k := range p.gameEventHandlers {
+		delete(p.gameEventHandlers, k)
+	}
+	for k := range p.gameEventNames {
+		delete(p.gameEventNames, k)
+	}
+	for k := range p.gameEventTypes {
+		delete(p.gameEventTypes, k)
+	}
+	for k := range p.serializers {
+		delete(p.serializers, k)
+	}
+	p.stringTables.reset()
+
+	p.classIdSize = 0
+	p.classInfo = false
+	p.entityFullPackets = 0
+	p.entityHandlers = p.entityHandlers[:0]
+	p.modifierTableEntryHandlers = p.modifierTableEntryHandlers[:0]
+	p.isStopping = false
+	p.lastOuterMessage = nil
+	p.recordChanges = false
+	p.recordFieldBits = false
+	p.eventLog = nil
+	p.broadcast = nil
+	p.stopAtTick = 0
+	p.stopped = false
+	p.truncated = false
+	p.stream.Reader = r
+
+	p.registerInternalHandlers()
+
+	return p.readHeader()
 }
 
 // Start parsing the replay. Will stop processing new events after Stop() is called.
 func (p *Parser) Start() (err error) {
-	var msg *outerMessage
-
 	defer p.afterStop()
 
+	// Outer messages are either read here or by a pipeline running ahead.
+	readOuterMessage := p.readOuterMessage
+	if p.pipelineDepth > 0 && p.eventLog == nil && p.broadcast == nil {
+		pl := newOuterPipeline(p, p.pipelineDepth)
+		defer pl.stop()
+		readOuterMessage = pl.next
+	}
+
 	defer func() {
-		if p := recover(); p != nil {
-			if e, ok := p.(error); ok {
-				err = e
-			} else {
-				err = fmt.Errorf("%v", p)
-			}
+		if v := recover(); v != nil {
+			err = panicError(v)
 		}
 	}()
 
@@ -139,22 +248,100 @@ func (p *Parser) Start() (err error) {
 			return
 		}
 
-		msg, err = p.readOuterMessage()
-		if err != nil {
-			if err == io.EOF {
+		if err = p.next(readOuterMessage); err != nil {
+			if err == io.EOF || (err == io.ErrUnexpectedEOF && p.allowTruncated) {
 				err = nil
 			}
 			return
 		}
+	}
 
-		p.Tick = msg.tick
+	return
+}
 
-		if err = p.Callbacks.callByDemoType(msg.typeId, msg.data); err != nil {
-			return
+// Next parses a single outer message, or record of an event log, and returns
+// io.EOF once the replay ends. It allows parsing a replay one step at a time
+// instead of with Start, for example to inspect the state of the parser
+// between messages. Outer messages are not pipelined.
+func (p *Parser) Next() (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panicError(v)
 		}
+	}()
+
+	return p.next(p.readOuterMessage)
+}
+
+// next parses a single outer message read with the given function.
+func (p *Parser) next(readOuterMessage func() (*outerMessage, error)) error {
+	// Event logs are replayed record by record instead of decoding outer
+	// messages.
+	if p.eventLog != nil {
+		return p.eventLog.next(p)
 	}
 
-	return
+	// Broadcasts fetch fragments between outer messages.
+	if p.broadcast != nil {
+		return p.broadcast.next(p)
+	}
+
+	msg, err := readOuterMessage()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			p.truncated = !p.stopped
+		}
+		return p.truncatedError(err)
+	}
+
+	tick := p.Tick
+	p.Tick = msg.tick
+	p.lastOuterMessage = msg
+	if msg.typeId == int32(dota.EDemoCommands_DEM_Stop) {
+		p.stopped = true
+	}
+
+	if err := p.Callbacks.callByDemoType(msg.typeId, msg.tick, msg.data); err != nil {
+		if err = p.truncatedError(err); err == io.ErrUnexpectedEOF {
+			p.Tick = tick
+		}
+		return err
+	}
+	return nil
+}
+
+// decodeError is an error decoding the data of a message, as opposed to an
+// error returned by a handler.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// truncatedError returns io.ErrUnexpectedEOF for an error decoding a message
+// before the stop when truncated replays are allowed: a replay cut while it
+// was written can end with garbage instead of a partial message.
+func (p *Parser) truncatedError(err error) error {
+	var de *decodeError
+	if p.allowTruncated && !p.stopped && errors.As(err, &de) {
+		p.truncated = true
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// panicError returns the error of a recovered panic.
+func panicError(v interface{}) error {
+	if e, ok := v.(error); ok {
+		return e
+	}
+	return fmt.Errorf("%v", v)
 }
 
 // Stop parsing the replay, causing the parser to stop processing new events.
@@ -185,9 +372,12 @@ func (p *Parser) LookupStringByIndex(table string, index int32) (string, bool) {
 
 // Describes a demo message parsed from the replay.
 type outerMessage struct {
-	tick   uint32
-	typeId int32
-	data   []byte
+	tick       uint32
+	typeId     int32
+	data       []byte
+	size       uint32
+	compressed bool
+	owned      bool // data is not reused by the stream
 }
 
 // Read the next outer message from the buffer.
@@ -204,10 +394,11 @@ func (p *Parser) readOuterMessage() (*outerMessage, error) {
 	msgType := int32(command & ^dota.EDemoCommands_DEM_IsCompressed)
 	msgCompressed := (command & dota.EDemoCommands_DEM_IsCompressed) == dota.EDemoCommands_DEM_IsCompressed
 
-	// Read the tick that the message corresponds with.
+	// Read the tick that the message corresponds with. The replay may only
+	// end before a command, anywhere else it is truncated.
 	tick, err := p.stream.readVarUint32()
 	if err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 
 	// This appears to actually be an int32, where a -1 means pre-game.
@@ -218,27 +409,36 @@ func (p *Parser) readOuterMessage() (*outerMessage, error) {
 	// Read the size and following buffer.
 	size, err := p.stream.readVarUint32()
 	if err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 
-	buf, err := p.stream.readBytes(size)
+	// The stream buffer is reused for the next read unless buffers are
+	// immutable.
+	readBytes := p.stream.readBytes
+	if p.immutableBuffers {
+		readBytes = p.stream.readOwnedBytes
+	}
+	buf, err := readBytes(size)
 	if err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 
 	// If the buffer is compressed, decompress it with snappy.
 	if msgCompressed {
 		var err error
 		if buf, err = snappy.Decode(nil, buf); err != nil {
-			return nil, err
+			return nil, &decodeError{err}
 		}
 	}
 
 	// Return the message
 	msg := &outerMessage{
-		tick:   tick,
-		typeId: msgType,
-		data:   buf,
+		tick:       tick,
+		typeId:     msgType,
+		data:       buf,
+		size:       size,
+		compressed: msgCompressed,
+		owned:      msgCompressed || p.immutableBuffers,
 	}
 	return msg, nil
 }
@@ -90,3 +90,135 @@ func TestStreamingReaderUnexpectedEOF(t *testing.T) {
 	err = parser.Start()
 	assert.Equal(io.ErrUnexpectedEOF, err)
 }
+
+func TestParserReset(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := NewParser(_demo_stream(4))
+	if !assert.Nil(err) {
+		return
+	}
+
+	first := 0
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		first++
+		return nil
+	})
+	p.OnEntity(func(e *Entity, op EntityOp) error { return nil })
+	p.entities[1] = newEntity(1, 1, &class{name: "CDOTA_PlayerResource"})
+	assert.Nil(p.Start())
+	assert.Equal(4, first)
+	assert.Equal(uint32(6), p.Tick)
+
+	// After a reset nothing of the previous replay remains, including the
+	// handlers registered for it.
+	assert.Nil(p.Reset(bytes.NewReader(_demo_stream(6))))
+	assert.Equal(uint32(0), p.Tick)
+	assert.Empty(p.entities)
+	assert.Empty(p.entityHandlers)
+
+	second := 0
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		second++
+		return nil
+	})
+	assert.Nil(p.Start())
+	assert.Equal(4, first)
+	assert.Equal(6, second)
+	assert.Equal(uint32(10), p.Tick)
+
+	// The internal handlers are registered again.
+	assert.Nil(p.Reset(bytes.NewReader(_demo_stream(0))))
+	assert.Len(p.Callbacks.onCNETMsg_Tick, 1)
+	assert.Len(p.Callbacks.onCDemoPacket, 1)
+
+	assert.NotNil(p.Reset(bytes.NewReader([]byte("PBDEMS1\000"))))
+}
+
+func benchmarkCorpus(b *testing.B, reuse bool) {
+	s := testScenarios[2159568145]
+	data := mustGetReplayData(s.matchId, s.replayUrl)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var parser *Parser
+	for n := 0; n < b.N; n++ {
+		var err error
+		if reuse && parser != nil {
+			err = parser.Reset(bytes.NewReader(data))
+		} else {
+			parser, err = NewParser(data)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		parser.OnEntity(func(e *Entity, op EntityOp) error { return nil })
+
+		if err := parser.Start(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCorpusNewParser(b *testing.B)   { benchmarkCorpus(b, false) }
+func BenchmarkCorpusResetParser(b *testing.B) { benchmarkCorpus(b, true) }
+
+func TestParserNext(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", nil)
+	for i := 0; i < 5; i++ {
+		b.Advance(30)
+		b.Update(10, map[string]interface{}{"m_iHealth": 600 - i*10})
+	}
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	// Stepping through the replay sees the same state after each message as
+	// Start does.
+	var want []int32
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		if e := p.FindEntity(10); e != nil {
+			health, _ := e.GetInt32("m_iHealth")
+			want = append(want, health)
+		}
+		return nil
+	})
+	assert.Nil(p.Start())
+
+	var got []int32
+	var ticks []uint32
+	p, err = NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	for {
+		e := p.FindEntity(10)
+		health := int32(-1)
+		if e != nil {
+			health, _ = e.GetInt32("m_iHealth")
+		}
+		if err := p.Next(); err != nil {
+			assert.Equal(io.EOF, err)
+			break
+		}
+		if e != nil {
+			got = append(got, health)
+		}
+		ticks = append(ticks, p.Tick)
+	}
+	assert.Equal(want, got)
+	assert.Equal(uint32(150), ticks[len(ticks)-1])
+	assert.False(p.Truncated())
+	assert.Equal(io.EOF, p.Next())
+}
@@ -0,0 +1,72 @@
+package manta
+
+import "io"
+
+// EnablePipeline makes Start read and decompress up to depth outer messages
+// ahead of the one being dispatched, in a separate goroutine. Handlers are
+// still called one after another on the goroutine calling Start, in replay
+// order, so their semantics do not change. It must be called before Start.
+func (p *Parser) EnablePipeline(depth int) {
+	p.pipelineDepth = depth
+}
+
+// outerResult is an outer message, or the error that ended reading them.
+type outerResult struct {
+	msg *outerMessage
+	err error
+}
+
+// outerPipeline reads outer messages in a goroutine into a bounded buffer.
+type outerPipeline struct {
+	out  chan outerResult
+	done chan struct{}
+}
+
+// newOuterPipeline starts reading outer messages of the given parser.
+func newOuterPipeline(p *Parser, depth int) *outerPipeline {
+	pl := &outerPipeline{
+		out:  make(chan outerResult, depth),
+		done: make(chan struct{}),
+	}
+	go pl.run(p)
+	return pl
+}
+
+func (pl *outerPipeline) run(p *Parser) {
+	defer close(pl.out)
+
+	for {
+		msg, err := p.readOuterMessage()
+
+		// Messages may alias the stream buffer, which is reused by the next
+		// read while this message is still queued.
+		if err == nil && !msg.owned {
+			msg.data = RetainBytes(msg.data)
+			msg.owned = true
+		}
+
+		select {
+		case pl.out <- outerResult{msg, err}:
+		case <-pl.done:
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// next returns the next outer message, in the same way readOuterMessage does.
+func (pl *outerPipeline) next() (*outerMessage, error) {
+	r, ok := <-pl.out
+	if !ok {
+		return nil, io.EOF
+	}
+	return r.msg, r.err
+}
+
+// stop ends reading ahead. Messages already read are discarded.
+func (pl *outerPipeline) stop() {
+	close(pl.done)
+}
@@ -0,0 +1,107 @@
+package manta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+// _demo_stream builds a replay stream of outer messages with arbitrary
+// payloads, compressing every other one.
+func _demo_stream(n int) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(magicSource2)
+	buf.Write(make([]byte, 8))
+
+	varint := func(v uint64) {
+		b := make([]byte, binary.MaxVarintLen64)
+		buf.Write(b[:binary.PutUvarint(b, v)])
+	}
+
+	for i := 0; i < n; i++ {
+		data := bytes.Repeat([]byte{byte(i)}, 100+i*37)
+		cmd := uint64(dota.EDemoCommands_DEM_SyncTick)
+		if i%2 == 1 {
+			cmd = uint64(dota.EDemoCommands_DEM_ConsoleCmd)
+			cmd |= uint64(dota.EDemoCommands_DEM_IsCompressed)
+			data = snappy.Encode(nil, data)
+		}
+		varint(cmd)
+		varint(uint64(i * 2))
+		varint(uint64(len(data)))
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+type _raw_message struct {
+	t    int32
+	tick uint32
+	buf  []byte
+}
+
+func _collect_demo_messages(t *testing.T, data []byte, depth int) []_raw_message {
+	p, err := NewParser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.EnablePipeline(depth)
+
+	var ms []_raw_message
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		ms = append(ms, _raw_message{t, tick, append([]byte(nil), buf...)})
+		return nil
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return ms
+}
+
+func TestPipelineMatchesSequential(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _demo_stream(50)
+	sequential := _collect_demo_messages(t, data, 0)
+	assert.Len(sequential, 50)
+
+	for _, depth := range []int{1, 4, 64} {
+		assert.Equal(sequential, _collect_demo_messages(t, data, depth), "depth %d", depth)
+	}
+}
+
+func TestPipelineStop(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := NewParser(_demo_stream(50))
+	if !assert.Nil(err) {
+		return
+	}
+	p.EnablePipeline(2)
+
+	count := 0
+	p.Callbacks.OnAnyDemoMessage(func(t int32, tick uint32, buf []byte) error {
+		if count++; count == 3 {
+			p.Stop()
+		}
+		return nil
+	})
+
+	assert.Nil(p.Start())
+	assert.Equal(3, count)
+	assert.Equal(uint32(4), p.Tick)
+}
+
+func TestPipelineTruncated(t *testing.T) {
+	data := _demo_stream(10)
+	p, err := NewParser(data[:len(data)-5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.EnablePipeline(4)
+	assert.NotNil(t, p.Start())
+}
@@ -0,0 +1,267 @@
+package manta
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dotabuff/manta/dota"
+)
+
+// MessageStats describes how often a message type appeared in a replay and
+// how much space it took.
+type MessageStats struct {
+	Type  int32
+	Name  string
+	Count int
+
+	// Bytes is the decoded size of all messages of this type. For outer demo
+	// messages CompressedBytes is their size in the file, which differs from
+	// Bytes for the Compressed messages only.
+	Bytes           uint64
+	CompressedBytes uint64
+	Compressed      int
+}
+
+// EntityStats counts the entity operations observed for a class.
+type EntityStats struct {
+	Class   string
+	Created int
+	Updated int
+	Deleted int
+}
+
+// Total returns the number of operations of all kinds.
+func (s *EntityStats) Total() int {
+	return s.Created + s.Updated + s.Deleted
+}
+
+// Profiler collects statistics about every message and entity operation of a
+// replay, without decoding messages nobody else is interested in.
+type Profiler struct {
+	p *Parser
+
+	demo     map[int32]*MessageStats
+	packet   map[int32]*MessageStats
+	entities map[string]*EntityStats
+
+	firstTick    uint32
+	lastTick     uint32
+	tickInterval float32
+}
+
+// NewProfiler creates a Profiler collecting statistics from the given parser.
+func NewProfiler(p *Parser) *Profiler {
+	pr := &Profiler{
+		p:            p,
+		demo:         make(map[int32]*MessageStats),
+		packet:       make(map[int32]*MessageStats),
+		entities:     make(map[string]*EntityStats),
+		tickInterval: 1.0 / 30,
+	}
+
+	p.Callbacks.OnAnyDemoMessage(pr.onDemoMessage)
+	p.Callbacks.OnAnyPacketMessage(pr.onPacketMessage)
+	p.OnEntity(pr.onEntity)
+
+	p.Callbacks.OnCSVCMsg_ServerInfo(func(m *dota.CSVCMsg_ServerInfo) error {
+		if ti := m.GetTickInterval(); ti > 0 {
+			pr.tickInterval = ti
+		}
+		return nil
+	})
+
+	return pr
+}
+
+func (pr *Profiler) onDemoMessage(t int32, tick uint32, buf []byte) error {
+	s := statsFor(pr.demo, t, demoMessageName)
+	s.Count++
+	s.Bytes += uint64(len(buf))
+
+	if m := pr.p.lastOuterMessage; m != nil {
+		s.CompressedBytes += uint64(m.size)
+		if m.compressed {
+			s.Compressed++
+		}
+	} else {
+		s.CompressedBytes += uint64(len(buf))
+	}
+
+	if tick > 0 {
+		if pr.firstTick == 0 || tick < pr.firstTick {
+			pr.firstTick = tick
+		}
+		if tick > pr.lastTick {
+			pr.lastTick = tick
+		}
+	}
+	return nil
+}
+
+func (pr *Profiler) onPacketMessage(t int32, tick uint32, buf []byte) error {
+	s := statsFor(pr.packet, t, packetMessageName)
+	s.Count++
+	s.Bytes += uint64(len(buf))
+	s.CompressedBytes += uint64(len(buf))
+	return nil
+}
+
+func (pr *Profiler) onEntity(e *Entity, op EntityOp) error {
+	cn := e.GetClassName()
+	s, ok := pr.entities[cn]
+	if !ok {
+		s = &EntityStats{Class: cn}
+		pr.entities[cn] = s
+	}
+
+	switch {
+	case op.Flag(EntityOpCreated):
+		s.Created++
+	case op.Flag(EntityOpDeleted):
+		s.Deleted++
+	case op.Flag(EntityOpUpdated):
+		s.Updated++
+	}
+	return nil
+}
+
+func statsFor(m map[int32]*MessageStats, t int32, name func(int32) string) *MessageStats {
+	s, ok := m[t]
+	if !ok {
+		s = &MessageStats{Type: t, Name: name(t)}
+		m[t] = s
+	}
+	return s
+}
+
+// DemoMessages returns the statistics of outer demo messages, largest first.
+func (pr *Profiler) DemoMessages() []*MessageStats {
+	return sortedMessageStats(pr.demo)
+}
+
+// PacketMessages returns the statistics of messages embedded in packets,
+// largest first.
+func (pr *Profiler) PacketMessages() []*MessageStats {
+	return sortedMessageStats(pr.packet)
+}
+
+func sortedMessageStats(m map[int32]*MessageStats) []*MessageStats {
+	stats := make([]*MessageStats, 0, len(m))
+	for _, s := range m {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Type < stats[j].Type
+	})
+	return stats
+}
+
+// Entities returns the entity statistics per class, busiest first.
+func (pr *Profiler) Entities() []*EntityStats {
+	stats := make([]*EntityStats, 0, len(pr.entities))
+	for _, s := range pr.entities {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total() != stats[j].Total() {
+			return stats[i].Total() > stats[j].Total()
+		}
+		return stats[i].Class < stats[j].Class
+	})
+	return stats
+}
+
+// Minutes returns the duration of the replay covered so far in minutes.
+func (pr *Profiler) Minutes() float64 {
+	if pr.lastTick <= pr.firstTick {
+		return 0
+	}
+	return float64(pr.lastTick-pr.firstTick) * float64(pr.tickInterval) / 60
+}
+
+// PerMinute returns a count as a rate per minute of replay.
+func (pr *Profiler) PerMinute(n int) float64 {
+	if m := pr.Minutes(); m > 0 {
+		return float64(n) / m
+	}
+	return 0
+}
+
+// WriteReport writes the collected statistics as human readable tables.
+func (pr *Profiler) WriteReport(w io.Writer) error {
+	pw := &reportWriter{w: w}
+
+	pw.printf("duration: %.1f minutes (ticks %d-%d)\n", pr.Minutes(), pr.firstTick, pr.lastTick)
+	if pr.p.Truncated() {
+		pw.printf("truncated: the replay ends at tick %d before its stop\n", pr.p.Tick)
+	}
+
+	pw.printf("\n%-40s %10s %10s %14s %14s %10s\n", "demo message", "count", "/min", "bytes", "file bytes", "ratio")
+	for _, s := range pr.DemoMessages() {
+		ratio := 1.0
+		if s.CompressedBytes > 0 {
+			ratio = float64(s.Bytes) / float64(s.CompressedBytes)
+		}
+		pw.printf("%-40s %10d %10.1f %14d %14d %10.2f\n", s.Name, s.Count, pr.PerMinute(s.Count), s.Bytes, s.CompressedBytes, ratio)
+	}
+
+	pw.printf("\n%-40s %10s %10s %14s %10s\n", "packet message", "count", "/min", "bytes", "avg")
+	for _, s := range pr.PacketMessages() {
+		pw.printf("%-40s %10d %10.1f %14d %10.1f\n", s.Name, s.Count, pr.PerMinute(s.Count), s.Bytes, float64(s.Bytes)/float64(s.Count))
+	}
+
+	pw.printf("\n%-40s %10s %10s %10s %10s\n", "entity class", "created", "updated", "deleted", "upd/min")
+	for _, s := range pr.Entities() {
+		pw.printf("%-40s %10d %10d %10d %10.1f\n", s.Class, s.Created, s.Updated, s.Deleted, pr.PerMinute(s.Updated))
+	}
+
+	return pw.err
+}
+
+// reportWriter remembers the first write error so a report can be written
+// without checking each line.
+type reportWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (rw *reportWriter) printf(format string, args ...interface{}) {
+	if rw.err == nil {
+		_, rw.err = fmt.Fprintf(rw.w, format, args...)
+	}
+}
+
+// packetEnumNames are the enums of messages a server sends in packets, in
+// lookup order.
+var packetEnumNames = []map[int32]string{
+	dota.NET_Messages_name,
+	dota.SVC_Messages_name,
+	dota.Bidirectional_Messages_name,
+	dota.EBaseUserMessages_name,
+	dota.EBaseEntityMessages_name,
+	dota.EBaseGameEvents_name,
+	dota.ETEProtobufIds_name,
+	dota.EDotaUserMessages_name,
+}
+
+// demoMessageName returns the enum name of an outer demo message type.
+func demoMessageName(t int32) string {
+	if name, ok := dota.EDemoCommands_name[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", t)
+}
+
+// packetMessageName returns the enum name of a packet message type.
+func packetMessageName(t int32) string {
+	for _, names := range packetEnumNames {
+		if name, ok := names[t]; ok {
+			return name
+		}
+	}
+	return fmt.Sprintf("unknown(%d)", t)
+}
@@ -0,0 +1,64 @@
+package manta
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	assert := assert.New(t)
+
+	p := newParser(bytes.NewReader(nil))
+	pr := NewProfiler(p)
+
+	p.Tick = 1800
+	p.lastOuterMessage = &outerMessage{size: 40, compressed: true}
+	assert.Nil(pr.onDemoMessage(int32(dota.EDemoCommands_DEM_Packet), p.Tick, make([]byte, 100)))
+
+	p.Tick = 3600
+	p.lastOuterMessage = &outerMessage{size: 10}
+	assert.Nil(pr.onDemoMessage(int32(dota.EDemoCommands_DEM_Packet), p.Tick, make([]byte, 10)))
+	assert.Nil(pr.onDemoMessage(int32(dota.EDemoCommands_DEM_FileInfo), p.Tick, make([]byte, 5)))
+
+	assert.Nil(pr.onPacketMessage(int32(dota.SVC_Messages_svc_PacketEntities), p.Tick, make([]byte, 30)))
+	assert.Nil(pr.onPacketMessage(int32(dota.SVC_Messages_svc_PacketEntities), p.Tick, make([]byte, 20)))
+	assert.Nil(pr.onPacketMessage(int32(dota.EDotaUserMessages_DOTA_UM_ChatWheel), p.Tick, make([]byte, 8)))
+	assert.Nil(pr.onPacketMessage(9999, p.Tick, make([]byte, 1)))
+
+	hero := newDetachedEntity(1, 1, &class{name: "CDOTA_Unit_Hero_Puck"})
+	assert.Nil(pr.onEntity(hero, EntityOpCreatedEntered))
+	assert.Nil(pr.onEntity(hero, EntityOpUpdated))
+	assert.Nil(pr.onEntity(hero, EntityOpUpdated))
+	assert.Nil(pr.onEntity(hero, EntityOpDeletedLeft))
+
+	assert.InDelta(1.0, pr.Minutes(), 0.001)
+
+	demo := pr.DemoMessages()
+	if assert.Len(demo, 2) {
+		assert.Equal("DEM_Packet", demo[0].Name)
+		assert.Equal(2, demo[0].Count)
+		assert.Equal(uint64(110), demo[0].Bytes)
+		assert.Equal(uint64(50), demo[0].CompressedBytes)
+		assert.Equal(1, demo[0].Compressed)
+	}
+
+	packet := pr.PacketMessages()
+	if assert.Len(packet, 3) {
+		assert.Equal("svc_PacketEntities", packet[0].Name)
+		assert.Equal(uint64(50), packet[0].Bytes)
+		assert.Equal("DOTA_UM_ChatWheel", packet[1].Name)
+		assert.Equal("unknown(9999)", packet[2].Name)
+	}
+
+	entities := pr.Entities()
+	if assert.Len(entities, 1) {
+		assert.Equal(EntityStats{Class: "CDOTA_Unit_Hero_Puck", Created: 1, Updated: 2, Deleted: 1}, *entities[0])
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(pr.WriteReport(buf))
+	assert.Contains(buf.String(), "svc_PacketEntities")
+}
@@ -11,8 +11,8 @@ type reader struct {
 	buf      []byte
 	size     uint32
 	pos      uint32
-	bitVal   uint64 // value of the remaining bits in the current byte
-	bitCount uint32 // number of remaining bits in the current byte
+	bitVal   uint64 // value of the buffered bits not read yet
+	bitCount uint32 // number of buffered bits not read yet
 }
 
 // newReader creates a new reader object for the given buffer
@@ -22,19 +22,20 @@ func newReader(buf []byte) *reader {
 
 // remBits calculates the number of unread bits in the buffer
 func (r *reader) remBits() uint32 {
-	return r.remBytes() + r.bitCount
+	return (r.size-r.pos)*8 + r.bitCount
 }
 
 func (r *reader) position() string {
-	if r.bitCount > 0 {
-		return fmt.Sprintf("%d.%d", r.pos-1, 8-r.bitCount)
+	bits := r.pos*8 - r.bitCount
+	if bits%8 > 0 {
+		return fmt.Sprintf("%d.%d", bits/8, bits%8)
 	}
-	return fmt.Sprintf("%d", r.pos)
+	return fmt.Sprintf("%d", bits/8)
 }
 
-// remBytes calculates the number of unread bytes in the buffer
+// remBytes calculates the number of unread whole bytes in the buffer
 func (r *reader) remBytes() uint32 {
-	return r.size - r.pos
+	return r.size - r.pos + r.bitCount/8
 }
 
 // nextByte reads the next byte from the buffer
@@ -60,6 +61,18 @@ func (r *reader) readBits(n uint32) uint32 {
 	return uint32(x)
 }
 
+// peekBits returns the value of the given number of sequential bits without
+// consuming them. Bits past the end of the buffer are read as zero.
+func (r *reader) peekBits(n uint32) uint32 {
+	for n > r.bitCount && r.pos < r.size {
+		r.bitVal |= uint64(r.buf[r.pos]) << r.bitCount
+		r.pos++
+		r.bitCount += 8
+	}
+
+	return uint32(r.bitVal & ((1 << n) - 1))
+}
+
 // readByte reads a single byte
 func (r *reader) readByte() byte {
 	// Fast path if we're byte aligned
@@ -26,21 +26,53 @@ var itemCounts = map[string]int{
 
 // Internal callback for OnCDemoSendTables.
 func (p *Parser) onCDemoSendTables(m *dota.CDemoSendTables) error {
-	r := newReader(m.GetData())
+	// Serializers are only used to decode entities.
+	if p.subsystems&SubsystemEntities == 0 {
+		return nil
+	}
+
+	var serializers map[string]*serializer
+	var err error
+	if p.serializerCache != nil {
+		serializers, err = p.serializerCache.get(p.GameBuild, m.GetData())
+	} else {
+		serializers, err = parseSendTables(m.GetData(), p.GameBuild)
+	}
+	if err != nil {
+		return err
+	}
+
+	for name, serializer := range serializers {
+		p.serializers[name] = serializer
+
+		if _, ok := p.classesByName[name]; ok {
+			p.classesByName[name].serializer = serializer
+		}
+	}
+
+	return nil
+}
+
+// parseSendTables builds the serializers described by the data of a
+// CDemoSendTables message for the given game build.
+func parseSendTables(data []byte, build uint32) (map[string]*serializer, error) {
+	r := newReader(data)
 	buf := r.readBytes(r.readVarUint32())
 
 	msg := &dota.CSVCMsg_FlattenedSerializer{}
 	if err := proto.Unmarshal(buf, msg); err != nil {
-		return err
+		return nil, err
 	}
 
 	patches := []fieldPatch{}
 	for _, h := range fieldPatches {
-		if h.shouldApply(p.GameBuild) {
+		if h.shouldApply(build) {
 			patches = append(patches, h)
 		}
 	}
 
+	serializers := map[string]*serializer{}
+
 	fields := map[int32]*field{}
 	fieldTypes := map[string]*fieldType{}
 
@@ -57,7 +89,7 @@ func (p *Parser) onCDemoSendTables(m *dota.CDemoSendTables) error {
 				field := newField(msg, msg.GetFields()[i])
 
 				// patch parent name in builds <= 990
-				if p.GameBuild <= 990 {
+				if build <= 990 {
 					field.parentName = serializer.name
 				}
 
@@ -69,7 +101,7 @@ func (p *Parser) onCDemoSendTables(m *dota.CDemoSendTables) error {
 
 				// find associated serializer
 				if field.serializerName != "" {
-					field.serializer = p.serializers[field.serializerName]
+					field.serializer = serializers[field.serializerName]
 				}
 
 				// apply any build-specific patches to the field
@@ -101,12 +133,8 @@ func (p *Parser) onCDemoSendTables(m *dota.CDemoSendTables) error {
 		}
 
 		// store the serializer for field reference
-		p.serializers[serializer.name] = serializer
-
-		if _, ok := p.classesByName[serializer.name]; ok {
-			p.classesByName[serializer.name].serializer = serializer
-		}
+		serializers[serializer.name] = serializer
 	}
 
-	return nil
+	return serializers, nil
 }
@@ -0,0 +1,99 @@
+package manta
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// SerializerCache shares the serializers built from CDemoSendTables between
+// parsers, so that replays of the same build only pay for building them
+// once. Entries are keyed by game build and a hash of the send tables. It is
+// safe for concurrent use by parsers running in different goroutines.
+type SerializerCache struct {
+	mu      sync.Mutex
+	entries map[serializerCacheKey]*serializerCacheEntry
+	hits    int
+	misses  int
+}
+
+type serializerCacheKey struct {
+	build uint32
+	hash  [sha256.Size]byte
+}
+
+type serializerCacheEntry struct {
+	ready       chan struct{}
+	serializers map[string]*serializer
+	err         error
+}
+
+// NewSerializerCache returns an empty SerializerCache.
+func NewSerializerCache() *SerializerCache {
+	return &SerializerCache{
+		entries: make(map[serializerCacheKey]*serializerCacheEntry),
+	}
+}
+
+// UseSerializerCache makes the parser take serializers from the given cache
+// instead of building its own. It must be called before Start.
+func (p *Parser) UseSerializerCache(c *SerializerCache) {
+	p.serializerCache = c
+}
+
+// Stats returns the number of lookups served from the cache and the number
+// of send tables which had to be parsed.
+func (c *SerializerCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Len returns the number of cached send tables.
+func (c *SerializerCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// get returns the serializers for the given send tables, parsing them unless
+// they are cached. Concurrent lookups of the same send tables wait for the
+// first one to finish parsing. Serializers are never modified once built and
+// may be shared.
+func (c *SerializerCache) get(build uint32, data []byte) (map[string]*serializer, error) {
+	key := serializerCacheKey{build, sha256.Sum256(data)}
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		c.hits++
+		c.mu.Unlock()
+		<-e.ready
+		return e.serializers, e.err
+	}
+	e = &serializerCacheEntry{ready: make(chan struct{})}
+	c.entries[key] = e
+	c.misses++
+	c.mu.Unlock()
+
+	e.serializers, e.err = c.parse(data, build)
+	close(e.ready)
+
+	// Failures are not cached, a later replay may succeed.
+	if e.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	return e.serializers, e.err
+}
+
+// parse calls parseSendTables, turning the panics it raises on malformed
+// data into errors so that waiting lookups are always released.
+func (c *SerializerCache) parse(data []byte, build uint32) (serializers map[string]*serializer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = _errorf("unable to parse send tables: %v", r)
+		}
+	}()
+	return parseSendTables(data, build)
+}
@@ -0,0 +1,84 @@
+package manta
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func _send_tables_fixture(t *testing.T, name string) *dota.CDemoSendTables {
+	m := &dota.CDemoSendTables{}
+	if err := proto.Unmarshal(_read_fixture(name), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSerializerCacheShared(t *testing.T) {
+	assert := assert.New(t)
+
+	m := _send_tables_fixture(t, "send_tables/1560315800.pbmsg")
+	cache := NewSerializerCache()
+
+	parsers := make([]*Parser, 8)
+	var wg sync.WaitGroup
+	for i := range parsers {
+		p := newParser(bytes.NewReader(nil))
+		p.UseSerializerCache(cache)
+		p.classesByName["CDOTA_PlayerResource"] = &class{name: "CDOTA_PlayerResource"}
+		parsers[i] = p
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(p.onCDemoSendTables(m))
+		}()
+	}
+	wg.Wait()
+
+	hits, misses := cache.Stats()
+	assert.Equal(7, hits)
+	assert.Equal(1, misses)
+	assert.Equal(1, cache.Len())
+
+	// All parsers share the same serializers, and classes known before the
+	// send tables are linked to them.
+	first := parsers[0].serializers["CDOTA_PlayerResource"]
+	if assert.NotNil(first) {
+		for _, p := range parsers {
+			assert.True(first == p.serializers["CDOTA_PlayerResource"])
+			assert.True(first == p.classesByName["CDOTA_PlayerResource"].serializer)
+		}
+	}
+
+	// Serializers built without the cache are equivalent.
+	uncached := newParser(bytes.NewReader(nil))
+	assert.Nil(uncached.onCDemoSendTables(m))
+	assert.Equal(len(uncached.serializers), len(parsers[0].serializers))
+
+	// Another build of the same send tables is a separate entry.
+	other := newParser(bytes.NewReader(nil))
+	other.GameBuild = 1
+	other.UseSerializerCache(cache)
+	assert.Nil(other.onCDemoSendTables(m))
+	assert.Equal(2, cache.Len())
+	assert.False(first == other.serializers["CDOTA_PlayerResource"])
+}
+
+func TestSerializerCacheError(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewSerializerCache()
+	p := newParser(bytes.NewReader(nil))
+	p.UseSerializerCache(cache)
+
+	// Truncated send tables fail and are not cached.
+	m := _send_tables_fixture(t, "send_tables/1560315800.pbmsg")
+	m.Data = m.Data[:1000]
+	assert.NotNil(p.onCDemoSendTables(m))
+	assert.Equal(0, cache.Len())
+}
@@ -35,6 +35,25 @@ func (s *stream) readBytes(n uint32) ([]byte, error) {
 	return s.buf[:n], nil
 }
 
+// readOwnedBytes reads the given number of bytes into a new buffer which is
+// not reused by later reads.
+func (s *stream) readOwnedBytes(n uint32) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(s.Reader, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// unexpectedEOF turns io.EOF into io.ErrUnexpectedEOF, for reads in the
+// middle of an outer message.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
 // readByte reads a single byte from the reader
 func (s *stream) readByte() (byte, error) {
 	buf, err := s.readBytes(1)
@@ -1,6 +1,8 @@
 package manta
 
 import (
+	"sort"
+
 	"github.com/dotabuff/manta/dota"
 	"github.com/golang/snappy"
 )
@@ -17,6 +19,17 @@ type stringTables struct {
 	nextIndex int32
 }
 
+// reset removes all tables, keeping the allocated maps.
+func (ts *stringTables) reset() {
+	for k := range ts.Tables {
+		delete(ts.Tables, k)
+	}
+	for k := range ts.NameIndex {
+		delete(ts.NameIndex, k)
+	}
+	ts.nextIndex = 0
+}
+
 // Retrieves a string table by its name. Check the bool.
 func (ts *stringTables) GetTableByName(name string) (*stringTable, bool) {
 	i, ok := ts.NameIndex[name]
@@ -58,6 +71,41 @@ type stringTableItem struct {
 	Value []byte
 }
 
+// StringTableEntry is an entry of a string table.
+type StringTableEntry struct {
+	Index int32
+	Key   string
+	Value []byte
+}
+
+// StringTableNames returns the names of the string tables created so far, in
+// order of creation.
+func (p *Parser) StringTableNames() []string {
+	names := make([]string, 0, len(p.stringTables.Tables))
+	for i := int32(0); i < p.stringTables.nextIndex; i++ {
+		if t, ok := p.stringTables.Tables[i]; ok {
+			names = append(names, t.name)
+		}
+	}
+	return names
+}
+
+// StringTableEntries returns the current entries of a string table ordered
+// by index. Values are shared with the parser and must not be modified.
+func (p *Parser) StringTableEntries(table string) ([]StringTableEntry, bool) {
+	t, ok := p.stringTables.GetTableByName(table)
+	if !ok {
+		return nil, false
+	}
+
+	entries := make([]StringTableEntry, 0, len(t.Items))
+	for _, item := range t.Items {
+		entries = append(entries, StringTableEntry{item.Index, item.Key, item.Value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Index < entries[j].Index })
+	return entries, true
+}
+
 // Internal callback for CDemoStringTables.
 // These appear to be periodic state dumps and appear every 1800 outer ticks.
 // XXX TODO: decide if we want to at all integrate these updates,
@@ -70,6 +118,10 @@ func (p *Parser) onCDemoStringTables(m *dota.CDemoStringTables) error {
 // XXX TODO: This is currently using an artificial, internally crafted message.
 // This should be replaced with the real message once we have updated protos.
 func (p *Parser) onCSVCMsg_CreateStringTable(m *dota.CSVCMsg_CreateStringTable) error {
+	if p.subsystems&SubsystemStringTables == 0 {
+		return nil
+	}
+
 	// Create a new string table at the next index position
 	t := &stringTable{
 		index:             p.stringTables.nextIndex,
@@ -133,6 +185,10 @@ func (p *Parser) onCSVCMsg_CreateStringTable(m *dota.CSVCMsg_CreateStringTable)
 
 // Internal callback for CSVCMsg_UpdateStringTable.
 func (p *Parser) onCSVCMsg_UpdateStringTable(m *dota.CSVCMsg_UpdateStringTable) error {
+	if p.subsystems&SubsystemStringTables == 0 {
+		return nil
+	}
+
 	// TODO: integrate
 	t, ok := p.stringTables.Tables[m.GetTableId()]
 	if !ok {
@@ -176,6 +232,49 @@ func (p *Parser) onCSVCMsg_UpdateStringTable(m *dota.CSVCMsg_UpdateStringTable)
 	return nil
 }
 
+// writeStringTable encodes items, sorted by index, in the format read by
+// parseStringTable. Values are written uncompressed and keys without using
+// the key history.
+func writeStringTable(items []*stringTableItem, userDataFixed bool, userDataSizeBits int32, flags int32, varintBitCounts bool) []byte {
+	w := newWriter()
+
+	index := int32(-1)
+	for _, item := range items {
+		if item.Index == index+1 {
+			w.writeBoolean(true)
+		} else {
+			w.writeBoolean(false)
+			w.writeVarUint32(uint32(item.Index - 1))
+		}
+		index = item.Index
+
+		w.writeBoolean(item.Key != "")
+		if item.Key != "" {
+			w.writeBoolean(false)
+			w.writeString(item.Key)
+		}
+
+		w.writeBoolean(len(item.Value) > 0)
+		if len(item.Value) > 0 {
+			if userDataFixed {
+				w.writeBitsFrom(item.Value, uint32(userDataSizeBits))
+				continue
+			}
+			if (flags & 0x1) != 0 {
+				w.writeBoolean(false)
+			}
+			if varintBitCounts {
+				w.writeUBitVar(uint32(len(item.Value)))
+			} else {
+				w.writeBits(uint32(len(item.Value)), 17)
+			}
+			w.writeBytes(item.Value)
+		}
+	}
+
+	return w.bytes()
+}
+
 // Parse a string table data blob, returning a list of item updates.
 func parseStringTable(buf []byte, numUpdates int32, name string, userDataFixed bool, userDataSizeBits int32, flags int32, varintBitCounts bool) (items []*stringTableItem) {
 	defer func() {
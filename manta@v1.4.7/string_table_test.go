@@ -163,3 +163,39 @@ func TestParseStringTableUpdate(t *testing.T) {
 	assert.Equal(int32(263), items[2].Index)
 	assert.Equal("broodmother_incapacitating_bite", items[2].Key)
 }
+
+func TestStringTableEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.StringTable("ActiveModifiers", "modifier_juggernaut_blade_fury", []byte{1})
+	b.StringTable("ActiveModifiers", "modifier_item_power_treads", []byte{2})
+	b.StringTable("userinfo", "0", nil)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Empty(p.StringTableNames())
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	names := p.StringTableNames()
+	assert.Contains(names, "ActiveModifiers")
+	assert.Contains(names, "userinfo")
+
+	entries, ok := p.StringTableEntries("ActiveModifiers")
+	if assert.True(ok) && assert.Len(entries, 2) {
+		assert.Equal(StringTableEntry{0, "modifier_juggernaut_blade_fury", []byte{1}}, entries[0])
+		assert.Equal(StringTableEntry{1, "modifier_item_power_treads", []byte{2}}, entries[1])
+	}
+
+	_, ok = p.StringTableEntries("NotATable")
+	assert.False(ok)
+}
@@ -0,0 +1,85 @@
+package manta
+
+import (
+	"strings"
+)
+
+// Subsystem is a bitmask of the parts of replay state maintained by a Parser.
+type Subsystem int
+
+const (
+	// SubsystemEntities decodes packet entities, required for OnEntity and
+	// entity lookups. It implies SubsystemStringTables, which hold the
+	// entity baselines.
+	SubsystemEntities Subsystem = 1 << iota
+
+	// SubsystemStringTables maintains string tables, required for
+	// LookupStringByIndex and modifier table entries.
+	SubsystemStringTables
+
+	// SubsystemGameEvents offers game events to OnGameEvent handlers.
+	SubsystemGameEvents
+
+	SubsystemNone Subsystem = 0
+	SubsystemAll  Subsystem = SubsystemEntities | SubsystemStringTables | SubsystemGameEvents
+)
+
+// SetSubsystems declares which parts of the replay state the Parser should
+// maintain, all of them by default. Callbacks for raw messages are called
+// regardless, so tools which only need, for example, chat or the combat log
+// can skip decoding entities entirely, which FilterEntityClasses cannot do for
+// single classes. It must be called before Start.
+func (p *Parser) SetSubsystems(s Subsystem) {
+	if s&SubsystemEntities != 0 {
+		s |= SubsystemStringTables
+	}
+	p.subsystems = s
+}
+
+// Subsystems returns the parts of the replay state maintained by the Parser.
+func (p *Parser) Subsystems() Subsystem {
+	return p.subsystems
+}
+
+// FilterEntityClasses limits the entities materialized by the Parser to the
+// classes for which want returns true. Entities of other classes are still
+// tracked and offered to no OnEntity handlers. The packet entities stream has
+// no length per entity, so their field values are still decoded to find the
+// next entity; only storing them is skipped, which saves about 40% of the
+// decoding time of a class (see BenchmarkReadFields). A nil function
+// materializes every class. It must be called before Start.
+func (p *Parser) FilterEntityClasses(want func(className string) bool) {
+	p.entityClassFilter = want
+}
+
+// EntityClasses returns a filter for FilterEntityClasses which accepts the
+// given class names. A name ending in '*' accepts every class starting with
+// the rest of it, as in "CDOTA_Unit_Hero_*".
+func EntityClasses(names ...string) func(string) bool {
+	exact := make(map[string]bool, len(names))
+	var prefixes []string
+	for _, name := range names {
+		if n := len(name); n > 0 && name[n-1] == '*' {
+			prefixes = append(prefixes, name[:n-1])
+		} else {
+			exact[name] = true
+		}
+	}
+
+	return func(className string) bool {
+		if exact[className] {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(className, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// materializes returns whether entities of the given class keep their state.
+func (p *Parser) materializes(c *class) bool {
+	return p.entityClassFilter == nil || p.entityClassFilter(c.name)
+}
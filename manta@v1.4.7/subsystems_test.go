@@ -0,0 +1,134 @@
+package manta
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSubsystems(t *testing.T) {
+	assert := assert.New(t)
+
+	p := newParser(bytes.NewReader(nil))
+	assert.Equal(SubsystemAll, p.Subsystems())
+
+	// Entities need the string tables holding their baselines.
+	p.SetSubsystems(SubsystemEntities)
+	assert.Equal(SubsystemEntities|SubsystemStringTables, p.Subsystems())
+
+	p.SetSubsystems(SubsystemNone)
+	assert.Nil(p.onCSVCMsg_CreateStringTable(&dota.CSVCMsg_CreateStringTable{Name: proto.String("CombatLogNames")}))
+	assert.Empty(p.stringTables.Tables)
+	assert.Nil(p.onCSVCMsg_PacketEntities(&dota.CSVCMsg_PacketEntities{UpdatedEntries: proto.Int32(1)}))
+	assert.Empty(p.entities)
+	assert.Nil(p.onCDemoSendTables(_send_tables_fixture(t, "send_tables/1560315800.pbmsg")))
+	assert.Empty(p.serializers)
+
+	// Subsystems survive a reset.
+	assert.Nil(p.Reset(bytes.NewReader(_demo_stream(0))))
+	assert.Equal(SubsystemNone, p.Subsystems())
+}
+
+func TestEntityClasses(t *testing.T) {
+	assert := assert.New(t)
+
+	want := EntityClasses("CDOTA_PlayerResource", "CDOTA_Unit_Hero_*")
+	assert.True(want("CDOTA_PlayerResource"))
+	assert.True(want("CDOTA_Unit_Hero_Puck"))
+	assert.False(want("CDOTA_Unit_Hero"))
+	assert.False(want("CDOTAGamerulesProxy"))
+	assert.False(EntityClasses()("CDOTA_PlayerResource"))
+}
+
+func TestReadFieldsSkipped(t *testing.T) {
+	assert := assert.New(t)
+
+	p := newParser(bytes.NewReader(nil))
+	assert.Nil(p.onCDemoSendTables(_send_tables_fixture(t, "send_tables/1560315800.pbmsg")))
+
+	for _, name := range []string{"CDOTAGamerulesProxy", "CDOTATeam", "CDOTAPlayer"} {
+		s := p.serializers[name]
+		if !assert.NotNil(s, name) {
+			continue
+		}
+		buf := _read_fixture("instancebaseline/1560315800_" + name + ".rawbuf")
+
+		state := newFieldState()
+		decoded := newReader(buf)
+		readFields(decoded, s, state, nil, nil)
+
+		// Skipping fields reads exactly as far as decoding them.
+		skipped := newReader(buf)
+		readFields(skipped, s, nil, nil, nil)
+		assert.Equal(decoded.remBits(), skipped.remBits(), name)
+
+		state.release()
+	}
+}
+
+// BenchmarkReadFields compares decoding the fields of a class into its state
+// with reading past them, as done for classes left out by FilterEntityClasses.
+func BenchmarkReadFields(b *testing.B) {
+	p := newParser(bytes.NewReader(nil))
+	m := &dota.CDemoSendTables{}
+	if err := proto.Unmarshal(_read_fixture("send_tables/1560315800.pbmsg"), m); err != nil {
+		b.Fatal(err)
+	}
+	if err := p.onCDemoSendTables(m); err != nil {
+		b.Fatal(err)
+	}
+	s := p.serializers["CDOTAGamerulesProxy"]
+	buf := _read_fixture("instancebaseline/1560315800_CDOTAGamerulesProxy.rawbuf")
+
+	b.Run("decoded", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			state := newFieldState()
+			readFields(newReader(buf), s, state, nil, nil)
+			state.release()
+		}
+	})
+	b.Run("skipped", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			readFields(newReader(buf), s, nil, nil, nil)
+		}
+	})
+}
+
+func benchmarkCombatLogScan(b *testing.B, subsystems Subsystem) {
+	s := testScenarios[2159568145]
+	data := mustGetReplayData(s.matchId, s.replayUrl)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		parser, err := NewParser(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		parser.SetSubsystems(subsystems)
+
+		entries := 0
+		parser.Callbacks.OnCMsgDOTACombatLogEntry(func(m *dota.CMsgDOTACombatLogEntry) error {
+			entries++
+			return nil
+		})
+
+		if err := parser.Start(); err != nil {
+			b.Fatal(err)
+		}
+		if entries == 0 {
+			b.Fatal("no combat log entries")
+		}
+	}
+}
+
+func BenchmarkCombatLogScanAll(b *testing.B) { benchmarkCombatLogScan(b, SubsystemAll) }
+func BenchmarkCombatLogScanStringTables(b *testing.B) {
+	benchmarkCombatLogScan(b, SubsystemStringTables)
+}
@@ -0,0 +1,186 @@
+package manta
+
+import (
+	"io"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Truncated replays
+//
+// A replay which was only partially downloaded, or copied while it was still
+// being written, ends in the middle of an outer message or before its
+// CDemoStop. Validate reports how much of such a replay is intact, and a
+// Parser with AllowTruncated set parses the intact messages and ends without
+// an error, so that analyzers still produce a report for the part of the
+// game it holds.
+
+// Validation is the result of checking the integrity of a replay.
+type Validation struct {
+	// Messages is the number of outer messages which are intact.
+	Messages int
+
+	// LastGoodTick is the tick of the last intact outer message.
+	LastGoodTick uint32
+
+	// Offset is the size in bytes of the intact part of the replay,
+	// including the header.
+	Offset int64
+
+	// Stop and FileInfo tell whether the CDemoStop and CDemoFileInfo
+	// messages written at the end of a game are present.
+	Stop     bool
+	FileInfo bool
+
+	// Truncated is set when the replay ends before its CDemoStop, either
+	// because the file is cut or because a message is corrupt.
+	Truncated bool
+
+	// Err is the problem which ended the intact part of the replay, or nil
+	// if every message up to the end of the file is intact.
+	Err error
+}
+
+// Valid returns whether the replay is complete and intact.
+func (v *Validation) Valid() bool {
+	return v.Err == nil && v.Stop && v.FileInfo
+}
+
+// Validate reads every outer message of the replay read from r, checking
+// that its snappy block decompresses and that it and the messages of packets
+// are well formed protobufs, without running the parser. It only fails if r
+// is not a replay, problems with messages are reported in the Validation.
+func Validate(r io.Reader) (*Validation, error) {
+	cr := &countingReader{r: r}
+	p := newParser(cr)
+	if err := p.readHeader(); err != nil {
+		return nil, err
+	}
+
+	v := &Validation{Offset: cr.n}
+	for {
+		msg, err := p.readOuterMessage()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			v.Err = _errorf("offset %d: %s", v.Offset, err)
+			break
+		}
+		if err := validateOuterMessage(msg); err != nil {
+			v.Err = _errorf("offset %d: tick %d %s: %s", v.Offset, msg.tick, demoMessageName(msg.typeId), err)
+			break
+		}
+
+		v.Messages++
+		v.Offset = cr.n
+		if msg.tick > v.LastGoodTick {
+			v.LastGoodTick = msg.tick
+		}
+		switch dota.EDemoCommands(msg.typeId) {
+		case dota.EDemoCommands_DEM_Stop:
+			v.Stop = true
+		case dota.EDemoCommands_DEM_FileInfo:
+			v.FileInfo = true
+		}
+	}
+
+	v.Truncated = !v.Stop
+	return v, nil
+}
+
+// validateOuterMessage checks that an outer message and the messages of its
+// packet are well formed.
+func validateOuterMessage(msg *outerMessage) error {
+	if err := validateProto(msg.data); err != nil {
+		return err
+	}
+
+	var packet *dota.CDemoPacket
+	switch dota.EDemoCommands(msg.typeId) {
+	case dota.EDemoCommands_DEM_Packet, dota.EDemoCommands_DEM_SignonPacket:
+		packet = &dota.CDemoPacket{}
+		if err := proto.Unmarshal(msg.data, packet); err != nil {
+			return err
+		}
+
+	case dota.EDemoCommands_DEM_FullPacket:
+		m := &dota.CDemoFullPacket{}
+		if err := proto.Unmarshal(msg.data, m); err != nil {
+			return err
+		}
+		packet = m.GetPacket()
+	}
+
+	if packet == nil {
+		return nil
+	}
+	return validatePacket(packet.GetData())
+}
+
+// validatePacket checks that the messages of a packet are well formed.
+func validatePacket(data []byte) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panicError(v)
+		}
+	}()
+
+	r := newReader(data)
+	for r.remBytes() > 0 {
+		t := int32(r.readUBitVar())
+		buf := r.readBytes(r.readVarUint32())
+		if err := validateProto(buf); err != nil {
+			return _errorf("%s: %s", packetMessageName(t), err)
+		}
+	}
+	return nil
+}
+
+// validateProto checks that a buffer holds a well formed protobuf message,
+// without knowing its type.
+func validateProto(buf []byte) error {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		buf = buf[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, buf)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// AllowTruncated controls whether Start ends without an error when the
+// replay ends in the middle of an outer message, or with one which fails to
+// decompress or unmarshal, in which case Truncated reports it and Tick is
+// that of the last intact message. It must be called before Start.
+func (p *Parser) AllowTruncated(allow bool) {
+	p.allowTruncated = allow
+}
+
+// Truncated returns whether the replay parsed by Start ended before its
+// CDemoStop, so that the state of the parser is that of the last intact
+// message rather than of the end of the game.
+func (p *Parser) Truncated() bool {
+	return p.truncated
+}
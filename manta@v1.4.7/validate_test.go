@@ -0,0 +1,228 @@
+package manta
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// _validate_replay builds a complete replay, ending with a compressed file
+// info after the stop.
+func _validate_replay(t *testing.T) []byte {
+	b := NewReplayBuilder()
+	_builder_classes(b)
+	b.Create(0, "CDOTAGamerulesProxy", nil)
+	b.Create(10, "CDOTA_Unit_Hero_Juggernaut", nil)
+	for i := 0; i < 10; i++ {
+		b.Advance(30)
+		b.Update(0, map[string]interface{}{"m_pGameRules.m_fGameTime": float32(i)})
+		b.Update(10, map[string]interface{}{"m_iHealth": 600 - i*10})
+	}
+	data, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := proto.Marshal(&dota.CDemoFileInfo{PlaybackTicks: proto.Int32(300)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBuffer(data)
+	if err := writeOuterMessage(out, int32(dota.EDemoCommands_DEM_FileInfo), 300, buf, true); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _validate_replay(t)
+	v, err := Validate(bytes.NewReader(data))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.True(v.Valid())
+	assert.Nil(v.Err)
+	assert.True(v.Stop)
+	assert.True(v.FileInfo)
+	assert.False(v.Truncated)
+	assert.Equal(len(_outer_messages(t, data)), v.Messages)
+	assert.Equal(int64(len(data)), v.Offset)
+	assert.Equal(uint32(300), v.LastGoodTick)
+
+	_, err = Validate(bytes.NewReader([]byte("not a replay at all")))
+	assert.NotNil(err)
+}
+
+func TestValidateTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _validate_replay(t)
+	messages := 0
+	for n := 16; n < len(data); n++ {
+		v, err := Validate(bytes.NewReader(data[:n]))
+		if !assert.Nil(err, "cut at %d", n) {
+			return
+		}
+		assert.False(v.Valid(), "cut at %d", n)
+		assert.True(v.Offset <= int64(n), "cut at %d", n)
+		assert.True(v.Messages >= messages, "cut at %d", n)
+		messages = v.Messages
+
+		// A cut between messages leaves nothing partially read.
+		if v.Offset == int64(n) {
+			assert.Nil(v.Err, "cut at %d", n)
+		} else if assert.NotNil(v.Err, "cut at %d", n) {
+			assert.Contains(v.Err.Error(), io.ErrUnexpectedEOF.Error(), "cut at %d", n)
+		}
+		assert.Equal(!v.Stop, v.Truncated, "cut at %d", n)
+
+		// The parser reaches the last intact message, then ends as the
+		// validation does.
+		p, err := NewParser(data[:n])
+		if !assert.Nil(err) {
+			return
+		}
+		p.AllowTruncated(true)
+		if !assert.Nil(p.Start(), "cut at %d", n) {
+			return
+		}
+		assert.Equal(v.Truncated, p.Truncated(), "cut at %d", n)
+		if v.Messages > 0 {
+			assert.Equal(v.LastGoodTick, p.Tick, "cut at %d", n)
+		}
+	}
+}
+
+func TestValidateCorrupt(t *testing.T) {
+	assert := assert.New(t)
+
+	// The file info is the last message, its snappy block is broken.
+	data := _validate_replay(t)
+	data[len(data)-3] ^= 0xff
+	data[len(data)-2] ^= 0xff
+	v, err := Validate(bytes.NewReader(data))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.NotNil(v.Err)
+	assert.True(v.Stop)
+	assert.False(v.FileInfo)
+	assert.False(v.Truncated)
+	assert.False(v.Valid())
+
+	// A packet holding a message which is not a protobuf.
+	w := newWriter()
+	w.writeUBitVar(uint32(dota.EDotaUserMessages_DOTA_UM_ChatMessage))
+	w.writeVarUint32(2)
+	w.writeBytes([]byte{0x12, 0x7f})
+	packet, err := proto.Marshal(&dota.CDemoPacket{Data: w.bytes()})
+	if !assert.Nil(err) {
+		return
+	}
+	buf := &bytes.Buffer{}
+	buf.Write(magicSource2)
+	buf.Write(make([]byte, 8))
+	writeOuterMessage(buf, int32(dota.EDemoCommands_DEM_Packet), 7, packet, false)
+
+	v, err = Validate(bytes.NewReader(buf.Bytes()))
+	if !assert.Nil(err) {
+		return
+	}
+	if assert.NotNil(v.Err) {
+		assert.Contains(v.Err.Error(), "tick 7 DEM_Packet: DOTA_UM_ChatMessage")
+	}
+	assert.Equal(0, v.Messages)
+	assert.Equal(int64(16), v.Offset)
+	assert.True(v.Truncated)
+}
+
+func TestParserTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	data := _validate_replay(t)
+	p, err := NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Nil(p.Start())
+	assert.False(p.Truncated())
+
+	// Without AllowTruncated a replay cut in the middle of a message fails,
+	// as it always did. The cut is in the middle of the packets.
+	messages := _outer_messages(t, data)
+	n := 16 + 3
+	for _, m := range messages[:len(messages)-5] {
+		n += len(m)
+	}
+	cut := data[:n]
+	p, err = NewParser(cut)
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(io.ErrUnexpectedEOF, p.Start())
+	assert.True(p.Truncated())
+
+	p, err = NewParser(cut)
+	if !assert.Nil(err) {
+		return
+	}
+	p.AllowTruncated(true)
+	hero := 0
+	p.OnEntity(func(e *Entity, op EntityOp) error {
+		if e.GetClassName() == "CDOTA_Unit_Hero_Juggernaut" {
+			hero++
+		}
+		return nil
+	})
+	assert.Nil(p.Start())
+	assert.True(p.Truncated())
+	assert.True(hero > 0)
+	assert.True(p.Tick < 300)
+
+	// A replay ending with garbage instead of a partial message is truncated
+	// too, whether it fails to decompress or to unmarshal.
+	good := bytes.NewBuffer(nil)
+	good.Write(data[:16])
+	for _, m := range messages[:len(messages)-5] {
+		good.Write(m)
+	}
+	p, err = NewParser(good.Bytes())
+	if !assert.Nil(err) {
+		return
+	}
+	p.AllowTruncated(true)
+	assert.Nil(p.Start())
+	last := p.Tick
+
+	garbage := []byte{0xff, 0xff, 0xff, 0xff}
+	compressed := append([]byte{}, good.Bytes()...)
+	compressed = append(compressed, byte(dota.EDemoCommands_DEM_Packet|dota.EDemoCommands_DEM_IsCompressed), 0xe8, 0x07, byte(len(garbage)))
+	compressed = append(compressed, garbage...)
+	uncompressed := bytes.NewBuffer(append([]byte{}, good.Bytes()...))
+	if err := writeOuterMessage(uncompressed, int32(dota.EDemoCommands_DEM_Packet), 1000, garbage, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{compressed, uncompressed.Bytes()} {
+		p, err = NewParser(data)
+		if !assert.Nil(err) {
+			return
+		}
+		assert.NotNil(p.Start())
+
+		p, err = NewParser(data)
+		if !assert.Nil(err) {
+			return
+		}
+		p.AllowTruncated(true)
+		assert.Nil(p.Start())
+		assert.True(p.Truncated())
+		assert.Equal(last, p.Tick)
+	}
+}
@@ -0,0 +1,170 @@
+package manta
+
+// writer performs write operations against a growing buffer, producing data
+// in the format read by reader.
+type writer struct {
+	buf      []byte
+	bitVal   uint64 // value of the pending bits not written to buf yet
+	bitCount uint32 // number of pending bits not written to buf yet
+}
+
+// newWriter creates a new writer with an empty buffer
+func newWriter() *writer {
+	return &writer{}
+}
+
+// bitLen returns the number of bits written
+func (w *writer) bitLen() uint32 {
+	return uint32(len(w.buf))*8 + w.bitCount
+}
+
+// bytes returns the written data, padding the last byte with zero bits
+func (w *writer) bytes() []byte {
+	buf := w.buf
+	if w.bitCount > 0 {
+		buf = append(buf[:len(buf):len(buf)], byte(w.bitVal))
+	}
+	return buf
+}
+
+// writeBits writes the given number of low bits of x, at most 32
+func (w *writer) writeBits(x uint32, n uint32) {
+	w.bitVal |= (uint64(x) & ((1 << n) - 1)) << w.bitCount
+	w.bitCount += n
+
+	for w.bitCount >= 8 {
+		w.buf = append(w.buf, byte(w.bitVal))
+		w.bitVal >>= 8
+		w.bitCount -= 8
+	}
+}
+
+// writeBoolean writes a single bit
+func (w *writer) writeBoolean(b bool) {
+	if b {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// writeByte writes a single byte
+func (w *writer) writeByte(b byte) {
+	w.writeBits(uint32(b), 8)
+}
+
+// writeBytes writes the given bytes
+func (w *writer) writeBytes(buf []byte) {
+	if w.bitCount == 0 {
+		w.buf = append(w.buf, buf...)
+		return
+	}
+	for _, b := range buf {
+		w.writeByte(b)
+	}
+}
+
+// writeBitsFrom writes n bits of buf, as read by readBits from its start
+func (w *writer) writeBitsFrom(buf []byte, n uint32) {
+	for i := 0; n >= 8; i, n = i+1, n-8 {
+		w.writeByte(buf[i])
+	}
+	if n > 0 {
+		w.writeBits(uint32(buf[len(buf)-1]), n)
+	}
+}
+
+// writeVarUint32 writes an unsigned 32-bit varint
+func (w *writer) writeVarUint32(x uint32) {
+	for x >= 0x80 {
+		w.writeByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	w.writeByte(byte(x))
+}
+
+// writeVarUint64 writes an unsigned 64-bit varint
+func (w *writer) writeVarUint64(x uint64) {
+	for x >= 0x80 {
+		w.writeByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	w.writeByte(byte(x))
+}
+
+// writeLeUint64 writes a little endian uint64
+func (w *writer) writeLeUint64(x uint64) {
+	for i := 0; i < 8; i++ {
+		w.writeByte(byte(x >> uint(8*i)))
+	}
+}
+
+// writeVarInt32 writes a signed 32-bit varint
+func (w *writer) writeVarInt32(x int32) {
+	ux := uint32(x) << 1
+	if x < 0 {
+		ux = ^ux
+	}
+	w.writeVarUint32(ux)
+}
+
+// writeUBitVar writes a variable length uint32 as read by readUBitVar
+func (w *writer) writeUBitVar(x uint32) {
+	switch {
+	case x < 1<<4:
+		w.writeBits(x, 6)
+	case x < 1<<8:
+		w.writeBits(x&15|16, 6)
+		w.writeBits(x>>4, 4)
+	case x < 1<<12:
+		w.writeBits(x&15|32, 6)
+		w.writeBits(x>>4, 8)
+	default:
+		w.writeBits(x&15|48, 6)
+		w.writeBits(x>>4, 28)
+	}
+}
+
+// writeUBitVarFP writes a variable length uint32 using field path encoding
+func (w *writer) writeUBitVarFP(x uint32) {
+	switch {
+	case x < 1<<2:
+		w.writeBoolean(true)
+		w.writeBits(x, 2)
+	case x < 1<<4:
+		w.writeBits(2, 2)
+		w.writeBits(x, 4)
+	case x < 1<<10:
+		w.writeBits(4, 3)
+		w.writeBits(x, 10)
+	case x < 1<<17:
+		w.writeBits(8, 4)
+		w.writeBits(x, 17)
+	default:
+		w.writeBits(0, 4)
+		w.writeBits(x, 31)
+	}
+}
+
+// writeString writes a null terminated string
+func (w *writer) writeString(s string) {
+	w.writeBytes([]byte(s))
+	w.writeByte(0)
+}
+
+// readBitRange returns the bits of buf between the given bit offsets, packed
+// so that writeBitsFrom writes them back.
+func readBitRange(buf []byte, start, end uint32) []byte {
+	r := newReader(buf[start/8:])
+	r.readBits(start % 8)
+
+	n := end - start
+	out := make([]byte, 0, (n+7)/8)
+	for ; n >= 8; n -= 8 {
+		out = append(out, byte(r.readBits(8)))
+	}
+	if n > 0 {
+		out = append(out, byte(r.readBits(n)))
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package match
+
+import (
+	"fmt"
+
+	"github.com/dotabuff/manta"
+)
+
+// Economy is the gold and experience of a player at the current tick. Gold
+// and net worth are networked by the team data entities (CDOTA_DataRadiant,
+// CDOTA_DataDire), indexed by the team slot of the player, and the level by
+// the player resource.
+type Economy struct {
+	ReliableGold   int32 `json:"reliable_gold"`
+	UnreliableGold int32 `json:"unreliable_gold"`
+	NetWorth       int32 `json:"net_worth"`
+	EarnedGold     int32 `json:"earned_gold"`
+	XP             int32 `json:"xp"`
+	Level          int32 `json:"level"`
+	LastHits       int32 `json:"last_hits"`
+	Denies         int32 `json:"denies"`
+
+	// GoldLostToDeath and GoldSpentOnBuybacks are totals since the start
+	// of the game.
+	GoldLostToDeath     int32 `json:"gold_lost_to_death"`
+	GoldSpentOnBuybacks int32 `json:"gold_spent_on_buybacks"`
+
+	// BuybackCooldown is the server time at which buyback is available
+	// again, zero if it never was used.
+	BuybackCooldown float32 `json:"buyback_cooldown,omitempty"`
+}
+
+// Gold returns the total gold of the player.
+func (e Economy) Gold() int32 {
+	return e.ReliableGold + e.UnreliableGold
+}
+
+// Economy returns the economy of the player with the given id, or false if
+// the player or the data entity of their team is unknown.
+func (m *Match) Economy(id int32) (Economy, bool) {
+	pl := m.players[id]
+	if pl == nil {
+		return Economy{}, false
+	}
+	index, ok := m.teamData[pl.Team]
+	if !ok {
+		return Economy{}, false
+	}
+	data := m.p.FindEntity(index)
+	if data == nil {
+		return Economy{}, false
+	}
+
+	slot := pl.slot
+	if slot < 0 {
+		// Before the team slot is networked players fill their teams in
+		// order of ids, five per team.
+		slot = id % 5
+	}
+
+	var ec Economy
+	prefix := fmt.Sprintf("m_vecDataTeam.%04d.", slot)
+	for field, v := range map[string]*int32{
+		"m_iReliableGold":        &ec.ReliableGold,
+		"m_iUnreliableGold":      &ec.UnreliableGold,
+		"m_iNetWorth":            &ec.NetWorth,
+		"m_iTotalEarnedGold":     &ec.EarnedGold,
+		"m_iTotalEarnedXP":       &ec.XP,
+		"m_iLastHitCount":        &ec.LastHits,
+		"m_iDenyCount":           &ec.Denies,
+		"m_iGoldLostToDeath":     &ec.GoldLostToDeath,
+		"m_iGoldSpentOnBuybacks": &ec.GoldSpentOnBuybacks,
+	} {
+		if x, ok := intValue(data.Get(prefix + field)); ok {
+			*v = int32(x)
+		}
+	}
+
+	if res := m.p.FindEntity(m.playerResource); res != nil && m.playerResource >= 0 {
+		team := fmt.Sprintf("m_vecPlayerTeamData.%04d.", id)
+		if x, ok := intValue(res.Get(team + "m_iLevel")); ok {
+			ec.Level = int32(x)
+		}
+		if x, ok := res.GetFloat32(team + "m_flBuybackCooldownTime"); ok {
+			ec.BuybackCooldown = x
+		}
+	}
+	return ec, true
+}
+
+// onTeamData records the index of the data entity of a team.
+func (m *Match) onTeamData(e *manta.Entity, team int32) {
+	m.teamData[team] = e.GetIndex()
+}
@@ -0,0 +1,68 @@
+package match
+
+import (
+	"fmt"
+
+	"github.com/dotabuff/manta"
+)
+
+// Slots of the m_hItems array of heroes.
+const (
+	SlotInventory = 0  // the six main slots, 0 to 5
+	SlotBackpack  = 6  // the three backpack slots, 6 to 8
+	SlotTeleport  = 15 // the teleport scroll
+	SlotNeutral   = 16 // the neutral item
+)
+
+// MaxAbilities is the length of the m_hAbilities array of heroes.
+const MaxAbilities = 35
+
+// PowerTreadsClass is the entity class of Power Treads.
+const PowerTreadsClass = "CDOTA_Item_PowerTreads"
+
+var treadsStats = []string{"str", "int", "agi"}
+
+// Item returns the item entity in a slot of a hero, or nil if it is empty.
+func Item(p *manta.Parser, hero *manta.Entity, slot int) *manta.Entity {
+	h, ok := hero.GetUint32(fmt.Sprintf("m_hItems.%04d", slot))
+	if !ok || h == NullHandle {
+		return nil
+	}
+	return p.FindEntityByHandle(uint64(h))
+}
+
+// Ability returns the ability entity in a slot of the m_hAbilities array of a
+// hero, or nil if it is empty.
+func Ability(p *manta.Parser, hero *manta.Entity, slot int) *manta.Entity {
+	h, ok := hero.GetUint32(fmt.Sprintf("m_hAbilities.%04d", slot))
+	if !ok || h == NullHandle {
+		return nil
+	}
+	return p.FindEntityByHandle(uint64(h))
+}
+
+// ItemName returns the item name (item_*) of an item entity, or the ability
+// name of an ability entity, falling back to its class name.
+func ItemName(p *manta.Parser, e *manta.Entity) string {
+	var index int32 = -1
+	switch v := e.Get("m_pEntity.m_nameStringableIndex").(type) {
+	case int32:
+		index = v
+	case uint32:
+		index = int32(v)
+	}
+	if name, ok := p.LookupStringByIndex("EntityNames", index); ok && index >= 0 {
+		return name
+	}
+	return e.GetClassName()
+}
+
+// PowerTreadsStat returns the attribute (str, int or agi) a Power Treads item
+// entity is switched to.
+func PowerTreadsStat(e *manta.Entity) (string, bool) {
+	v, ok := e.GetInt32("m_iStat")
+	if !ok || v < 0 {
+		return "", false
+	}
+	return treadsStats[v%int32(len(treadsStats))], true
+}
@@ -0,0 +1,427 @@
+// Package match tracks the match-wide state most analyzers need while a
+// replay is parsed: who the players are, which hero each of them controls and
+// what the game clock reads at the current tick.
+package match
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// NullHandle is the value of an entity handle field which points nowhere.
+const NullHandle = 16777215
+
+// Teams as networked in m_iPlayerTeam / m_iTeamNum.
+const (
+	TeamRadiant = 2
+	TeamDire    = 3
+)
+
+const maxPlayers = 24
+
+// Player is a single player slot of the match.
+type Player struct {
+	ID      int32  `json:"id"`
+	Name    string `json:"name"`
+	SteamID uint64 `json:"steam_id,omitempty"`
+	Team    int32  `json:"team"`
+	HeroID  int32  `json:"hero_id,omitempty"`
+
+	// Hero is the npc name of the hero (npc_dota_hero_*) once it has been
+	// seen in the combat log or looked up with HeroName, HeroClass the
+	// entity class of the hero.
+	Hero       string `json:"hero,omitempty"`
+	HeroClass  string `json:"hero_class,omitempty"`
+	HeroHandle uint64 `json:"-"`
+
+	// slot is the index of the player within their team, or -1.
+	slot int32
+}
+
+// Match follows CDOTA_PlayerResource, the game rules proxy and the hero
+// entities of a parser. Create it with New before calling Start.
+type Match struct {
+	p *manta.Parser
+
+	players      map[int32]*Player
+	byHeroHandle map[uint64]*Player
+	byHeroNPC    map[string]*Player
+
+	// teamData and playerResource are the indexes of the entities holding
+	// the economy of players, playerResource is -1 until it is seen.
+	teamData       map[int32]int32
+	playerResource int32
+
+	tickInterval float32
+
+	gameState     int32
+	gameStartTime float32
+	gameEndTime   float32
+	clockTick     uint32
+	clockTime     float32
+	clockValid    bool
+	paused        bool
+}
+
+// New returns a Match which keeps itself up to date from the given parser.
+func New(p *manta.Parser) *Match {
+	m := &Match{
+		p:              p,
+		players:        make(map[int32]*Player),
+		byHeroHandle:   make(map[uint64]*Player),
+		byHeroNPC:      make(map[string]*Player),
+		teamData:       make(map[int32]int32),
+		playerResource: -1,
+		tickInterval:   1.0 / 30,
+	}
+
+	p.Callbacks.OnCSVCMsg_ServerInfo(func(msg *dota.CSVCMsg_ServerInfo) error {
+		if ti := msg.GetTickInterval(); ti > 0 {
+			m.tickInterval = ti
+		}
+		return nil
+	})
+
+	p.Callbacks.OnCSVCMsg_SetPause(func(msg *dota.CSVCMsg_SetPause) error {
+		m.advanceClock()
+		m.paused = msg.GetPaused()
+		return nil
+	})
+
+	p.OnEntity(m.onEntity)
+
+	return m
+}
+
+func (m *Match) onEntity(e *manta.Entity, op manta.EntityOp) error {
+	if op.Flag(manta.EntityOpDeleted) {
+		return nil
+	}
+
+	cn := e.GetClassName()
+	switch {
+	case cn == "CDOTAGamerulesProxy":
+		m.onGameRules(e)
+	case cn == "CDOTA_PlayerResource":
+		m.playerResource = e.GetIndex()
+		m.onPlayerResource(e)
+	case cn == "CDOTA_DataRadiant":
+		m.onTeamData(e, TeamRadiant)
+	case cn == "CDOTA_DataDire":
+		m.onTeamData(e, TeamDire)
+	case IsHeroClass(cn):
+		m.onHero(e)
+	}
+	return nil
+}
+
+func (m *Match) onGameRules(e *manta.Entity) {
+	if v, ok := e.GetFloat32("m_pGameRules.m_fGameTime"); ok && v > 0 {
+		m.clockTick = m.p.Tick
+		m.clockTime = v
+		m.clockValid = true
+	}
+	if v, ok := e.GetFloat32("m_pGameRules.m_flGameStartTime"); ok && v > 0 {
+		m.gameStartTime = v
+	}
+	if v, ok := e.GetFloat32("m_pGameRules.m_flGameEndTime"); ok && v > 0 {
+		m.gameEndTime = v
+	}
+	if v, ok := intValue(e.Get("m_pGameRules.m_nGameState")); ok {
+		m.gameState = int32(v)
+	}
+	if v, ok := e.GetBool("m_pGameRules.m_bGamePaused"); ok {
+		m.paused = v
+	}
+}
+
+func (m *Match) onPlayerResource(e *manta.Entity) {
+	for i := int32(0); i < maxPlayers; i++ {
+		data := fmt.Sprintf("m_vecPlayerData.%04d.", i)
+		team := fmt.Sprintf("m_vecPlayerTeamData.%04d.", i)
+
+		name, ok := e.GetString(data + "m_iszPlayerName")
+		if !ok {
+			continue
+		}
+
+		pl := m.player(i)
+		pl.Name = name
+		if v, ok := e.GetUint64(data + "m_iPlayerSteamID"); ok {
+			pl.SteamID = v
+		}
+		if v, ok := intValue(e.Get(data + "m_iPlayerTeam")); ok {
+			pl.Team = int32(v)
+		}
+		if v, ok := intValue(e.Get(team + "m_iTeamSlot")); ok {
+			pl.slot = int32(v)
+		}
+		if v, ok := intValue(e.Get(team + "m_nSelectedHeroID")); ok {
+			pl.HeroID = int32(v)
+		}
+		if v, ok := e.GetUint32(team + "m_hSelectedHero"); ok && v != NullHandle {
+			pl.HeroHandle = uint64(v)
+			m.byHeroHandle[pl.HeroHandle] = pl
+		}
+	}
+}
+
+func (m *Match) onHero(e *manta.Entity) {
+	// Illusions and clones share the class of the real hero but are never
+	// the selected hero of a player.
+	if v, ok := e.GetUint32("m_hReplicatingOtherHeroModel"); ok && v != NullHandle {
+		return
+	}
+
+	id, ok := intValue(e.Get("m_iPlayerID"))
+	if !ok || id < 0 || id >= maxPlayers {
+		return
+	}
+
+	pl := m.player(int32(id))
+	if pl.HeroClass == "" {
+		pl.HeroClass = e.GetClassName()
+	}
+	if pl.HeroHandle == 0 {
+		pl.HeroHandle = e.GetHandle()
+		m.byHeroHandle[pl.HeroHandle] = pl
+	}
+}
+
+func (m *Match) player(id int32) *Player {
+	pl, ok := m.players[id]
+	if !ok {
+		pl = &Player{ID: id, slot: -1}
+		m.players[id] = pl
+	}
+	return pl
+}
+
+// Players returns the known players ordered by id.
+func (m *Match) Players() []*Player {
+	players := make([]*Player, 0, len(m.players))
+	for _, pl := range m.players {
+		if pl.Name == "" && pl.HeroClass == "" {
+			continue
+		}
+		players = append(players, pl)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players
+}
+
+// Player returns the player with the given id, or nil.
+func (m *Match) Player(id int32) *Player {
+	return m.players[id]
+}
+
+// PlayerForController returns the player of a player controller entity
+// index, as found in chat messages, or nil. Controllers are allocated right
+// after the world entity, one for each player slot.
+func (m *Match) PlayerForController(index int32) *Player {
+	if index < 1 || index > maxPlayers {
+		return nil
+	}
+	return m.players[index-1]
+}
+
+// PlayerForHero returns the player controlling the hero with the given npc
+// name (as found in the CombatLogNames string table), or nil. Npc names are
+// matched with the classes of the hero entities through HeroKey.
+func (m *Match) PlayerForHero(npc string) *Player {
+	if pl, ok := m.byHeroNPC[npc]; ok {
+		return pl
+	}
+
+	key := HeroKey(npc)
+	if key == "" || !IsHeroNPC(npc) {
+		return nil
+	}
+	for _, pl := range m.players {
+		if HeroKey(pl.HeroClass) == key {
+			pl.Hero = npc
+			m.byHeroNPC[npc] = pl
+			return pl
+		}
+	}
+	return nil
+}
+
+// HeroName returns the npc name of the hero of a player. Heroes which did
+// not show up in the combat log yet are looked up in the CombatLogNames
+// string table by their class, which is returned if that fails too.
+func (m *Match) HeroName(pl *Player) string {
+	if pl.Hero != "" || pl.HeroClass == "" {
+		return pl.Hero
+	}
+	key := HeroKey(pl.HeroClass)
+	entries, _ := m.p.StringTableEntries("CombatLogNames")
+	for _, e := range entries {
+		if IsHeroNPC(e.Key) && HeroKey(e.Key) == key {
+			pl.Hero = e.Key
+			m.byHeroNPC[e.Key] = pl
+			return pl.Hero
+		}
+	}
+	return pl.HeroClass
+}
+
+// PlayerForHandle returns the player owning the entity with the given
+// handle. The entity is either the hero itself or something whose chain of
+// m_hOwnerEntity handles leads to it (items, wards, summons).
+func (m *Match) PlayerForHandle(handle uint64) *Player {
+	for depth := 0; depth < 4 && handle != NullHandle; depth++ {
+		if pl, ok := m.byHeroHandle[handle]; ok {
+			return pl
+		}
+
+		e := m.p.FindEntityByHandle(handle)
+		if e == nil {
+			return nil
+		}
+		if IsHeroClass(e.GetClassName()) {
+			if id, ok := intValue(e.Get("m_iPlayerID")); ok {
+				return m.players[int32(id)]
+			}
+		}
+
+		owner, ok := e.GetUint32("m_hOwnerEntity")
+		if !ok {
+			return nil
+		}
+		handle = uint64(owner)
+	}
+	return nil
+}
+
+// PlayerForEntity returns the player owning the given entity, as
+// PlayerForHandle does.
+func (m *Match) PlayerForEntity(e *manta.Entity) *Player {
+	if e == nil {
+		return nil
+	}
+	return m.PlayerForHandle(e.GetHandle())
+}
+
+// CombatLogName resolves an index into the CombatLogNames string table.
+func (m *Match) CombatLogName(index uint32) string {
+	name, _ := m.p.LookupStringByIndex("CombatLogNames", int32(index))
+	return name
+}
+
+// Coverage is how much of a replay was parsed: whether it was truncated
+// before its end and the tick of its last intact message. Reports embed it
+// so that a cut replay can be told apart from a short game.
+type Coverage struct {
+	Truncated bool   `json:"truncated"`
+	LastTick  uint32 `json:"last_tick"`
+}
+
+// Coverage returns the coverage of the replay parsed so far.
+func (m *Match) Coverage() Coverage {
+	return Coverage{Truncated: m.p.Truncated(), LastTick: m.p.Tick}
+}
+
+// TickInterval returns the duration of a single tick in seconds.
+func (m *Match) TickInterval() float32 {
+	return m.tickInterval
+}
+
+// GameState returns the current DOTA_GameState of the match.
+func (m *Match) GameState() dota.DOTA_GameState {
+	return dota.DOTA_GameState(m.gameState)
+}
+
+// Paused reports whether the game is currently paused.
+func (m *Match) Paused() bool {
+	return m.paused
+}
+
+// GameStartTime returns the server time at which the horn sounded, or zero
+// if the game has not started (yet).
+func (m *Match) GameStartTime() float32 {
+	return m.gameStartTime
+}
+
+// GameEndTime returns the server time at which the ancient fell, or zero if
+// the game has not ended (yet).
+func (m *Match) GameEndTime() float32 {
+	return m.gameEndTime
+}
+
+// ServerTime returns the server time at the current tick. It's the last
+// networked game rules time, advanced by the ticks parsed since unless the
+// game is paused. Before the game rules are known it is derived from the tick.
+func (m *Match) ServerTime() float32 {
+	if !m.clockValid {
+		return float32(m.p.Tick) * m.tickInterval
+	}
+	if m.paused || m.p.Tick < m.clockTick {
+		return m.clockTime
+	}
+	return m.clockTime + float32(m.p.Tick-m.clockTick)*m.tickInterval
+}
+
+// GameTime converts a server time (such as the one returned by ServerTime or
+// a combat log timestamp) into the in-game clock, which is negative before
+// the horn. Before the start time is known the server time is returned.
+func (m *Match) GameTime(serverTime float32) float32 {
+	if m.gameStartTime == 0 {
+		return serverTime
+	}
+	return serverTime - m.gameStartTime
+}
+
+// advanceClock pins the clock to the current tick, so that time passed so
+// far is kept when the game becomes paused.
+func (m *Match) advanceClock() {
+	if m.clockValid {
+		m.clockTime = m.ServerTime()
+		m.clockTick = m.p.Tick
+	}
+}
+
+// Position returns the world position of an entity from its body component.
+func Position(e *manta.Entity) (x, y float32, ok bool) {
+	cx, ok1 := e.GetUint64("CBodyComponent.m_cellX")
+	cy, ok2 := e.GetUint64("CBodyComponent.m_cellY")
+	vx, ok3 := e.GetFloat32("CBodyComponent.m_vecX")
+	vy, ok4 := e.GetFloat32("CBodyComponent.m_vecY")
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return 0, 0, false
+	}
+	return float32(cx)*cellWidth + vx - mapOffset, float32(cy)*cellWidth + vy - mapOffset, true
+}
+
+const (
+	cellWidth = 128
+	mapOffset = 16384
+)
+
+// Int returns an integer field of an entity regardless of its networked
+// type. Small unsigned fields such as m_lifeState decode as uint64, so that
+// GetInt32 never finds them.
+func Int(e *manta.Entity, name string) (int64, bool) {
+	return intValue(e.Get(name))
+}
+
+// intValue returns integer entity values regardless of their networked type.
+func intValue(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package match
+
+import (
+	"fmt"
+	"strings"
+)
+
+const heroNPCPrefix = "npc_dota_hero_"
+
+// IsHeroNPC reports whether an npc name is the name of a hero.
+func IsHeroNPC(npc string) bool {
+	return strings.HasPrefix(npc, heroNPCPrefix)
+}
+
+const heroClassPrefix = "CDOTA_Unit_Hero_"
+
+// heroSummonClasses are entity classes with the hero class prefix which are
+// summons rather than heroes.
+var heroSummonClasses = map[string]bool{
+	"CDOTA_Unit_Hero_Beastmaster_Beasts": true,
+	"CDOTA_Unit_Hero_Beastmaster_Boar":   true,
+	"CDOTA_Unit_Hero_Beastmaster_Hawk":   true,
+}
+
+// IsHeroClass reports whether an entity class is the class of a hero, which
+// excludes the summons sharing the hero class prefix.
+func IsHeroClass(class string) bool {
+	return strings.HasPrefix(class, heroClassPrefix) && !heroSummonClasses[class]
+}
+
+// HeroKey returns the key the npc name and the entity class of a hero have
+// in common: the hero part of the name, lowercased, with underscores
+// removed. Class names do not follow npc names in their use of case and
+// underscores, npc_dota_hero_antimage is CDOTA_Unit_Hero_AntiMage and
+// npc_dota_hero_life_stealer is CDOTA_Unit_Hero_Life_Stealer, but both have
+// the same key. It returns "" for names which are neither.
+func HeroKey(name string) string {
+	switch {
+	case IsHeroNPC(name):
+		name = strings.TrimPrefix(name, heroNPCPrefix)
+	case IsHeroClass(name):
+		name = strings.TrimPrefix(name, heroClassPrefix)
+	default:
+		return ""
+	}
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// IsBuildingNPC reports whether an npc name belongs to a building which can be
+// destroyed: towers, barracks, the ancient and other team structures.
+func IsBuildingNPC(npc string) bool {
+	for _, s := range []string{"_tower", "_rax_", "_fort", "_healers", "_fillers", "_effigy", "_watch_tower"} {
+		if strings.Contains(npc, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRoshanNPC reports whether an npc name is Roshan.
+func IsRoshanNPC(npc string) bool {
+	return npc == "npc_dota_roshan"
+}
+
+var runeNames = []string{
+	"double_damage",
+	"haste",
+	"illusion",
+	"invisibility",
+	"regeneration",
+	"bounty",
+	"arcane",
+	"water",
+	"wisdom",
+	"shield",
+}
+
+// RuneName returns the name of a rune type as found in the combat log.
+func RuneName(t uint32) string {
+	if int(t) < len(runeNames) {
+		return runeNames[t]
+	}
+	return "unknown"
+}
+
+var damageTypeNames = map[uint32]string{
+	1: "physical",
+	2: "magical",
+	4: "pure",
+}
+
+// DamageTypeName returns the name of the damage type of a combat log entry:
+// physical, magical or pure, or "" for other types such as HP removal.
+func DamageTypeName(t uint32) string {
+	return damageTypeNames[t]
+}
+
+// FormatGameTime formats a game time in seconds as the in-game clock does,
+// for example -0:45 or 12:03.
+func FormatGameTime(t float32) string {
+	sign := ""
+	if t < 0 {
+		sign = "-"
+		t = -t
+	}
+	s := int(t)
+	return fmt.Sprintf("%s%d:%02d", sign, s/60, s%60)
+}
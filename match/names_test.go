@@ -0,0 +1,39 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeroKey(t *testing.T) {
+	assert := assert.New(t)
+
+	for npc, class := range map[string]string{
+		"npc_dota_hero_axe":                 "CDOTA_Unit_Hero_Axe",
+		"npc_dota_hero_antimage":            "CDOTA_Unit_Hero_AntiMage",
+		"npc_dota_hero_queenofpain":         "CDOTA_Unit_Hero_QueenOfPain",
+		"npc_dota_hero_vengefulspirit":      "CDOTA_Unit_Hero_VengefulSpirit",
+		"npc_dota_hero_life_stealer":        "CDOTA_Unit_Hero_Life_Stealer",
+		"npc_dota_hero_obsidian_destroyer":  "CDOTA_Unit_Hero_Obsidian_Destroyer",
+		"npc_dota_hero_keeper_of_the_light": "CDOTA_Unit_Hero_KeeperOfTheLight",
+		"npc_dota_hero_skywrath_mage":       "CDOTA_Unit_Hero_Skywrath_Mage",
+		"npc_dota_hero_winter_wyvern":       "CDOTA_Unit_Hero_Winter_Wyvern",
+	} {
+		assert.NotEmpty(HeroKey(npc), npc)
+		assert.Equal(HeroKey(npc), HeroKey(class), npc)
+	}
+
+	assert.Equal("", HeroKey("npc_dota_creep_badguys_melee"))
+	assert.Equal("", HeroKey("CDOTA_Unit_Hero_Beastmaster_Boar"))
+}
+
+func TestIsHeroClass(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsHeroClass("CDOTA_Unit_Hero_Beastmaster"))
+	assert.True(IsHeroClass("CDOTA_Unit_Hero_Nyx_Assassin"))
+	assert.False(IsHeroClass("CDOTA_Unit_Hero_Beastmaster_Boar"))
+	assert.False(IsHeroClass("CDOTA_Unit_Hero_Beastmaster_Hawk"))
+	assert.False(IsHeroClass("CDOTA_Unit_SpiritBear"))
+}
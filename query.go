@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+
+	"dota2/corpus"
+	"dota2/query"
+
+	"github.com/dotabuff/manta"
+)
+
+// runQuery implements the `query` mode: it runs a query (see package query)
+// over a replay, or over every given replay and every .dem below the given
+// directories, and writes the resulting table.
+//
+//	go run . query [-format table|csv|json] [-o out.csv] "from events | where type = 'kill' | group unit, count()" [replays/...]
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table, csv or json")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of replays parsed concurrently")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	fs.Parse(args)
+
+	switch *format {
+	case "table", "csv", "json":
+	default:
+		log.Fatalf("unknown format %q, want table, csv or json", *format)
+	}
+	if fs.NArg() == 0 {
+		log.Fatal("usage: query [flags] <query> [replay.dem|dir ...]")
+	}
+	q, err := query.Parse(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paths := fs.Args()[1:]
+	if len(paths) == 0 {
+		paths = []string{"replay1.dem"}
+	}
+	files, err := corpus.Files(paths)
+	if err != nil {
+		log.Fatalf("corpus: %v", err)
+	}
+
+	r := corpus.New(map[string]corpus.Analyzer{
+		"query": func(p *manta.Parser) func() (interface{}, error) {
+			rows := q.Attach(p)
+			return func() (interface{}, error) { return rows() }
+		},
+	})
+	r.Workers = *workers
+
+	var batches []*query.Batch
+	err = r.Run(context.Background(), files, func(res corpus.Result) error {
+		if res.Error != "" {
+			log.Printf("%s: %s", res.File, res.Error)
+		}
+		if res.Truncated {
+			log.Printf("%s: replay is truncated, queried up to tick %d", res.File, res.Ticks)
+		}
+		if b, ok := res.Output["query"].(*query.Batch); ok {
+			// A single replay needs no replay column.
+			if len(files) > 1 {
+				b.Replay = res.File
+			}
+			batches = append(batches, b)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("corpus: %v", err)
+	}
+	// Replays complete in any order, rows are kept in the order of files.
+	sort.Slice(batches, func(i, j int) bool { return batches[i].Replay < batches[j].Replay })
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		fo, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("create %s: %v", *out, err)
+		}
+		defer fo.Close()
+		w = fo
+	}
+	if err := q.Result(batches...).Write(w, *format); err != nil {
+		log.Fatalf("write: %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"dota2/match"
+)
+
+// Values of expressions are nil, float64, string or bool. Entity and event
+// values are converted with value.
+
+// expr is an expression evaluated against a row.
+type expr interface {
+	eval(r *Row) interface{}
+}
+
+type literal struct {
+	v interface{}
+}
+
+func (l literal) eval(*Row) interface{} {
+	return l.v
+}
+
+// ident is a reference to a value of a row, see Row.Get.
+type ident struct {
+	name string
+}
+
+func (id *ident) eval(r *Row) interface{} {
+	return r.Get(id.name)
+}
+
+type not struct {
+	e expr
+}
+
+func (n *not) eval(r *Row) interface{} {
+	return !truthy(n.e.eval(r))
+}
+
+type binary struct {
+	op   string
+	l, r expr
+}
+
+func (b *binary) eval(r *Row) interface{} {
+	switch b.op {
+	case "and":
+		return truthy(b.l.eval(r)) && truthy(b.r.eval(r))
+	case "or":
+		return truthy(b.l.eval(r)) || truthy(b.r.eval(r))
+	}
+
+	l, rv := b.l.eval(r), b.r.eval(r)
+	switch b.op {
+	case "=":
+		return equal(l, rv)
+	case "!=":
+		return !equal(l, rv)
+	case "~":
+		if l == nil || rv == nil {
+			return false
+		}
+		return strings.Contains(strings.ToLower(format(l)), strings.ToLower(format(rv)))
+	case "<", "<=", ">", ">=":
+		c, ok := compare(l, rv)
+		if !ok {
+			return false
+		}
+		switch b.op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}
+
+	x, ok1 := l.(float64)
+	y, ok2 := rv.(float64)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	switch b.op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	default:
+		if y == 0 {
+			return nil
+		}
+		return x / y
+	}
+}
+
+// call is a call of a scalar function.
+type call struct {
+	name string
+	fn   func(args []interface{}) interface{}
+	args []expr
+}
+
+func (c *call) eval(r *Row) interface{} {
+	args := make([]interface{}, len(c.args))
+	for i, a := range c.args {
+		args[i] = a.eval(r)
+	}
+	return c.fn(args)
+}
+
+// functions are the scalar functions, by name.
+var functions = map[string]struct {
+	args int
+	fn   func(args []interface{}) interface{}
+}{
+	"lower": {1, func(args []interface{}) interface{} {
+		if args[0] == nil {
+			return nil
+		}
+		return strings.ToLower(format(args[0]))
+	}},
+	"abs": {1, func(args []interface{}) interface{} {
+		if x, ok := args[0].(float64); ok {
+			return math.Abs(x)
+		}
+		return nil
+	}},
+	"round": {1, func(args []interface{}) interface{} {
+		if x, ok := args[0].(float64); ok {
+			return math.Round(x)
+		}
+		return nil
+	}},
+	// clock formats a game time in seconds as m:ss.
+	"clock": {1, func(args []interface{}) interface{} {
+		if x, ok := args[0].(float64); ok {
+			return match.FormatGameTime(float32(x))
+		}
+		return nil
+	}},
+}
+
+// aggregate is a call of an aggregate function, only evaluated by groups.
+type aggregate struct {
+	name string
+	arg  expr
+}
+
+func (a *aggregate) eval(*Row) interface{} {
+	return nil
+}
+
+// aggregates are the aggregate functions: count() counts rows, count(x)
+// those where x is not null, and the others skip nulls.
+var aggregates = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true, "first": true, "last": true,
+}
+
+// hasAggregate reports whether an expression contains an aggregate.
+func hasAggregate(e expr) bool {
+	switch x := e.(type) {
+	case *aggregate:
+		return true
+	case *not:
+		return hasAggregate(x.e)
+	case *binary:
+		return hasAggregate(x.l) || hasAggregate(x.r)
+	case *call:
+		for _, a := range x.args {
+			if hasAggregate(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// idents calls fn with the name of every value an expression refers to.
+func idents(e expr, fn func(name string)) {
+	switch x := e.(type) {
+	case *ident:
+		fn(x.name)
+	case *aggregate:
+		if x.arg != nil {
+			idents(x.arg, fn)
+		}
+	case *not:
+		idents(x.e, fn)
+	case *binary:
+		idents(x.l, fn)
+		idents(x.r, fn)
+	case *call:
+		for _, a := range x.args {
+			idents(a, fn)
+		}
+	}
+}
+
+// accumulator computes an aggregate over the rows of a group.
+type accumulator struct {
+	agg   *aggregate
+	n     int
+	sum   float64
+	value interface{}
+}
+
+func (acc *accumulator) add(r *Row) {
+	var v interface{} = true
+	if acc.agg.arg != nil {
+		v = acc.agg.arg.eval(r)
+	}
+	if v == nil {
+		return
+	}
+
+	switch acc.agg.name {
+	case "sum", "avg":
+		x, ok := v.(float64)
+		if !ok {
+			return
+		}
+		acc.sum += x
+	case "min":
+		if c, ok := compare(v, acc.value); acc.n == 0 || (ok && c < 0) {
+			acc.value = v
+		}
+	case "max":
+		if c, ok := compare(v, acc.value); acc.n == 0 || (ok && c > 0) {
+			acc.value = v
+		}
+	case "first":
+		if acc.n == 0 {
+			acc.value = v
+		}
+	case "last":
+		acc.value = v
+	}
+	acc.n++
+}
+
+func (acc *accumulator) result() interface{} {
+	switch acc.agg.name {
+	case "count":
+		return float64(acc.n)
+	case "sum":
+		return acc.sum
+	case "avg":
+		if acc.n == 0 {
+			return nil
+		}
+		return acc.sum / float64(acc.n)
+	}
+	return acc.value
+}
+
+// value converts an entity or event value into a value of an expression.
+// Vectors are formatted as text.
+func value(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, float64, string, bool:
+		return x
+	case float32:
+		// Keep the shortest decimal of the float32, 0.3 rather than
+		// 0.30000001192092896.
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(x), 'g', -1, 32), 64)
+		return f
+	case int:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint32:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case []float32:
+		parts := make([]string, len(x))
+		for i, f := range x {
+			parts[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// truthy reports whether a value is true. Only true is.
+func truthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// equal compares two values. Numbers and text are different values, while
+// null only equals null.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	c, ok := compare(a, b)
+	return ok && c == 0
+}
+
+// compare orders two values of the same type. Values of different types, or
+// nulls, are not ordered.
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case bool:
+		y, ok := b.(bool)
+		switch {
+		case !ok:
+			return 0, false
+		case x == y:
+			return 0, true
+		case !x:
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// order orders any two values for sorting: nulls first, then numbers, text
+// and booleans.
+func order(a, b interface{}) int {
+	if c, ok := compare(a, b); ok {
+		return c
+	}
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case float64:
+			return 1
+		case string:
+			return 2
+		}
+		return 3
+	}
+	return rank(a) - rank(b)
+}
+
+// format formats a value as text. Whole numbers have no decimals.
+func format(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(x, 'g', 6, 64)
+	}
+	return fmt.Sprint(v)
+}
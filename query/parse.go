@@ -0,0 +1,540 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token of a query.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+// token is a lexical token of a query. pos and end are its byte offsets in
+// the query, text the unquoted value of strings.
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+	end  int
+}
+
+// twoCharOps are the operators of two characters, checked before single
+// characters.
+var twoCharOps = []string{"<=", ">=", "!=", "=="}
+
+// lex splits a query into tokens, ending with a tokenEOF.
+func lex(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+
+		case c == '\'' || c == '"':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(s) && rune(s[j]) != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return nil, fmt.Errorf("query: unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), pos: i, end: j + 1})
+			i = j + 1
+
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(rune(s[i+1]))):
+			j := i
+			for j < len(s) && (isDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("query: bad number %q at %d", s[i:j], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[i:j], num: n, pos: i, end: j})
+			i = j
+
+		case isIdentChar(c):
+			j := i
+			for j < len(s) {
+				r, n := utf8.DecodeRuneInString(s[j:])
+				if !isIdentChar(r) && r != '.' {
+					break
+				}
+				j += n
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[i:j], pos: i, end: j})
+			i = j
+
+		default:
+			op := s[i : i+size]
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(s[i:], two) {
+					op = two
+				}
+			}
+			if !strings.Contains("=<>!~+-*/(),|", op[:1]) || op == "!" {
+				return nil, fmt.Errorf("query: unexpected %q at %d", op, i)
+			}
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i, end: i + len(op)})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s), end: len(s)}), nil
+}
+
+func isIdentChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// isDigit reports whether c is an ASCII digit, the only ones numbers are
+// written with.
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser is a recursive descent parser over the tokens of a query.
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// back steps back over a token returned by next. The end of the query is
+// never consumed, so there is nothing to step back over.
+func (p *parser) back(t token) {
+	if t.kind != tokenEOF {
+		p.i--
+	}
+}
+
+// keyword reports whether the next token is the given keyword, consuming it
+// if so. Keywords are case insensitive.
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokenIdent && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// op reports whether the next token is the given operator, consuming it if
+// so.
+func (p *parser) op(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	at := "end of query"
+	if t.kind != tokenEOF {
+		at = fmt.Sprintf("%q at %d", p.src[t.pos:t.end], t.pos)
+	}
+	return fmt.Errorf("query: %s, found %s", fmt.Sprintf(format, args...), at)
+}
+
+// endOfStage reports whether the current stage is over.
+func (p *parser) endOfStage() bool {
+	t := p.peek()
+	return t.kind == tokenEOF || (t.kind == tokenOp && t.text == "|")
+}
+
+// parseQuery parses: from <source> { | <stage> }.
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{text: p.src}
+	if !p.keyword("from") {
+		return nil, p.errorf("want from events or from history")
+	}
+	if err := p.parseSource(&q.source); err != nil {
+		return nil, err
+	}
+
+	for p.op("|") {
+		st, err := p.parseStage()
+		if err != nil {
+			return nil, err
+		}
+		q.stages = append(q.stages, st)
+	}
+	if !p.endOfStage() {
+		return nil, p.errorf("want | before the next stage")
+	}
+	return q, nil
+}
+
+// parseSource parses: events | history <class pattern> {field}.
+func (p *parser) parseSource(src *source) error {
+	switch {
+	case p.keyword(sourceEvents):
+		src.kind = sourceEvents
+
+	case p.keyword(sourceHistory):
+		src.kind = sourceHistory
+		// The class pattern and fields are taken verbatim up to the next
+		// stage, as patterns like CDOTA_Unit_Hero_* are no expressions.
+		start := p.peek().pos
+		for !p.endOfStage() {
+			p.next()
+		}
+		words := strings.Fields(p.src[start:p.peek().pos])
+		if len(words) == 0 {
+			return p.errorf("want a class pattern after history")
+		}
+		src.class, src.fields = words[0], words[1:]
+
+	default:
+		return p.errorf("unknown source, want events or history")
+	}
+
+	if !p.endOfStage() {
+		return p.errorf("want | after the source")
+	}
+	return nil
+}
+
+// parseStage parses a single stage after a |.
+func (p *parser) parseStage() (stage, error) {
+	switch {
+	case p.keyword("where"):
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if hasAggregate(e) {
+			return nil, fmt.Errorf("query: aggregates are only allowed in group")
+		}
+		return &whereStage{cond: e}, p.endStage()
+
+	case p.keyword("select"):
+		items, err := p.parseItems(false)
+		if err != nil {
+			return nil, err
+		}
+		return &selectStage{items: items}, p.endStage()
+
+	case p.keyword("group"):
+		p.keyword("by")
+		items, err := p.parseItems(true)
+		if err != nil {
+			return nil, err
+		}
+		st := &groupStage{}
+		for _, it := range items {
+			if _, ok := it.expr.(*aggregate); ok {
+				st.aggs = append(st.aggs, it)
+			} else {
+				st.keys = append(st.keys, it)
+			}
+		}
+		return st, p.endStage()
+
+	case p.keyword("sort"):
+		p.keyword("by")
+		st := &sortStage{}
+		for {
+			start := p.peek().pos
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			key := sortKey{text: p.src[start:p.tokens[p.i-1].end], expr: e}
+			if p.keyword("desc") {
+				key.desc = true
+			} else {
+				p.keyword("asc")
+			}
+			st.keys = append(st.keys, key)
+			if !p.op(",") {
+				break
+			}
+		}
+		return st, p.endStage()
+
+	case p.keyword("limit"):
+		t := p.next()
+		if t.kind != tokenNumber || t.num < 0 || t.num != float64(int(t.num)) {
+			p.back(t)
+			return nil, p.errorf("want a row count after limit")
+		}
+		return &limitStage{n: int(t.num)}, p.endStage()
+	}
+	return nil, p.errorf("unknown stage, want where, select, group, sort or limit")
+}
+
+func (p *parser) endStage() error {
+	if !p.endOfStage() {
+		return p.errorf("unexpected")
+	}
+	return nil
+}
+
+// parseItems parses a comma separated list of expressions, each optionally
+// named with as. Aggregates are allowed as whole items of groups only.
+func (p *parser) parseItems(group bool) ([]item, error) {
+	var items []item
+	for {
+		start := p.peek().pos
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		end := p.tokens[p.i-1].end
+
+		_, isAggregate := e.(*aggregate)
+		if hasAggregate(e) && (!group || !isAggregate) {
+			return nil, fmt.Errorf("query: %s: aggregates are only allowed as whole items of group", p.src[start:end])
+		}
+
+		it := item{name: p.src[start:end], expr: e}
+		if id, ok := e.(*ident); ok {
+			it.name = id.name
+		}
+		if p.keyword("as") {
+			t := p.next()
+			if t.kind != tokenIdent && t.kind != tokenString {
+				p.back(t)
+				return nil, p.errorf("want a column name after as")
+			}
+			it.name = t.text
+		}
+		items = append(items, it)
+
+		if !p.op(",") {
+			return items, nil
+		}
+	}
+}
+
+// Expressions, from the lowest precedence:
+//
+//	or, and, not, comparisons (= != < <= > >= ~), + -, * /, unary -.
+func (p *parser) parseExpr() (expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "or", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "and", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.keyword("not") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{e: e}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<=", ">=", "<", ">", "~"} {
+		if p.op(op) {
+			r, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &binary{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAdditive() (expr, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if p.peek().kind != tokenOp || (op != "+" && op != "-") {
+			return l, nil
+		}
+		p.next()
+		r, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseMultiplicative() (expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if p.peek().kind != tokenOp || (op != "*" && op != "/") {
+			return l, nil
+		}
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.op("-") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binary{op: "-", l: literal{0.0}, r: e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return literal{t.num}, nil
+	case tokenString:
+		return literal{t.text}, nil
+
+	case tokenOp:
+		if t.text == "(" {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.op(")") {
+				return nil, p.errorf("want )")
+			}
+			return e, nil
+		}
+
+	case tokenIdent:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		if p.op("(") {
+			return p.parseCall(strings.ToLower(t.text))
+		}
+		return &ident{name: t.text}, nil
+	}
+
+	p.back(t)
+	return nil, p.errorf("want an expression")
+}
+
+// parseCall parses the arguments of a function call after its (.
+func (p *parser) parseCall(name string) (expr, error) {
+	var args []expr
+	if !p.op(")") {
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, e)
+			if p.op(")") {
+				break
+			}
+			if !p.op(",") {
+				return nil, p.errorf("want , or )")
+			}
+		}
+	}
+
+	if _, ok := aggregates[name]; ok {
+		switch {
+		case name == "count" && len(args) > 1, name != "count" && len(args) != 1:
+			return nil, fmt.Errorf("query: wrong number of arguments to %s", name)
+		}
+		for _, a := range args {
+			if hasAggregate(a) {
+				return nil, fmt.Errorf("query: aggregates of aggregates are not allowed")
+			}
+		}
+		a := &aggregate{name: name}
+		if len(args) == 1 {
+			a.arg = args[0]
+		}
+		return a, nil
+	}
+
+	f, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("query: unknown function %s", name)
+	}
+	if len(args) != f.args {
+		return nil, fmt.Errorf("query: %s takes %d argument(s)", name, f.args)
+	}
+	return &call{name: name, fn: f.fn, args: args}, nil
+}
@@ -0,0 +1,572 @@
+// Package query answers ad-hoc questions about replays with a small query
+// language over the timeline events and the entity history of a replay, such
+// as the abilities cast by player 3 while below 30% mana:
+//
+//	from events
+//	| where type = 'ability_cast' and player = 3 and hero.m_flMana / hero.m_flMaxMana < 0.3
+//	| group name, count() as casts
+//	| sort casts desc
+//
+// A query starts with a source and continues with stages separated by |:
+//
+//	from events                      the timeline events: tick, time, type,
+//	                                 player, target, unit, target_unit, name,
+//	                                 text, value, x and y
+//	from history <class> [field ...] the changes of the fields (all if none)
+//	                                 of entities whose class matches a pattern
+//	                                 like CDOTA_Unit_Hero_*: tick, time,
+//	                                 handle, class, field, value and player
+//	where <expr>                     keep the rows where expr is true
+//	select <expr> [as name], ...     compute the columns of the rows
+//	group <expr> [as name], ...      group the rows by the expressions which
+//	                                 are not aggregates, computing the
+//	                                 aggregates count, sum, avg, min, max,
+//	                                 first and last for each group
+//	sort <expr> [asc|desc], ...      order the rows
+//	limit <n>                        keep the first n rows
+//
+// Rows with a player also resolve player.name, player.team, player.hero (the
+// npc name), player.hero_class and player.steam_id (as text, which keeps all
+// its digits), and hero.<field>, the value of a field of the player's hero at
+// the tick of the row. target_player and target_hero do the same
+// for the target of events. The replay column is the replay a row is from.
+//
+// Expressions combine values with or, and, not, = != < <= > >=, ~ (contains,
+// ignoring case), + - * / and the functions lower, abs, round and clock
+// (game time as m:ss). Text is quoted with ' or ".
+//
+// A Query is parsed once and attached to the parser of each replay it runs
+// over; the rows of all replays are then combined into a single Result.
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dota2/match"
+	"dota2/timeline"
+
+	"github.com/dotabuff/manta"
+)
+
+const (
+	sourceEvents  = "events"
+	sourceHistory = "history"
+
+	heroClasses = "CDOTA_Unit_Hero_*"
+)
+
+// Query is a parsed query.
+type Query struct {
+	text   string
+	source source
+	stages []stage
+}
+
+// source is the data a query starts from.
+type source struct {
+	kind   string
+	class  string
+	fields []string
+}
+
+// Parse parses a query.
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: s, tokens: tokens}
+	return p.parseQuery()
+}
+
+func (q *Query) String() string {
+	return q.text
+}
+
+// heroFields returns the fields of heroes the query refers to.
+func (q *Query) heroFields() []string {
+	seen := make(map[string]bool)
+	var fields []string
+	add := func(name string) {
+		for _, prefix := range []string{"hero.", "target_hero."} {
+			if f := strings.TrimPrefix(name, prefix); f != name && !seen[f] {
+				seen[f] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	for _, st := range q.stages {
+		st.idents(add)
+	}
+	return fields
+}
+
+// Batch is the rows a query produced from a single replay, before the stages
+// which need the rows of all replays.
+type Batch struct {
+	// Replay names the replay of the rows, it is the replay column.
+	Replay string
+
+	rows []*Row
+}
+
+// Attach registers the handlers the query needs with a parser, before Start.
+// The returned function produces the rows of the replay once it is parsed.
+func (q *Query) Attach(p *manta.Parser) func() (*Batch, error) {
+	env := &env{}
+
+	var t *timeline.Timeline
+	if q.source.kind == sourceEvents {
+		t = timeline.New(p)
+		env.match = t.Match
+	} else {
+		env.match = match.New(p)
+	}
+
+	if fields := q.heroFields(); len(fields) > 0 {
+		env.heroes = manta.NewHistory(p, map[string][]string{heroClasses: fields})
+	}
+
+	var changes *manta.History
+	if q.source.kind == sourceHistory {
+		changes = manta.NewHistory(p, map[string][]string{q.source.class: q.source.fields})
+
+		// Changes only have ticks, their game times are those of the
+		// entity updates.
+		p.OnEntity(func(e *manta.Entity, op manta.EntityOp) error {
+			if n := len(env.times); n == 0 || env.times[n-1].tick != p.Tick {
+				env.times = append(env.times, tickTime{p.Tick, env.match.ServerTime()})
+			}
+			return nil
+		})
+	}
+
+	return func() (*Batch, error) {
+		var rows []*Row
+		if t != nil {
+			rows = eventRows(env, t.Events())
+		} else {
+			rows = historyRows(env, changes.Changes())
+		}
+
+		// Leading filters are applied right away, so that only the
+		// matching rows of a corpus are held in memory.
+		for _, st := range q.stages {
+			w, ok := st.(*whereStage)
+			if !ok {
+				break
+			}
+			rows = w.apply(rows)
+		}
+		return &Batch{rows: rows}, nil
+	}
+}
+
+// Result runs the stages of the query over the rows of the given replays.
+func (q *Query) Result(batches ...*Batch) *Result {
+	var rows []*Row
+	replays := false
+	for _, b := range batches {
+		for _, r := range b.rows {
+			r.replay = b.Replay
+		}
+		replays = replays || b.Replay != ""
+		rows = append(rows, b.rows...)
+	}
+
+	res := &Result{Columns: defaultColumns(q.source.kind, replays)}
+	for _, st := range q.stages {
+		rows, res.Columns = st.run(rows, res.Columns)
+	}
+
+	res.Rows = make([][]interface{}, len(rows))
+	for i, r := range rows {
+		res.Rows[i] = make([]interface{}, len(res.Columns))
+		for j, c := range res.Columns {
+			res.Rows[i][j] = r.Get(c)
+		}
+	}
+	return res
+}
+
+// Exec runs the query over the replay of a parser which has not been
+// started, for embedding queries in other programs.
+func (q *Query) Exec(p *manta.Parser) (*Result, error) {
+	rows := q.Attach(p)
+	if err := p.Start(); err != nil {
+		return nil, err
+	}
+	b, err := rows()
+	if err != nil {
+		return nil, err
+	}
+	return q.Result(b), nil
+}
+
+// defaultColumns are the columns of rows which have not been selected or
+// grouped.
+func defaultColumns(kind string, replays bool) []string {
+	var columns []string
+	if replays {
+		columns = append(columns, "replay")
+	}
+	if kind == sourceEvents {
+		return append(columns, "tick", "time", "type", "player", "target", "unit", "target_unit", "name", "text", "value", "x", "y")
+	}
+	return append(columns, "tick", "time", "handle", "class", "field", "value", "player")
+}
+
+// env is the state of a replay which rows refer to.
+type env struct {
+	match  *match.Match
+	heroes *manta.History
+	times  []tickTime
+}
+
+// tickTime is the server time at a tick.
+type tickTime struct {
+	tick uint32
+	time float32
+}
+
+// gameTime returns the game time at a tick from the recorded times.
+func (env *env) gameTime(tick uint32) float32 {
+	i := sort.Search(len(env.times), func(i int) bool { return env.times[i].tick > tick })
+	if i == 0 {
+		return 0
+	}
+	return env.match.GameTime(env.times[i-1].time)
+}
+
+// Row is a single row of a query. Rows of sources refer to the state of
+// their replay to resolve player and hero values.
+type Row struct {
+	values map[string]interface{}
+	replay string
+
+	env    *env
+	tick   uint32
+	player int32
+	target int32
+}
+
+func eventRows(env *env, events []timeline.Event) []*Row {
+	rows := make([]*Row, len(events))
+	for i, e := range events {
+		rows[i] = &Row{
+			values: map[string]interface{}{
+				"tick":        value(e.Tick),
+				"time":        value(e.Time),
+				"type":        string(e.Kind),
+				"player":      value(e.Player),
+				"target":      value(e.Target),
+				"unit":        e.Unit,
+				"target_unit": e.TargetUnit,
+				"name":        e.Name,
+				"text":        e.Text,
+				"value":       value(e.Value),
+				"x":           value(e.X),
+				"y":           value(e.Y),
+			},
+			env:    env,
+			tick:   e.Tick,
+			player: e.Player,
+			target: e.Target,
+		}
+	}
+	return rows
+}
+
+func historyRows(env *env, changes []manta.HistoryChange) []*Row {
+	rows := make([]*Row, len(changes))
+	for i, c := range changes {
+		player := int32(-1)
+		if pl := env.match.PlayerForHandle(c.Handle); pl != nil {
+			player = pl.ID
+		}
+		rows[i] = &Row{
+			values: map[string]interface{}{
+				"tick":   value(c.Tick),
+				"time":   value(env.gameTime(c.Tick)),
+				"handle": value(c.Handle),
+				"class":  c.Class,
+				"field":  c.Field,
+				"value":  value(c.Value),
+				"player": value(player),
+			},
+			env:    env,
+			tick:   c.Tick,
+			player: player,
+			target: -1,
+		}
+	}
+	return rows
+}
+
+// Get returns a value of the row, or nil if it has none by that name.
+func (r *Row) Get(name string) interface{} {
+	if v, ok := r.values[name]; ok {
+		return v
+	}
+	if name == "replay" {
+		return r.replay
+	}
+	if r.env == nil {
+		return nil
+	}
+
+	i := strings.Index(name, ".")
+	if i < 0 {
+		return nil
+	}
+	ns, field := name[:i], name[i+1:]
+	switch ns {
+	case "player":
+		return r.playerValue(r.player, field)
+	case "target_player":
+		return r.playerValue(r.target, field)
+	case "hero":
+		return r.heroValue(r.player, field)
+	case "target_hero":
+		return r.heroValue(r.target, field)
+	}
+	return nil
+}
+
+func (r *Row) playerValue(id int32, field string) interface{} {
+	pl := r.env.match.Player(id)
+	if id < 0 || pl == nil {
+		return nil
+	}
+	switch field {
+	case "name":
+		return pl.Name
+	case "team":
+		return value(pl.Team)
+	case "hero":
+		return r.env.match.HeroName(pl)
+	case "hero_class":
+		return pl.HeroClass
+	case "steam_id":
+		return strconv.FormatUint(pl.SteamID, 10)
+	}
+	return nil
+}
+
+func (r *Row) heroValue(id int32, field string) interface{} {
+	pl := r.env.match.Player(id)
+	if id < 0 || pl == nil || r.env.heroes == nil {
+		return nil
+	}
+	v, ok := r.env.heroes.ValueAt(pl.HeroHandle, field, r.tick)
+	if !ok {
+		return nil
+	}
+	return value(v)
+}
+
+// item is an expression computing a column.
+type item struct {
+	name string
+	expr expr
+}
+
+// stage transforms rows and their columns.
+type stage interface {
+	run(rows []*Row, columns []string) ([]*Row, []string)
+	idents(fn func(name string))
+}
+
+type whereStage struct {
+	cond expr
+}
+
+func (st *whereStage) apply(rows []*Row) []*Row {
+	kept := rows[:0]
+	for _, r := range rows {
+		if truthy(st.cond.eval(r)) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (st *whereStage) run(rows []*Row, columns []string) ([]*Row, []string) {
+	return st.apply(rows), columns
+}
+
+func (st *whereStage) idents(fn func(string)) {
+	idents(st.cond, fn)
+}
+
+// selectStage computes new columns. The rows keep their replay state, so
+// that later stages can still refer to player and hero values.
+type selectStage struct {
+	items []item
+}
+
+func (st *selectStage) run(rows []*Row, columns []string) ([]*Row, []string) {
+	out := make([]*Row, len(rows))
+	for i, r := range rows {
+		values := make(map[string]interface{}, len(st.items))
+		for _, it := range st.items {
+			values[it.name] = it.expr.eval(r)
+		}
+		nr := *r
+		nr.values = values
+		out[i] = &nr
+	}
+	return out, itemNames(st.items)
+}
+
+func (st *selectStage) idents(fn func(string)) {
+	for _, it := range st.items {
+		idents(it.expr, fn)
+	}
+}
+
+// groupStage groups rows by its keys, in the order groups are first seen.
+type groupStage struct {
+	keys []item
+	aggs []item
+}
+
+func (st *groupStage) run(rows []*Row, columns []string) ([]*Row, []string) {
+	type group struct {
+		keys []interface{}
+		accs []*accumulator
+	}
+	groups := make(map[string]*group)
+	var order []*group
+
+	for _, r := range rows {
+		keys := make([]interface{}, len(st.keys))
+		var id strings.Builder
+		for i, k := range st.keys {
+			keys[i] = k.expr.eval(r)
+			fmt.Fprintf(&id, "%T:%v\x00", keys[i], keys[i])
+		}
+
+		g, ok := groups[id.String()]
+		if !ok {
+			g = &group{keys: keys}
+			for _, a := range st.aggs {
+				g.accs = append(g.accs, &accumulator{agg: a.expr.(*aggregate)})
+			}
+			groups[id.String()] = g
+			order = append(order, g)
+		}
+		for _, acc := range g.accs {
+			acc.add(r)
+		}
+	}
+
+	// Aggregates over no rows at all still give a single row, like a count
+	// of zero.
+	if len(st.keys) == 0 && len(order) == 0 {
+		g := &group{}
+		for _, a := range st.aggs {
+			g.accs = append(g.accs, &accumulator{agg: a.expr.(*aggregate)})
+		}
+		order = append(order, g)
+	}
+
+	out := make([]*Row, len(order))
+	for i, g := range order {
+		values := make(map[string]interface{}, len(st.keys)+len(st.aggs))
+		for j, k := range st.keys {
+			values[k.name] = g.keys[j]
+		}
+		for j, a := range st.aggs {
+			values[a.name] = g.accs[j].result()
+		}
+		out[i] = &Row{values: values}
+	}
+	return out, append(itemNames(st.keys), itemNames(st.aggs)...)
+}
+
+func (st *groupStage) idents(fn func(string)) {
+	for _, it := range append(st.keys, st.aggs...) {
+		idents(it.expr, fn)
+	}
+}
+
+type sortKey struct {
+	text string
+	expr expr
+	desc bool
+}
+
+type sortStage struct {
+	keys []sortKey
+}
+
+func (st *sortStage) run(rows []*Row, columns []string) ([]*Row, []string) {
+	// A key written as a column is that column, so that sorting by count()
+	// after a group sorts by the aggregate.
+	values := make([][]interface{}, len(rows))
+	for i, r := range rows {
+		values[i] = make([]interface{}, len(st.keys))
+		for j, k := range st.keys {
+			if v, ok := r.values[k.text]; ok {
+				values[i][j] = v
+			} else {
+				values[i][j] = k.expr.eval(r)
+			}
+		}
+	}
+
+	index := make([]int, len(rows))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		for j, k := range st.keys {
+			c := order(values[index[a]][j], values[index[b]][j])
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	out := make([]*Row, len(rows))
+	for i, j := range index {
+		out[i] = rows[j]
+	}
+	return out, columns
+}
+
+func (st *sortStage) idents(fn func(string)) {
+	for _, k := range st.keys {
+		idents(k.expr, fn)
+	}
+}
+
+type limitStage struct {
+	n int
+}
+
+func (st *limitStage) run(rows []*Row, columns []string) ([]*Row, []string) {
+	if len(rows) > st.n {
+		rows = rows[:st.n]
+	}
+	return rows, columns
+}
+
+func (st *limitStage) idents(func(string)) {}
+
+func itemNames(items []item) []string {
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.name
+	}
+	return names
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"testing"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/stretchr/testify/assert"
+)
+
+// events returns a batch of hand-built event rows with names, players and
+// values.
+func events() *Batch {
+	b := &Batch{}
+	for _, e := range []struct {
+		name   string
+		player float64
+		value  interface{}
+	}{
+		{"antimage_blink", 0, 10.0},
+		{"item_bfury", 0, 5.0},
+		{"antimage_blink", 1, 7.0},
+		{"Blink", 2, nil},
+	} {
+		b.rows = append(b.rows, &Row{
+			values: map[string]interface{}{"name": e.name, "player": e.player, "value": e.value},
+			player: -1,
+			target: -1,
+		})
+	}
+	return b
+}
+
+func run(t *testing.T, s string) *Result {
+	q, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q.Result(events())
+}
+
+// column returns the values of a column of a result.
+func column(res *Result, name string) []interface{} {
+	for i, c := range res.Columns {
+		if c == name {
+			values := make([]interface{}, len(res.Rows))
+			for j, row := range res.Rows {
+				values[j] = row[i]
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+func TestPrecedence(t *testing.T) {
+	assert := assert.New(t)
+
+	res := run(t, `from events | limit 1 | select
+		1 + 2 * 3 as a,
+		(1 + 2) * 3 as b,
+		-2 * 3 as c,
+		7 - 2 - 1 as d,
+		false and false or true as e,
+		true or true and false as f,
+		not false and false as g,
+		not 1 = 2 as h,
+		1 + 1 = 2 as i`)
+	assert.Equal([]string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}, res.Columns)
+	assert.Equal([][]interface{}{{7.0, 9.0, -6.0, 4.0, true, true, false, true, true}}, res.Rows)
+}
+
+func TestNotAndContains(t *testing.T) {
+	assert := assert.New(t)
+
+	res := run(t, "from events | where name ~ 'BLINK' | select name")
+	assert.Equal([]interface{}{"antimage_blink", "antimage_blink", "Blink"}, column(res, "name"))
+
+	res = run(t, "from events | where not name ~ 'blink' | select name")
+	assert.Equal([]interface{}{"item_bfury"}, column(res, "name"))
+
+	// Nulls contain nothing, so that not keeps them.
+	res = run(t, "from events | where not value ~ '1' | select name")
+	assert.Equal([]interface{}{"item_bfury", "antimage_blink", "Blink"}, column(res, "name"))
+}
+
+func TestGroupEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	// Aggregates over no rows give a single row.
+	res := run(t, "from events | where player = 9 | group count() as n, sum(value) as s, max(value) as m")
+	assert.Equal([]string{"n", "s", "m"}, res.Columns)
+	assert.Equal([][]interface{}{{0.0, 0.0, nil}}, res.Rows)
+
+	// Groups by keys over no rows give none.
+	res = run(t, "from events | where player = 9 | group player, count()")
+	assert.Equal([]string{"player", "count()"}, res.Columns)
+	assert.Empty(res.Rows)
+}
+
+func TestSortAggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	res := run(t, "from events | group name, count(), sum(value) as total | sort count() desc, name")
+	assert.Equal([]string{"name", "count()", "total"}, res.Columns)
+	assert.Equal([][]interface{}{
+		{"antimage_blink", 2.0, 17.0},
+		{"Blink", 1.0, 0.0},
+		{"item_bfury", 1.0, 5.0},
+	}, res.Rows)
+
+	res = run(t, "from events | group name, sum(value) as total | sort total desc | limit 2")
+	assert.Equal([]interface{}{"antimage_blink", "item_bfury"}, column(res, "name"))
+}
+
+func TestLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Len(run(t, "from events | select name | limit 2").Rows, 2)
+	assert.Len(run(t, "from events | select name | limit 10").Rows, 4)
+	assert.Empty(run(t, "from events | select name | limit 0").Rows)
+
+	for s, want := range map[string]string{
+		"from events | limit":          "query: want a row count after limit, found end of query",
+		"from events | limit x":        `query: want a row count after limit, found "x" at 20`,
+		"from events | limit 1.5":      `query: want a row count after limit, found "1.5" at 20`,
+		"from events | limit -1":       `query: want a row count after limit, found "-" at 20`,
+		"from events | limit 1 2":      `query: unexpected, found "2" at 22`,
+		"from events | select x as":    "query: want a column name after as, found end of query",
+		"from events | where player =": "query: want an expression, found end of query",
+	} {
+		_, err := Parse(s)
+		if assert.NotNil(err, s) {
+			assert.Equal(want, err.Error(), s)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for s, want := range map[string]string{
+		"from events | where name = 'blink":   "query: unterminated string at 27",
+		"from events | select 1.2.3":          `query: bad number "1.2.3" at 21`,
+		"from events | where value > 1 € 2":   `query: unexpected "€" at 30`,
+		"from events | where name = \"blink'": "query: unterminated string at 27",
+	} {
+		_, err := Parse(s)
+		if assert.NotNil(err, s) {
+			assert.Equal(want, err.Error(), s)
+		}
+	}
+
+	// Identifiers may have letters of any script.
+	q, err := Parse("from events | select héros, name ~ 'ö' as umlaut")
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal([]string{"héros", "umlaut"}, q.Result(events()).Columns)
+}
+
+func TestSteamID(t *testing.T) {
+	assert := assert.New(t)
+
+	// Steam IDs have more digits than a float64 keeps.
+	b := manta.NewReplayBuilder()
+	b.Class("CDOTA_PlayerResource",
+		manta.BuilderField{Name: "m_vecPlayerData.0000.m_iszPlayerName", Type: "char[128]"},
+		manta.BuilderField{Name: "m_vecPlayerData.0000.m_iPlayerSteamID", Type: "uint64"},
+	)
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{
+		"m_vecPlayerData.0000.m_iszPlayerName":  "alice",
+		"m_vecPlayerData.0000.m_iPlayerSteamID": uint64(76561198000000123),
+	})
+	b.Advance(1)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := manta.NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	m := match.New(p)
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	r := &Row{env: &env{match: m}, player: 0, target: -1}
+	assert.Equal("alice", r.Get("player.name"))
+	assert.Equal("76561198000000123", r.Get("player.steam_id"))
+	assert.Nil(r.Get("target_player.steam_id"))
+}
@@ -0,0 +1,68 @@
+package query
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Result is the table produced by a query.
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// MarshalJSON encodes the result as an array of objects keyed by column.
+func (res *Result) MarshalJSON() ([]byte, error) {
+	objects := make([]map[string]interface{}, len(res.Rows))
+	for i, row := range res.Rows {
+		objects[i] = make(map[string]interface{}, len(res.Columns))
+		for j, c := range res.Columns {
+			objects[i][c] = row[j]
+		}
+	}
+	return json.Marshal(objects)
+}
+
+// Write writes the result as an aligned table, as CSV or as JSON.
+func (res *Result) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(res.Columns); err != nil {
+			return err
+		}
+		for _, row := range res.Rows {
+			if err := cw.Write(res.strings(row)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(res.Columns, "\t"))
+		for _, row := range res.Rows {
+			fmt.Fprintln(tw, strings.Join(res.strings(row), "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("query: unknown format %q, want table, csv or json", format)
+}
+
+func (res *Result) strings(row []interface{}) []string {
+	s := make([]string, len(row))
+	for i, v := range row {
+		s[i] = format(v)
+	}
+	return s
+}
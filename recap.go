@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"time"
+
+	"dota2/recap"
+
+	"github.com/dotabuff/manta"
+)
+
+// runRecap implements the `recap` mode: it explains every hero death of a
+// replay, with the damage taken in the seconds before by source and ability,
+// the disables, the items and abilities left unused, the gold lost and the
+// buyback status.
+//
+//	go run . recap [-window 15s] [-format text|json] [-o recap.txt] [replay.dem]
+func runRecap(args []string) {
+	fs := flag.NewFlagSet("recap", flag.ExitOnError)
+	window := fs.Duration("window", 15*time.Second, "game time before each death the damage taken is collected over")
+	format := fs.String("format", "text", "output format: text or json")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	fs.Parse(args)
+
+	switch *format {
+	case "text", "json":
+	default:
+		log.Fatalf("unknown format %q, want text or json", *format)
+	}
+
+	runReport(fs, *out, "deaths after tick %d are missing", func(p *manta.Parser) func(io.Writer) error {
+		r := recap.New(p, *window)
+		if *format == "json" {
+			return r.WriteJSON
+		}
+		return r.WriteText
+	})
+}
@@ -0,0 +1,489 @@
+// Package recap explains the deaths of heroes. For every death it collects
+// the damage the hero took in the seconds before, by source, ability and
+// damage type, the disables applied to it, the items and abilities it still
+// had ready, the gold it lost and whether it could buy back.
+//
+// Damage, healing and disables come from the combat log, the rest from the
+// hero, its items and abilities and the economy of the player at the time of
+// death.
+package recap
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+const noPlayer = -1
+
+// Buyback costs buybackBaseCost plus a buybackNetWorthDivisor-th of the net
+// worth of the player, the formula introduced in patch 7.07.
+const (
+	buybackBaseCost        = 200
+	buybackNetWorthDivisor = 13
+)
+
+// Damage is the damage a hero took from a single source with a single
+// ability or attack. Source is the npc name of the unit owning the damage,
+// which is the hero for its illusions and summons.
+type Damage struct {
+	Player    int32  `json:"player"`
+	Source    string `json:"source"`
+	Inflictor string `json:"inflictor"`
+	Type      string `json:"type,omitempty"`
+	Damage    int32  `json:"damage"`
+	Hits      int    `json:"hits"`
+}
+
+// Disable is a stun, silence or root applied to a hero. Before is the number
+// of seconds between the disable and the death.
+type Disable struct {
+	Kind     string  `json:"kind"`
+	Modifier string  `json:"modifier"`
+	Source   string  `json:"source"`
+	Player   int32   `json:"player"`
+	Duration float32 `json:"duration,omitempty"`
+	Before   float32 `json:"before"`
+}
+
+// Buyback is the buyback status of a player at the time of death. Cooldown
+// is the number of seconds until buyback is available again.
+type Buyback struct {
+	Available bool    `json:"available"`
+	Cost      int32   `json:"cost"`
+	Gold      int32   `json:"gold"`
+	Cooldown  float32 `json:"cooldown,omitempty"`
+}
+
+// Recap is the explanation of a single death. Killer is the npc name of the
+// unit which landed the last hit, KillerPlayer the player owning it or -1,
+// Assists the other enemy players who damaged the hero within the window.
+type Recap struct {
+	Tick         uint32  `json:"tick"`
+	Time         float32 `json:"time"`
+	Player       int32   `json:"player"`
+	Hero         string  `json:"hero"`
+	Killer       string  `json:"killer"`
+	KillerPlayer int32   `json:"killer_player"`
+	Inflictor    string  `json:"inflictor,omitempty"`
+	Assists      []int32 `json:"assists"`
+
+	// Window is the number of seconds before the death the damage,
+	// healing and disables are collected over.
+	Window      float32   `json:"window"`
+	DamageTaken int32     `json:"damage_taken"`
+	Healing     int32     `json:"healing"`
+	Damage      []Damage  `json:"damage"`
+	Disables    []Disable `json:"disables"`
+
+	// Items and Abilities were ready to be used when the hero died: off
+	// cooldown, affordable with the mana left and known to be active.
+	Items     []string `json:"unused_items"`
+	Abilities []string `json:"unused_abilities"`
+
+	GoldLost int32   `json:"gold_lost"`
+	Buyback  Buyback `json:"buyback"`
+
+	serverTime float32
+}
+
+// PlayerSummary are the kills, deaths and assists of a player.
+type PlayerSummary struct {
+	Player   int32  `json:"player"`
+	Name     string `json:"name"`
+	Team     int32  `json:"team"`
+	Hero     string `json:"hero,omitempty"`
+	Kills    int    `json:"kills"`
+	Deaths   int    `json:"deaths"`
+	Assists  int    `json:"assists"`
+	GoldLost int32  `json:"gold_lost"`
+}
+
+// Result is the recap of every death of a match.
+type Result struct {
+	match.Coverage
+	Players []PlayerSummary `json:"players"`
+	Deaths  []Recap         `json:"deaths"`
+}
+
+// entry is a combat log entry about a hero kept for the window.
+type entry struct {
+	kind       dota.DOTA_COMBATLOG_TYPES
+	serverTime float32
+	source     string
+	inflictor  string
+	damageType uint32
+	value      int32
+	sourceTeam uint32
+
+	// disable and duration describe modifiers which disable the hero.
+	disable  string
+	duration float32
+}
+
+// pending is a recap waiting for the gold loss of the death to be networked.
+type pending struct {
+	recap    *Recap
+	lostBase int32
+	gold     int32
+}
+
+// Recorder collects the recaps of the deaths of a parser. Create it with New
+// before calling Start and read the result once parsing is done.
+type Recorder struct {
+	Match *match.Match
+
+	p       *manta.Parser
+	window  float32
+	recent  map[string][]entry
+	used    map[string]map[string]bool
+	recaps  []*Recap
+	pending map[int32]*pending
+}
+
+// New returns a Recorder which recaps the deaths of heroes of the given
+// parser from the window of game time before each of them.
+func New(p *manta.Parser, window time.Duration) *Recorder {
+	r := &Recorder{
+		Match:   match.New(p),
+		p:       p,
+		window:  float32(window.Seconds()),
+		recent:  make(map[string][]entry),
+		used:    make(map[string]map[string]bool),
+		pending: make(map[int32]*pending),
+	}
+
+	p.Callbacks.OnCMsgDOTACombatLogEntry(r.onCombatLog)
+	// Gold lost to a death is networked after the combat log entry, once
+	// the entity updates of a later tick are applied.
+	p.Callbacks.OnCSVCMsg_PacketEntities(r.onPacketEntities)
+	return r
+}
+
+func (r *Recorder) onCombatLog(m *dota.CMsgDOTACombatLogEntry) error {
+	name := r.Match.CombatLogName
+	attacker := name(m.GetAttackerName())
+	target := name(m.GetTargetName())
+	inflictor := name(m.GetInflictorName())
+	ts := m.GetTimestamp()
+
+	switch m.GetType() {
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ABILITY, dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ITEM:
+		if m.GetIsAttackerIllusion() || !match.IsHeroNPC(attacker) || inflictor == "" {
+			return nil
+		}
+		used, ok := r.used[attacker]
+		if !ok {
+			used = make(map[string]bool)
+			r.used[attacker] = used
+		}
+		used[inflictor] = true
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DAMAGE,
+		dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_HEAL,
+		dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_MODIFIER_ADD:
+		if !m.GetIsTargetHero() || m.GetIsTargetIllusion() || !match.IsHeroNPC(target) {
+			return nil
+		}
+		source := name(m.GetDamageSourceName())
+		if source == "" {
+			source = attacker
+		}
+		if m.GetInflictorName() == 0 {
+			// Attacks have no inflictor.
+			inflictor = ""
+		}
+		duration := m.GetModifierDuration()
+		if duration == 0 {
+			duration = m.GetStunDuration()
+		}
+		r.remember(target, entry{
+			kind:       m.GetType(),
+			serverTime: ts,
+			source:     source,
+			inflictor:  inflictor,
+			damageType: m.GetDamageType(),
+			value:      int32(m.GetValue()),
+			sourceTeam: m.GetAttackerTeam(),
+			disable:    disableKind(m),
+			duration:   duration,
+		})
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DEATH:
+		if !m.GetIsTargetHero() || m.GetIsTargetIllusion() || m.GetWillReincarnate() {
+			return nil
+		}
+		r.onDeath(m, target, attacker, inflictor, ts)
+	}
+	return nil
+}
+
+// remember adds an entry about a hero, dropping those which fell out of the
+// window.
+func (r *Recorder) remember(hero string, e entry) {
+	entries := r.recent[hero]
+	i := 0
+	for i < len(entries) && entries[i].serverTime < e.serverTime-r.window {
+		i++
+	}
+	r.recent[hero] = append(entries[i:], e)
+}
+
+func (r *Recorder) onDeath(m *dota.CMsgDOTACombatLogEntry, hero, attacker, inflictor string, ts float32) {
+	rc := &Recap{
+		Tick:         r.p.Tick,
+		Player:       noPlayer,
+		Hero:         hero,
+		Killer:       attacker,
+		KillerPlayer: noPlayer,
+		Inflictor:    inflictor,
+		Assists:      []int32{},
+		Window:       r.window,
+		Damage:       []Damage{},
+		Disables:     []Disable{},
+		Items:        []string{},
+		Abilities:    []string{},
+		serverTime:   ts,
+	}
+	pl := r.Match.PlayerForHero(hero)
+	if pl != nil {
+		rc.Player = pl.ID
+	}
+	if source := r.Match.CombatLogName(m.GetDamageSourceName()); source != "" {
+		rc.Killer = source
+	}
+	if killer := r.Match.PlayerForHero(rc.Killer); killer != nil && (pl == nil || killer.Team != pl.Team) {
+		rc.KillerPlayer = killer.ID
+	}
+
+	r.collect(rc, m.GetTargetTeam())
+	if pl != nil {
+		r.unused(rc, pl)
+		r.buyback(rc, pl)
+	}
+
+	r.recaps = append(r.recaps, rc)
+	delete(r.recent, hero)
+}
+
+// collect fills the damage, healing, disables and assists of a recap from
+// the entries of the window.
+func (r *Recorder) collect(rc *Recap, team uint32) {
+	type key struct{ source, inflictor, damageType string }
+	damage := make(map[key]*Damage)
+	assists := make(map[int32]bool)
+
+	for _, e := range r.recent[rc.Hero] {
+		if e.serverTime < rc.serverTime-r.window || e.serverTime > rc.serverTime {
+			continue
+		}
+		player := int32(noPlayer)
+		if pl := r.Match.PlayerForHero(e.source); pl != nil {
+			player = pl.ID
+		}
+
+		switch e.kind {
+		case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DAMAGE:
+			inflictor := e.inflictor
+			if inflictor == "" {
+				inflictor = "attack"
+			}
+			k := key{e.source, inflictor, match.DamageTypeName(e.damageType)}
+			d, ok := damage[k]
+			if !ok {
+				d = &Damage{Player: player, Source: e.source, Inflictor: inflictor, Type: k.damageType}
+				damage[k] = d
+			}
+			d.Damage += e.value
+			d.Hits++
+			rc.DamageTaken += e.value
+			if player != noPlayer && player != rc.KillerPlayer && e.sourceTeam != team {
+				assists[player] = true
+			}
+
+		case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_HEAL:
+			rc.Healing += e.value
+
+		case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_MODIFIER_ADD:
+			if e.disable == "" || e.sourceTeam == team {
+				continue
+			}
+			rc.Disables = append(rc.Disables, Disable{
+				Kind:     e.disable,
+				Modifier: e.inflictor,
+				Source:   e.source,
+				Player:   player,
+				Duration: e.duration,
+				Before:   rc.serverTime - e.serverTime,
+			})
+		}
+	}
+
+	for _, d := range damage {
+		rc.Damage = append(rc.Damage, *d)
+	}
+	sort.Slice(rc.Damage, func(i, j int) bool {
+		a, b := rc.Damage[i], rc.Damage[j]
+		if a.Damage != b.Damage {
+			return a.Damage > b.Damage
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Inflictor < b.Inflictor
+	})
+	for id := range assists {
+		rc.Assists = append(rc.Assists, id)
+	}
+	sort.Slice(rc.Assists, func(i, j int) bool { return rc.Assists[i] < rc.Assists[j] })
+}
+
+// disableKind returns stun, silence or root for modifiers which disable
+// their target, or "".
+func disableKind(m *dota.CMsgDOTACombatLogEntry) string {
+	switch {
+	case m.GetStunDuration() > 0:
+		return "stun"
+	case m.GetSilenceModifier():
+		return "silence"
+	case m.GetRootModifier():
+		return "root"
+	}
+	return ""
+}
+
+// unused fills the items and abilities the hero of a player had ready.
+func (r *Recorder) unused(rc *Recap, pl *match.Player) {
+	hero := r.p.FindEntityByHandle(pl.HeroHandle)
+	if pl.HeroHandle == 0 || hero == nil {
+		return
+	}
+	mana, _ := hero.GetFloat32("m_flMana")
+	used := r.used[rc.Hero]
+
+	var slots []int
+	for slot := match.SlotInventory; slot < match.SlotBackpack; slot++ {
+		slots = append(slots, slot)
+	}
+	for _, slot := range append(slots, match.SlotTeleport, match.SlotNeutral) {
+		if item := match.Item(r.p, hero, slot); item != nil {
+			if name := match.ItemName(r.p, item); ready(item, name, used, rc.serverTime, mana) {
+				rc.Items = append(rc.Items, name)
+			}
+		}
+	}
+	for slot := 0; slot < match.MaxAbilities; slot++ {
+		if ability := match.Ability(r.p, hero, slot); ability != nil {
+			if name := match.ItemName(r.p, ability); ready(ability, name, used, rc.serverTime, mana) {
+				rc.Abilities = append(rc.Abilities, name)
+			}
+		}
+	}
+}
+
+// ready reports whether an item or ability could have been used at the given
+// server time. Passive abilities look like ready active ones, so only those
+// the hero used before or which have a cooldown count.
+func ready(e *manta.Entity, name string, used map[string]bool, now, mana float32) bool {
+	if hidden, _ := e.GetBool("m_bHidden"); hidden {
+		return false
+	}
+	if level, ok := match.Int(e, "m_iLevel"); ok && level <= 0 {
+		return false
+	}
+	if cooldown, ok := e.GetFloat32("m_fCooldown"); ok && cooldown > now {
+		return false
+	}
+	if cost, ok := match.Int(e, "m_iManaCost"); ok && float32(cost) > mana {
+		return false
+	}
+	length, _ := e.GetFloat32("m_flCooldownLength")
+	return used[name] || length > 0
+}
+
+// buyback fills the buyback status of a recap and waits for the gold lost to
+// the death.
+func (r *Recorder) buyback(rc *Recap, pl *match.Player) {
+	ec, ok := r.Match.Economy(pl.ID)
+	if !ok {
+		return
+	}
+	rc.Buyback.Cost = buybackBaseCost + ec.NetWorth/buybackNetWorthDivisor
+	if ec.BuybackCooldown > rc.serverTime {
+		rc.Buyback.Cooldown = ec.BuybackCooldown - rc.serverTime
+	}
+
+	if prev, ok := r.pending[pl.ID]; ok {
+		prev.done()
+	}
+	pd := &pending{recap: rc, lostBase: ec.GoldLostToDeath, gold: ec.Gold()}
+	pd.done()
+	r.pending[pl.ID] = pd
+}
+
+// done computes the buyback status from the gold lost so far.
+func (pd *pending) done() {
+	b := &pd.recap.Buyback
+	b.Gold = pd.gold - pd.recap.GoldLost
+	b.Available = b.Cooldown == 0 && b.Gold >= b.Cost
+}
+
+func (r *Recorder) onPacketEntities(*dota.CSVCMsg_PacketEntities) error {
+	for id, pd := range r.pending {
+		ec, ok := r.Match.Economy(id)
+		if !ok || ec.GoldLostToDeath == pd.lostBase {
+			continue
+		}
+		pd.recap.GoldLost = ec.GoldLostToDeath - pd.lostBase
+		pd.done()
+		delete(r.pending, id)
+	}
+	return nil
+}
+
+// Result returns the recaps of the deaths ordered by tick, and the kills,
+// deaths and assists of every player.
+func (r *Recorder) Result() *Result {
+	res := &Result{Coverage: r.Match.Coverage(), Players: []PlayerSummary{}, Deaths: []Recap{}}
+
+	summaries := make(map[int32]*PlayerSummary)
+	for _, pl := range r.Match.Players() {
+		res.Players = append(res.Players, PlayerSummary{Player: pl.ID, Name: pl.Name, Team: pl.Team, Hero: r.Match.HeroName(pl)})
+	}
+	for i := range res.Players {
+		summaries[res.Players[i].Player] = &res.Players[i]
+	}
+
+	for _, rc := range r.recaps {
+		d := *rc
+		d.Time = r.Match.GameTime(rc.serverTime)
+		res.Deaths = append(res.Deaths, d)
+
+		if s, ok := summaries[d.Player]; ok {
+			s.Deaths++
+			s.GoldLost += d.GoldLost
+		}
+		if s, ok := summaries[d.KillerPlayer]; ok {
+			s.Kills++
+		}
+		for _, id := range d.Assists {
+			if s, ok := summaries[id]; ok {
+				s.Assists++
+			}
+		}
+	}
+	return res
+}
+
+// WriteJSON writes the result as a single JSON document.
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Result())
+}
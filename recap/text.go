@@ -0,0 +1,77 @@
+package recap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"dota2/match"
+)
+
+// WriteText writes the recaps as text meant to be read by the players, one
+// block per death.
+func (r *Recorder) WriteText(w io.Writer) error {
+	res := r.Result()
+	names := make(map[int32]string)
+	for _, s := range res.Players {
+		names[s.Player] = s.Name
+	}
+	// unit names a unit by the player owning it, if any.
+	unit := func(npc string, player int32) string {
+		if name, ok := names[player]; ok && name != "" {
+			return fmt.Sprintf("%s (%s)", name, npc)
+		}
+		return npc
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, s := range res.Players {
+		fmt.Fprintf(bw, "%-20s %-32s %2d/%2d/%2d  lost %d gold\n", s.Name, s.Hero, s.Kills, s.Deaths, s.Assists, s.GoldLost)
+	}
+
+	for _, d := range res.Deaths {
+		fmt.Fprintf(bw, "\n%s %s killed by %s", match.FormatGameTime(d.Time), unit(d.Hero, d.Player), unit(d.Killer, d.KillerPlayer))
+		if d.Inflictor != "" {
+			fmt.Fprintf(bw, " with %s", d.Inflictor)
+		}
+		fmt.Fprintln(bw)
+		if len(d.Assists) > 0 {
+			var assists []string
+			for _, id := range d.Assists {
+				assists = append(assists, names[id])
+			}
+			fmt.Fprintf(bw, "  assisted by %s\n", strings.Join(assists, ", "))
+		}
+
+		fmt.Fprintf(bw, "  took %d damage and %d healing in the last %gs\n", d.DamageTaken, d.Healing, d.Window)
+		for _, dmg := range d.Damage {
+			fmt.Fprintf(bw, "    %6d  %-40s %-32s %-8s %d hits\n", dmg.Damage, unit(dmg.Source, dmg.Player), dmg.Inflictor, dmg.Type, dmg.Hits)
+		}
+		for _, dis := range d.Disables {
+			fmt.Fprintf(bw, "  %s by %s from %s, %.1fs before death", dis.Kind, dis.Modifier, unit(dis.Source, dis.Player), dis.Before)
+			if dis.Duration > 0 {
+				fmt.Fprintf(bw, " for %.1fs", dis.Duration)
+			}
+			fmt.Fprintln(bw)
+		}
+		if len(d.Items) > 0 {
+			fmt.Fprintf(bw, "  unused items: %s\n", strings.Join(d.Items, ", "))
+		}
+		if len(d.Abilities) > 0 {
+			fmt.Fprintf(bw, "  unused abilities: %s\n", strings.Join(d.Abilities, ", "))
+		}
+
+		b := d.Buyback
+		fmt.Fprintf(bw, "  lost %d gold, buyback ", d.GoldLost)
+		switch {
+		case b.Available:
+			fmt.Fprintf(bw, "available for %d gold, had %d\n", b.Cost, b.Gold)
+		case b.Cooldown > 0:
+			fmt.Fprintf(bw, "on cooldown for %s\n", match.FormatGameTime(b.Cooldown))
+		default:
+			fmt.Fprintf(bw, "needs %d gold, had %d\n", b.Cost, b.Gold)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/dotabuff/manta"
+)
+
+// runReport parses the replay named by the arguments of fs, replay1.dem by
+// default, with the analyzer attach registers, and writes its report with
+// the function attach returns to the file out, or to stdout if out is empty.
+// Truncated replays are parsed up to their last intact message and logged
+// with truncated, a format given the tick parsing ended at.
+func runReport(fs *flag.FlagSet, out, truncated string, attach func(p *manta.Parser) func(w io.Writer) error) {
+	path := "replay1.dem"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	p, err := manta.NewStreamParser(f)
+	if err != nil {
+		log.Fatalf("NewStreamParser: %v", err)
+	}
+	p.AllowTruncated(true)
+
+	write := attach(p)
+
+	if err := p.Start(); err != nil && err != io.EOF {
+		log.Fatalf("parse error: %v", err)
+	}
+	if p.Truncated() {
+		log.Printf("replay is truncated, "+truncated, p.Tick)
+	}
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		fo, err := os.Create(out)
+		if err != nil {
+			log.Fatalf("create %s: %v", out, err)
+		}
+		defer fo.Close()
+		w = fo
+	}
+	if err := write(w); err != nil {
+		log.Fatalf("write: %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/dotabuff/manta"
+)
+
+// runStats implements the `stats` mode: it parses a replay with a profiler
+// attached and prints message and entity statistics.
+//
+//	go run . stats [-o report.txt] [replay.dem]
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	out := fs.String("o", "", "write the report to this file instead of stdout")
+	fs.Parse(args)
+
+	runReport(fs, *out, "the report only covers ticks up to %d", func(p *manta.Parser) func(io.Writer) error {
+		return manta.NewProfiler(p).WriteReport
+	})
+}
@@ -0,0 +1,336 @@
+// Package timeline merges the events of a match which are spread over the
+// combat log, user messages, entities and net messages into a single tick
+// ordered stream.
+package timeline
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+)
+
+// Kind is the type of a timeline event.
+type Kind string
+
+const (
+	KindKill        Kind = "kill"
+	KindDeath       Kind = "death"
+	KindPurchase    Kind = "purchase"
+	KindItemUse     Kind = "item_use"
+	KindItemToggle  Kind = "item_toggle"
+	KindAbilityCast Kind = "ability_cast"
+	KindRune        Kind = "rune"
+	KindWard        Kind = "ward"
+	KindBuilding    Kind = "building_destroyed"
+	KindChat        Kind = "chat"
+	KindPing        Kind = "ping"
+	KindPause       Kind = "pause"
+	KindRoshan      Kind = "roshan"
+)
+
+const (
+	noPlayer        = -1
+	wardObserver    = "CDOTA_NPC_Observer_Ward"
+	wardSentry      = "CDOTA_NPC_Observer_Ward_TrueSight"
+	itemClassPrefix = "CDOTA_Item_"
+)
+
+// Event is a single entry of the timeline. Player and Target are player ids,
+// or -1 when the event has no (resolvable) player.
+type Event struct {
+	Tick   uint32  `json:"tick"`
+	Time   float32 `json:"time"`
+	Kind   Kind    `json:"type"`
+	Player int32   `json:"player"`
+	Target int32   `json:"target"`
+
+	// Unit and TargetUnit are the npc names of the units involved.
+	Unit       string `json:"unit,omitempty"`
+	TargetUnit string `json:"target_unit,omitempty"`
+
+	// Name is the item, ability, rune or building the event is about, Text
+	// the chat message, the new state of a toggled item or ward, or
+	// "reincarnated" for the death and kill of a hero who comes back.
+	Name  string  `json:"name,omitempty"`
+	Text  string  `json:"text,omitempty"`
+	Value int32   `json:"value,omitempty"`
+	X     float32 `json:"x,omitempty"`
+	Y     float32 `json:"y,omitempty"`
+
+	serverTime float32
+	seq        int
+}
+
+// Timeline collects the events of a parser. Create it with New before
+// calling Start and read the result with Events once parsing is done.
+type Timeline struct {
+	Match *match.Match
+
+	p      *manta.Parser
+	events []*Event
+	items  map[uint64]string
+}
+
+// New returns a Timeline which collects events from the given parser.
+func New(p *manta.Parser) *Timeline {
+	t := &Timeline{
+		Match: match.New(p),
+		p:     p,
+		items: make(map[uint64]string),
+	}
+
+	p.Callbacks.OnCMsgDOTACombatLogEntry(t.onCombatLog)
+	p.Callbacks.OnCUserMessageSayText2(t.onSayText2)
+	p.Callbacks.OnCDOTAUserMsg_ChatMessage(t.onChatMessage)
+	p.Callbacks.OnCDOTAUserMsg_LocationPing(t.onLocationPing)
+	p.Callbacks.OnCSVCMsg_SetPause(t.onSetPause)
+	p.OnEntity(t.onEntity)
+
+	return t
+}
+
+func (t *Timeline) add(e *Event, serverTime float32) {
+	e.Tick = t.p.Tick
+	e.serverTime = serverTime
+	e.seq = len(t.events)
+	t.events = append(t.events, e)
+}
+
+// unitEvent returns an event between two units whose players are resolved
+// from their npc names once the match is known.
+func unitEvent(kind Kind, unit, target, name string) *Event {
+	return &Event{Kind: kind, Player: noPlayer, Target: noPlayer, Unit: unit, TargetUnit: target, Name: name}
+}
+
+func (t *Timeline) onCombatLog(m *dota.CMsgDOTACombatLogEntry) error {
+	name := t.Match.CombatLogName
+	attacker := name(m.GetAttackerName())
+	target := name(m.GetTargetName())
+	ts := m.GetTimestamp()
+
+	// Index 0 is what entries without an inflictor, such as deaths to
+	// attacks, point to.
+	var inflictor string
+	if i := m.GetInflictorName(); i != 0 {
+		inflictor = name(i)
+	}
+
+	switch m.GetType() {
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DEATH:
+		switch {
+		case m.GetIsTargetHero() && !m.GetIsTargetIllusion():
+			death := unitEvent(KindDeath, target, attacker, inflictor)
+			kill := unitEvent(KindKill, attacker, target, inflictor)
+			if m.GetWillReincarnate() {
+				death.Text = "reincarnated"
+				kill.Text = "reincarnated"
+			}
+			t.add(death, ts)
+			t.add(kill, ts)
+		case match.IsRoshanNPC(target):
+			t.add(unitEvent(KindRoshan, attacker, target, "killed"), ts)
+		case m.GetIsTargetBuilding() || match.IsBuildingNPC(target):
+			e := unitEvent(KindBuilding, attacker, "", target)
+			e.Value = int32(m.GetTargetTeam())
+			t.add(e, ts)
+		}
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_AEGIS_TAKEN:
+		t.add(unitEvent(KindRoshan, target, "", "aegis"), ts)
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_PURCHASE:
+		t.add(unitEvent(KindPurchase, target, "", name(m.GetValue())), ts)
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ITEM:
+		t.add(unitEvent(KindItemUse, attacker, target, inflictor), ts)
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ABILITY:
+		if m.GetIsAttackerIllusion() {
+			return nil
+		}
+		e := unitEvent(KindAbilityCast, attacker, target, inflictor)
+		e.Value = int32(m.GetAbilityLevel())
+		t.add(e, ts)
+
+	case dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_PICKUP_RUNE:
+		t.add(unitEvent(KindRune, target, "", match.RuneName(m.GetValue())), ts)
+	}
+
+	return nil
+}
+
+func (t *Timeline) onSayText2(m *dota.CUserMessageSayText2) error {
+	e := unitEvent(KindChat, "", "", "")
+	e.Text = m.GetParam2()
+
+	if pl := t.Match.PlayerForController(m.GetEntityindex()); pl != nil {
+		e.Player = pl.ID
+	}
+	t.add(e, t.Match.ServerTime())
+	return nil
+}
+
+func (t *Timeline) onChatMessage(m *dota.CDOTAUserMsg_ChatMessage) error {
+	t.add(&Event{
+		Kind:   KindChat,
+		Player: m.GetSourcePlayerId(),
+		Target: noPlayer,
+		Text:   m.GetMessageText(),
+		Value:  int32(m.GetChannelType()),
+	}, t.Match.ServerTime())
+	return nil
+}
+
+func (t *Timeline) onLocationPing(m *dota.CDOTAUserMsg_LocationPing) error {
+	ping := m.GetLocationPing()
+	t.add(&Event{
+		Kind:   KindPing,
+		Player: m.GetPlayerId(),
+		Target: noPlayer,
+		Value:  int32(ping.GetType()),
+		X:      float32(ping.GetX()),
+		Y:      float32(ping.GetY()),
+	}, t.Match.ServerTime())
+	return nil
+}
+
+func (t *Timeline) onSetPause(m *dota.CSVCMsg_SetPause) error {
+	e := unitEvent(KindPause, "", "", "unpaused")
+	if m.GetPaused() {
+		e.Name = "paused"
+	}
+	t.add(e, t.Match.ServerTime())
+	return nil
+}
+
+func (t *Timeline) onEntity(e *manta.Entity, op manta.EntityOp) error {
+	cn := e.GetClassName()
+
+	switch {
+	case cn == wardObserver || cn == wardSentry:
+		if !op.Flag(manta.EntityOpCreated) && !op.Flag(manta.EntityOpDeleted) {
+			return nil
+		}
+		ev := unitEvent(KindWard, "", "", "observer")
+		ev.Text = "placed"
+		if cn == wardSentry {
+			ev.Name = "sentry"
+		}
+		if op.Flag(manta.EntityOpDeleted) {
+			ev.Text = "removed"
+		}
+		if pl := t.Match.PlayerForEntity(e); pl != nil {
+			ev.Player = pl.ID
+		}
+		ev.X, ev.Y, _ = match.Position(e)
+		t.add(ev, t.Match.ServerTime())
+
+	case strings.HasPrefix(cn, itemClassPrefix):
+		t.onItem(e, op)
+	}
+
+	return nil
+}
+
+// onItem records toggles of items, such as Power Treads switching attribute
+// or Armlet being turned on, which are not part of the combat log.
+func (t *Timeline) onItem(e *manta.Entity, op manta.EntityOp) {
+	h := e.GetHandle()
+	if op.Flag(manta.EntityOpDeleted) {
+		delete(t.items, h)
+		return
+	}
+
+	state := ""
+	if e.GetClassName() == match.PowerTreadsClass {
+		state, _ = match.PowerTreadsStat(e)
+	} else if v, ok := e.GetBool("m_bToggleState"); ok {
+		state = "off"
+		if v {
+			state = "on"
+		}
+	}
+	if state == "" {
+		return
+	}
+
+	prev, seen := t.items[h]
+	t.items[h] = state
+	if !seen || prev == state {
+		return
+	}
+
+	ev := unitEvent(KindItemToggle, "", "", match.ItemName(t.p, e))
+	ev.Text = state
+	if pl := t.Match.PlayerForEntity(e); pl != nil {
+		ev.Player = pl.ID
+		ev.Unit = pl.Hero
+	}
+	t.add(ev, t.Match.ServerTime())
+}
+
+// Events returns all events ordered by tick, with game times and players
+// resolved against the final state of the match.
+func (t *Timeline) Events() []Event {
+	events := make([]Event, len(t.events))
+	for i, e := range t.events {
+		ev := *e
+		ev.Time = t.Match.GameTime(e.serverTime)
+		if ev.Player == noPlayer && ev.Unit != "" {
+			if pl := t.Match.PlayerForHero(ev.Unit); pl != nil {
+				ev.Player = pl.ID
+			}
+		}
+		if ev.Target == noPlayer && ev.TargetUnit != "" {
+			if pl := t.Match.PlayerForHero(ev.TargetUnit); pl != nil {
+				ev.Target = pl.ID
+			}
+		}
+		events[i] = ev
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Tick != events[j].Tick {
+			return events[i].Tick < events[j].Tick
+		}
+		return events[i].seq < events[j].seq
+	})
+	return events
+}
+
+// Filter returns the events of the given kinds, in timeline order.
+func (t *Timeline) Filter(kinds ...Kind) []Event {
+	want := make(map[Kind]bool, len(kinds))
+	for _, k := range kinds {
+		want[k] = true
+	}
+
+	var events []Event
+	for _, e := range t.Events() {
+		if want[e.Kind] {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// document is the JSON representation of a timeline.
+type document struct {
+	Players []*match.Player `json:"players"`
+	Events  []Event         `json:"events"`
+}
+
+// WriteJSON writes the players and events of the timeline as a single JSON
+// document.
+func (t *Timeline) WriteJSON(w io.Writer) error {
+	events := t.Events()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(document{Players: t.Match.Players(), Events: events})
+}
@@ -0,0 +1,209 @@
+package timeline
+
+import (
+	"testing"
+
+	"dota2/match"
+
+	"github.com/dotabuff/manta"
+	"github.com/dotabuff/manta/dota"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func field(name, typ string) manta.BuilderField {
+	return manta.BuilderField{Name: name, Type: typ}
+}
+
+// buildPowerTreads builds a replay in which Anti-Mage switches Power Treads
+// to intelligence, casts a spell and switches back, then kills Lifestealer.
+// Beastmaster's boar shares the hero class prefix and the player id of its
+// owner.
+func buildPowerTreads(t *testing.T) []byte {
+	b := manta.NewReplayBuilder()
+	b.Class("CDOTAGamerulesProxy",
+		field("m_pGameRules.m_fGameTime", "float32"),
+		field("m_pGameRules.m_flGameStartTime", "float32"),
+	)
+	b.Class("CDOTA_PlayerResource",
+		field("m_vecPlayerData.0000.m_iszPlayerName", "char[128]"),
+		field("m_vecPlayerData.0000.m_iPlayerTeam", "int32"),
+		field("m_vecPlayerData.0005.m_iszPlayerName", "char[128]"),
+		field("m_vecPlayerData.0005.m_iPlayerTeam", "int32"),
+		field("m_vecPlayerData.0006.m_iszPlayerName", "char[128]"),
+		field("m_vecPlayerData.0006.m_iPlayerTeam", "int32"),
+	)
+	for _, class := range []string{"CDOTA_Unit_Hero_AntiMage", "CDOTA_Unit_Hero_Life_Stealer", "CDOTA_Unit_Hero_Beastmaster", "CDOTA_Unit_Hero_Beastmaster_Boar"} {
+		b.Class(class,
+			field("m_iPlayerID", "int32"),
+			field("m_flMana", "float32"),
+		)
+	}
+	b.Class(match.PowerTreadsClass,
+		field("m_iStat", "int32"),
+		field("m_hOwnerEntity", "CHandle< CBaseEntity >"),
+	)
+
+	b.Create(0, "CDOTAGamerulesProxy", map[string]interface{}{"m_pGameRules.m_fGameTime": float32(100), "m_pGameRules.m_flGameStartTime": float32(90)})
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{
+		"m_vecPlayerData.0000.m_iszPlayerName": "alice", "m_vecPlayerData.0000.m_iPlayerTeam": int32(match.TeamRadiant),
+		"m_vecPlayerData.0005.m_iszPlayerName": "bob", "m_vecPlayerData.0005.m_iPlayerTeam": int32(match.TeamDire),
+		"m_vecPlayerData.0006.m_iszPlayerName": "carol", "m_vecPlayerData.0006.m_iPlayerTeam": int32(match.TeamDire),
+	})
+	b.Create(11, "CDOTA_Unit_Hero_Beastmaster_Boar", map[string]interface{}{"m_iPlayerID": int32(6)})
+	b.Create(10, "CDOTA_Unit_Hero_AntiMage", map[string]interface{}{"m_iPlayerID": int32(0), "m_flMana": float32(900)})
+	b.Create(12, "CDOTA_Unit_Hero_Life_Stealer", map[string]interface{}{"m_iPlayerID": int32(5)})
+	b.Create(13, "CDOTA_Unit_Hero_Beastmaster", map[string]interface{}{"m_iPlayerID": int32(6)})
+	b.Create(20, match.PowerTreadsClass, map[string]interface{}{"m_iStat": int32(2), "m_hOwnerEntity": uint32(b.Handle(10))})
+
+	// Index 0 of the table is what unset names of entries point to.
+	b.CombatLogName("dota_unknown")
+	am := b.CombatLogName("npc_dota_hero_antimage")
+	ls := b.CombatLogName("npc_dota_hero_life_stealer")
+	blink := b.CombatLogName("antimage_blink")
+
+	b.Advance(30)
+	b.Update(20, map[string]interface{}{"m_iStat": int32(1)})
+	b.Advance(3)
+	b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+		Type:           dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_ABILITY.Enum(),
+		AttackerName:   proto.Uint32(am),
+		InflictorName:  proto.Uint32(blink),
+		IsAttackerHero: proto.Bool(true),
+		Timestamp:      proto.Float32(101.1),
+	})
+	b.Update(10, map[string]interface{}{"m_flMana": float32(840)})
+	b.Advance(3)
+	b.Update(20, map[string]interface{}{"m_iStat": int32(2)})
+	b.Advance(30)
+	b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+		Type:           dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DEATH.Enum(),
+		AttackerName:   proto.Uint32(am),
+		TargetName:     proto.Uint32(ls),
+		IsAttackerHero: proto.Bool(true),
+		IsTargetHero:   proto.Bool(true),
+		Timestamp:      proto.Float32(102.2),
+	})
+	b.Advance(1)
+
+	data, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTimelinePowerTreads(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := manta.NewParser(buildPowerTreads(t))
+	if !assert.Nil(err) {
+		return
+	}
+	tl := New(p)
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	type event struct {
+		kind           Kind
+		player, target int32
+		name, text     string
+	}
+	var events []event
+	for _, e := range tl.Events() {
+		events = append(events, event{e.Kind, e.Player, e.Target, e.Name, e.Text})
+	}
+	assert.Equal([]event{
+		{KindItemToggle, 0, noPlayer, match.PowerTreadsClass, "int"},
+		{KindAbilityCast, 0, noPlayer, "antimage_blink", ""},
+		{KindItemToggle, 0, noPlayer, match.PowerTreadsClass, "agi"},
+		{KindDeath, 5, 0, "", ""},
+		{KindKill, 0, 5, "", ""},
+	}, events)
+
+	// The boar is created first but is not the hero of its player.
+	assert.Equal("CDOTA_Unit_Hero_Beastmaster", tl.Match.Player(6).HeroClass)
+	assert.Equal(tl.Match.Player(5), tl.Match.PlayerForHero("npc_dota_hero_life_stealer"))
+}
+
+func TestTimelineDeaths(t *testing.T) {
+	assert := assert.New(t)
+
+	// A tower kills Lifestealer, who reincarnates, then Anti-Mage kills him
+	// with Mana Void.
+	b := manta.NewReplayBuilder()
+	b.Class("CDOTAGamerulesProxy", field("m_pGameRules.m_fGameTime", "float32"))
+	b.Class("CDOTA_PlayerResource",
+		field("m_vecPlayerData.0000.m_iszPlayerName", "char[128]"),
+		field("m_vecPlayerData.0005.m_iszPlayerName", "char[128]"),
+	)
+	for _, class := range []string{"CDOTA_Unit_Hero_AntiMage", "CDOTA_Unit_Hero_Life_Stealer"} {
+		b.Class(class, field("m_iPlayerID", "int32"))
+	}
+	b.Create(0, "CDOTAGamerulesProxy", map[string]interface{}{"m_pGameRules.m_fGameTime": float32(100)})
+	b.Create(1, "CDOTA_PlayerResource", map[string]interface{}{
+		"m_vecPlayerData.0000.m_iszPlayerName": "alice",
+		"m_vecPlayerData.0005.m_iszPlayerName": "bob",
+	})
+	b.Create(10, "CDOTA_Unit_Hero_AntiMage", map[string]interface{}{"m_iPlayerID": int32(0)})
+	b.Create(12, "CDOTA_Unit_Hero_Life_Stealer", map[string]interface{}{"m_iPlayerID": int32(5)})
+
+	b.CombatLogName("dota_unknown")
+	am := b.CombatLogName("npc_dota_hero_antimage")
+	ls := b.CombatLogName("npc_dota_hero_life_stealer")
+	tower := b.CombatLogName("npc_dota_goodguys_tower1_mid")
+	manaVoid := b.CombatLogName("antimage_mana_void")
+
+	b.Advance(30)
+	b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+		Type:            dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DEATH.Enum(),
+		AttackerName:    proto.Uint32(tower),
+		TargetName:      proto.Uint32(ls),
+		IsTargetHero:    proto.Bool(true),
+		IsAttackerHero:  proto.Bool(false),
+		WillReincarnate: proto.Bool(true),
+		Timestamp:       proto.Float32(101),
+	})
+	b.Advance(30)
+	b.CombatLog(&dota.CMsgDOTACombatLogEntry{
+		Type:           dota.DOTA_COMBATLOG_TYPES_DOTA_COMBATLOG_DEATH.Enum(),
+		AttackerName:   proto.Uint32(am),
+		TargetName:     proto.Uint32(ls),
+		InflictorName:  proto.Uint32(manaVoid),
+		IsAttackerHero: proto.Bool(true),
+		IsTargetHero:   proto.Bool(true),
+		Timestamp:      proto.Float32(102),
+	})
+	b.Advance(1)
+	data, err := b.Bytes()
+	if !assert.Nil(err) {
+		return
+	}
+
+	p, err := manta.NewParser(data)
+	if !assert.Nil(err) {
+		return
+	}
+	tl := New(p)
+	if !assert.Nil(p.Start()) {
+		return
+	}
+
+	type event struct {
+		kind           Kind
+		player, target int32
+		unit, name     string
+		text           string
+	}
+	var events []event
+	for _, e := range tl.Events() {
+		events = append(events, event{e.Kind, e.Player, e.Target, e.Unit, e.Name, e.Text})
+	}
+	assert.Equal([]event{
+		{KindDeath, 5, noPlayer, "npc_dota_hero_life_stealer", "", "reincarnated"},
+		{KindKill, noPlayer, 5, "npc_dota_goodguys_tower1_mid", "", "reincarnated"},
+		{KindDeath, 5, 0, "npc_dota_hero_life_stealer", "antimage_mana_void", ""},
+		{KindKill, 0, 5, "npc_dota_hero_antimage", "antimage_mana_void", ""},
+	}, events)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"dota2/corpus"
+
+	"github.com/dotabuff/manta"
+)
+
+// runValidate implements the `validate` mode: it checks the integrity of the
+// given replays (or every .dem below the given directories) and prints one
+// line per replay. It exits with status 1 if any replay is incomplete.
+//
+//	go run . validate replays/...
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"replay1.dem"}
+	}
+	files, err := corpus.Files(paths)
+	if err != nil {
+		log.Fatalf("validate: %v", err)
+	}
+
+	invalid := 0
+	for _, file := range files {
+		v, err := validateFile(file)
+		if err != nil {
+			fmt.Printf("%s: %v\n", file, err)
+			invalid++
+			continue
+		}
+		if !v.Valid() {
+			invalid++
+		}
+
+		status := "ok"
+		switch {
+		case v.Err != nil:
+			status = fmt.Sprintf("error: %v", v.Err)
+		case v.Truncated:
+			status = "truncated"
+		case !v.FileInfo:
+			status = "missing file info"
+		}
+		fmt.Printf("%s: %s (%d messages, %d bytes, last good tick %d, stop %t, file info %t)\n",
+			file, status, v.Messages, v.Offset, v.LastGoodTick, v.Stop, v.FileInfo)
+	}
+
+	if invalid > 0 {
+		os.Exit(1)
+	}
+}
+
+// validateFile validates a single replay file.
+func validateFile(path string) (*manta.Validation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return manta.Validate(f)
+}